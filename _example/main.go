@@ -36,19 +36,22 @@ func main() {
 	cfg := &wishlist.Config{
 		Port: 2233,
 		Factory: func(e wishlist.Endpoint) (*ssh.Server, error) {
+			middlewares := append(
+				e.Middlewares, // this is the important bit: the middlewares from the endpoint
+				lm.Middleware(),
+			)
+			if e.Name != "list" {
+				// the listing checks for a PTY itself, as `list --json`
+				// doesn't need one.
+				middlewares = append(middlewares, activeterm.Middleware())
+			}
 			return wish.NewServer(
 				wish.WithAddress(e.Address),
 				wish.WithHostKeyPEM(k.RawPrivateKey()),
 				wish.WithPublicKeyAuth(func(ctx ssh.Context, key ssh.PublicKey) bool {
 					return true
 				}),
-				wish.WithMiddleware(
-					append(
-						e.Middlewares, // this is the important bit: the middlewares from the endpoint
-						lm.Middleware(),
-						activeterm.Middleware(),
-					)...,
-				),
+				wish.WithMiddleware(middlewares...),
 			)
 		},
 		Endpoints: []*wishlist.Endpoint{
@@ -0,0 +1,153 @@
+package wishlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Role is a named set of access rules, which users can have.
+type Role struct {
+	Name  string   `yaml:"name"`
+	Allow []string `yaml:"allow"` // Rules of the endpoints allowed, if any, all others are denied.
+	Deny  []string `yaml:"deny"`  // Rules of the endpoints denied, taking precedence over allow rules.
+}
+
+// Fields access rules can match, e.g. `tag:prod`.
+// Rules without a field match the endpoint name or full name.
+var accessRuleFields = []string{
+	queryFieldName,
+	queryFieldGroup,
+	queryFieldTag,
+}
+
+// parseAccessRule parses an access rule, in the `field:glob` format.
+func parseAccessRule(rule string) (queryTerm, error) {
+	field, value, ok := strings.Cut(strings.TrimSpace(rule), ":")
+	if !ok {
+		field, value = "", field
+	}
+	field = strings.ToLower(field)
+	if field != "" && !slices.Contains(accessRuleFields, field) {
+		return queryTerm{}, fmt.Errorf("invalid access rule %q: field must be one of %s", rule, strings.Join(accessRuleFields, ", "))
+	}
+	if value == "" {
+		return queryTerm{}, fmt.Errorf("invalid access rule %q: empty value", rule)
+	}
+	return queryTerm{field: field, value: strings.ToLower(value)}, nil
+}
+
+// validateAccess returns an error if the roles or the rules of the users are
+// invalid.
+func validateAccess(roles []Role, users []User) error {
+	names := map[string]bool{}
+	for _, role := range roles {
+		if role.Name == "" {
+			return fmt.Errorf("role name is required")
+		}
+		if names[role.Name] {
+			return fmt.Errorf("role %q: duplicated", role.Name)
+		}
+		names[role.Name] = true
+		for _, rule := range append(role.Allow, role.Deny...) {
+			if _, err := parseAccessRule(rule); err != nil {
+				return fmt.Errorf("role %q: %w", role.Name, err)
+			}
+		}
+	}
+	for _, user := range users {
+		for _, role := range user.Roles {
+			if !names[role] {
+				return fmt.Errorf("user %q: role %q not found", user.Name, role)
+			}
+		}
+		for _, rule := range append(user.Allow, user.Deny...) {
+			if _, err := parseAccessRule(rule); err != nil {
+				return fmt.Errorf("user %q: %w", user.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// accessRules are the access rules of a user.
+// The zero value allows all endpoints.
+type accessRules struct {
+	allow []queryTerm
+	deny  []queryTerm
+}
+
+// userAccess returns the access rules of the given user, including the ones
+// of their roles.
+// Users are only authenticated if the config has users, so all endpoints are
+// allowed otherwise.
+// Invalid rules are ignored, as they are validated with the config.
+func userAccess(config *Config, name string) accessRules {
+	var result accessRules
+	if len(config.Users) == 0 {
+		return result
+	}
+	add := func(allow, deny []string) {
+		for _, rule := range allow {
+			if term, err := parseAccessRule(rule); err == nil {
+				result.allow = append(result.allow, term)
+			}
+		}
+		for _, rule := range deny {
+			if term, err := parseAccessRule(rule); err == nil {
+				result.deny = append(result.deny, term)
+			}
+		}
+	}
+	for _, user := range config.Users {
+		if user.Name != name {
+			continue
+		}
+		add(user.Allow, user.Deny)
+		for _, role := range config.Roles {
+			if slices.Contains(user.Roles, role.Name) {
+				add(role.Allow, role.Deny)
+			}
+		}
+	}
+	return result
+}
+
+// allowed returns whether the endpoint can be listed and connected to.
+func (a accessRules) allowed(e *Endpoint) bool {
+	for _, term := range a.deny {
+		if accessRuleMatches(term, e) {
+			return false
+		}
+	}
+	if len(a.allow) == 0 {
+		return true
+	}
+	for _, term := range a.allow {
+		if accessRuleMatches(term, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// filter returns the allowed endpoints.
+func (a accessRules) filter(endpoints []*Endpoint) []*Endpoint {
+	if len(a.allow)+len(a.deny) == 0 {
+		return endpoints
+	}
+	result := make([]*Endpoint, 0, len(endpoints))
+	for _, e := range endpoints {
+		if a.allowed(e) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func accessRuleMatches(term queryTerm, e *Endpoint) bool {
+	if term.field == "" {
+		return matchGlob(term.value, e.Name) || matchGlob(term.value, e.FullName())
+	}
+	return term.matches(ItemWrapper{endpoint: e})
+}
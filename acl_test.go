@@ -0,0 +1,84 @@
+package wishlist
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAccessRule(t *testing.T) {
+	for rule, expected := range map[string]queryTerm{
+		"db*":        {value: "db*"},
+		"prod/db1":   {value: "prod/db1"},
+		"tag:Secret": {field: queryFieldTag, value: "secret"},
+		"group:prod": {field: queryFieldGroup, value: "prod"},
+		"Name:db?":   {field: queryFieldName, value: "db?"},
+	} {
+		t.Run(rule, func(t *testing.T) {
+			term, err := parseAccessRule(rule)
+			require.NoError(t, err)
+			require.Equal(t, expected, term)
+		})
+	}
+
+	t.Run("invalid field", func(t *testing.T) {
+		_, err := parseAccessRule("status:up")
+		require.EqualError(t, err, `invalid access rule "status:up": field must be one of name, group, tag`)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := parseAccessRule("tag:")
+		require.EqualError(t, err, `invalid access rule "tag:": empty value`)
+	})
+}
+
+func TestValidateAccess(t *testing.T) {
+	roles := []Role{{Name: "dba", Allow: []string{"tag:db"}}}
+	require.NoError(t, validateAccess(roles, []User{{Name: "carlos", Roles: []string{"dba"}, Deny: []string{"prod/*"}}}))
+	require.EqualError(t, validateAccess(roles, []User{{Name: "carlos", Roles: []string{"dev"}}}), `user "carlos": role "dev" not found`)
+	require.EqualError(t, validateAccess(roles, []User{{Name: "carlos", Allow: []string{"host:db"}}}), `user "carlos": invalid access rule "host:db": field must be one of name, group, tag`)
+	require.EqualError(t, validateAccess([]Role{{Name: "dba"}, {Name: "dba"}}, nil), `role "dba": duplicated`)
+	require.EqualError(t, validateAccess([]Role{{Allow: []string{"db1"}}}, nil), `role name is required`)
+	require.EqualError(t, validateAccess([]Role{{Name: "dba", Deny: []string{"group:"}}}, nil), `role "dba": invalid access rule "group:": empty value`)
+}
+
+func TestUserAccess(t *testing.T) {
+	db1 := &Endpoint{Name: "db1", Group: "prod/eu", Tags: []string{"db"}}
+	db2 := &Endpoint{Name: "db2", Group: "staging", Tags: []string{"db"}}
+	secret := &Endpoint{Name: "secret-db", Group: "prod", Tags: []string{"db", "secret"}}
+	app1 := &Endpoint{Name: "app1", Group: "prod/eu"}
+	endpoints := []*Endpoint{db1, db2, secret, app1}
+
+	config := &Config{
+		Roles: []Role{
+			{Name: "dba", Allow: []string{"tag:db"}, Deny: []string{"tag:secret"}},
+			{Name: "eu", Allow: []string{"group:prod/eu"}},
+		},
+		Users: []User{
+			{Name: "admin"},
+			{Name: "carlos", Roles: []string{"dba"}},
+			{Name: "andrey", Roles: []string{"dba", "eu"}, Deny: []string{"prod/eu/db*"}},
+			{Name: "ayman", Allow: []string{"app*", "db2"}},
+		},
+	}
+
+	for user, expected := range map[string][]*Endpoint{
+		"admin":  endpoints,
+		"carlos": {db1, db2},
+		"andrey": {db2, app1},
+		"ayman":  {db2, app1},
+	} {
+		t.Run(user, func(t *testing.T) {
+			access := userAccess(config, user)
+			require.Equal(t, expected, access.filter(endpoints))
+			for _, e := range endpoints {
+				require.Equal(t, slices.Contains(expected, e), access.allowed(e), e.FullName())
+			}
+		})
+	}
+
+	t.Run("no users", func(t *testing.T) {
+		require.Equal(t, endpoints, userAccess(&Config{Roles: config.Roles}, "carlos").filter(endpoints))
+	})
+}
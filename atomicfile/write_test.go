@@ -0,0 +1,63 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	t.Run("new", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, Write(path, []byte("hello"), 0o600))
+		requireFile(t, path, "hello", 0o600)
+	})
+
+	t.Run("existing", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file")
+		require.NoError(t, os.WriteFile(path, []byte("hello"), 0o640))
+		require.NoError(t, os.Chmod(path, 0o640))
+		require.NoError(t, Write(path, []byte("world"), 0o600))
+		requireFile(t, path, "world", 0o640)
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "dotfiles", "config")
+		require.NoError(t, os.MkdirAll(filepath.Dir(target), 0o700))
+		require.NoError(t, os.WriteFile(target, []byte("hello"), 0o600))
+		link := filepath.Join(dir, "config")
+		require.NoError(t, os.Symlink(filepath.Join("dotfiles", "config"), link))
+
+		require.NoError(t, Write(link, []byte("world"), 0o644))
+		requireFile(t, target, "world", 0o600)
+		info, err := os.Lstat(link)
+		require.NoError(t, err)
+		require.Equal(t, os.ModeSymlink, info.Mode().Type())
+
+		entries, err := os.ReadDir(filepath.Dir(target))
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+	})
+
+	t.Run("missing dir", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "nope", "file")
+		require.Error(t, Write(path, []byte("hello"), 0o600))
+	})
+}
+
+func requireFile(tb testing.TB, path, content string, perm os.FileMode) {
+	tb.Helper()
+	bts, err := os.ReadFile(path)
+	require.NoError(tb, err)
+	require.Equal(tb, content, string(bts))
+	info, err := os.Stat(path)
+	require.NoError(tb, err)
+	require.Equal(tb, perm, info.Mode().Perm())
+}
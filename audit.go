@@ -0,0 +1,326 @@
+package wishlist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Audit log configuration, for connections in server mode.
+type Audit struct {
+	// Path of the audit log, in the JSON lines format. Empty disables it.
+	Path string `yaml:"path"`
+	// MaxSize is the size in megabytes after which the log is rotated, with 0
+	// disabling rotation.
+	MaxSize int64 `yaml:"max_size"`
+	// MaxBackups is how many rotated logs are kept, with 0 keeping them all.
+	MaxBackups int `yaml:"max_backups"`
+}
+
+// audit log events.
+const (
+	auditSessionStart = "session.start"
+	auditSessionEnd   = "session.end"
+	auditLoginDenied  = "login.denied"
+	auditAccessDenied = "access.denied"
+)
+
+// auditEvent is a line of the audit log.
+type auditEvent struct {
+	Time           time.Time  `json:"time"`
+	Event          string     `json:"event"`
+	User           string     `json:"user"`
+	RemoteAddr     string     `json:"remote_addr"`
+	KeyFingerprint string     `json:"key_fingerprint,omitempty"`
+	AuthMethod     string     `json:"auth_method,omitempty"`
+	Endpoint       string     `json:"endpoint,omitempty"`
+	Address        string     `json:"address,omitempty"`
+	Command        string     `json:"command,omitempty"`
+	Subsystem      string     `json:"subsystem,omitempty"`
+	Start          *time.Time `json:"start,omitempty"`
+	End            *time.Time `json:"end,omitempty"`
+	ExitStatus     *int       `json:"exit_status,omitempty"`
+	BytesIn        *int64     `json:"bytes_in,omitempty"`
+	BytesOut       *int64     `json:"bytes_out,omitempty"`
+	Error          string     `json:"error,omitempty"`
+}
+
+// auditLog writes audit events into a file, rotating it once it grows past
+// the max size.
+// A nil auditLog writes nothing, and write errors are logged instead of
+// interrupting the sessions.
+type auditLog struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	f          *os.File
+	size       int64
+}
+
+// openAuditLog opens the configured audit log, if any.
+func openAuditLog(config Audit) (*auditLog, error) {
+	if config.Path == "" {
+		return nil, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(config.Path), 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("could not create audit log dir: %w", err)
+	}
+	a := &auditLog{
+		path:       config.Path,
+		maxSize:    config.MaxSize * 1024 * 1024, //nolint:mnd
+		maxBackups: config.MaxBackups,
+	}
+	if err := a.open(); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (a *auditLog) open() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not open audit log: %w", err)
+	}
+	a.f = f
+	a.size = info.Size()
+	return nil
+}
+
+// write writes the event, setting its time if missing.
+func (a *auditLog) write(event auditEvent) {
+	if a == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	bts, err := json.Marshal(event)
+	if err != nil {
+		log.Warn("could not write audit log", "err", err)
+		return
+	}
+	bts = append(bts, '\n')
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return
+	}
+	if a.maxSize > 0 && a.size > 0 && a.size+int64(len(bts)) > a.maxSize {
+		if err := a.rotate(); err != nil {
+			log.Warn("could not rotate audit log", "err", err)
+			if a.f == nil {
+				return
+			}
+		}
+	}
+	n, err := a.f.Write(bts)
+	a.size += int64(n)
+	if err != nil {
+		log.Warn("could not write audit log", "err", err)
+	}
+}
+
+// rotate renames the current log, suffixed by the current time, starts a new
+// one, and removes the oldest rotated logs over the max backups.
+func (a *auditLog) rotate() error {
+	if err := a.f.Close(); err != nil {
+		return fmt.Errorf("could not close audit log: %w", err)
+	}
+	a.f = nil
+	rotated := a.path + "." + time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z")
+	if err := os.Rename(a.path, rotated); err != nil {
+		return errors.Join(fmt.Errorf("could not rotate audit log: %w", err), a.open())
+	}
+	if err := a.open(); err != nil {
+		return err
+	}
+	if a.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(a.path + ".*")
+	if err != nil {
+		return fmt.Errorf("could not list rotated audit logs: %w", err)
+	}
+	sort.Strings(backups)
+	for len(backups) > a.maxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return fmt.Errorf("could not remove rotated audit log: %w", err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Close closes the audit log.
+func (a *auditLog) Close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.f == nil {
+		return nil
+	}
+	err := a.f.Close()
+	a.f = nil
+	return err //nolint:wrapcheck
+}
+
+// denied records a denied login.
+func (a *auditLog) denied(ctx ssh.Context, key ssh.PublicKey, reason string) {
+	a.write(auditEvent{
+		Event:          auditLoginDenied,
+		User:           ctx.User(),
+		RemoteAddr:     ctx.RemoteAddr().String(),
+		KeyFingerprint: gossh.FingerprintSHA256(key),
+		AuthMethod:     authModePublicKey,
+		Error:          reason,
+	})
+}
+
+// accessDenied records a denied access to an endpoint.
+func (a *auditLog) accessDenied(s ssh.Session, e *Endpoint) {
+	event := sessionEvent(s, e)
+	event.Event = auditAccessDenied
+	a.write(event)
+}
+
+// session starts auditing the session to the given endpoint, running the
+// given command, if any.
+func (a *auditLog) session(s ssh.Session, e *Endpoint, command string) *auditSession {
+	if a == nil {
+		return nil
+	}
+	event := sessionEvent(s, e)
+	event.Command = command
+	event.Subsystem = s.Subsystem()
+	return &auditSession{log: a, event: event, start: time.Now()}
+}
+
+// sessionEvent returns an event of the user's session to the endpoint.
+func sessionEvent(s ssh.Session, e *Endpoint) auditEvent {
+	event := auditEvent{
+		User:       s.User(),
+		RemoteAddr: s.RemoteAddr().String(),
+		AuthMethod: "none",
+		Endpoint:   e.FullName(),
+		Address:    e.Address,
+	}
+	if key := s.PublicKey(); key != nil {
+		event.KeyFingerprint = gossh.FingerprintSHA256(key)
+		event.AuthMethod = authModePublicKey
+	}
+	return event
+}
+
+// auditSession audits a session to an endpoint, from the connection until
+// its end.
+// A nil auditSession audits nothing.
+type auditSession struct {
+	log      *auditLog
+	event    auditEvent
+	start    time.Time
+	bytesIn  atomic.Int64
+	bytesOut atomic.Int64
+}
+
+// connected records the session start, once connected to the endpoint.
+func (a *auditSession) connected() {
+	if a == nil {
+		return
+	}
+	event := a.event
+	event.Event = auditSessionStart
+	event.Time = time.Now()
+	event.Start = &a.start
+	a.log.write(event)
+}
+
+// end records the session end, with the error it ended with, if any.
+func (a *auditSession) end(err error) {
+	var exitErr *gossh.ExitError
+	if errors.As(err, &exitErr) {
+		a.exited(exitErr.ExitStatus(), nil)
+		return
+	}
+	a.exited(0, err)
+}
+
+// exited records the session end, with the exit status of its command, or
+// the error it failed with, if any.
+func (a *auditSession) exited(status int, err error) {
+	if a == nil {
+		return
+	}
+	event := a.event
+	event.Event = auditSessionEnd
+	event.Time = time.Now()
+	event.Start = &a.start
+	event.End = &event.Time
+	in, out := a.bytesIn.Load(), a.bytesOut.Load()
+	event.BytesIn = &in
+	event.BytesOut = &out
+
+	if err != nil {
+		event.Error = err.Error()
+	} else {
+		event.ExitStatus = &status
+	}
+	a.log.write(event)
+}
+
+// input returns a reader counting the bytes read from r as input.
+func (a *auditSession) input(r io.Reader) io.Reader {
+	if a == nil {
+		return r
+	}
+	return &countingReader{r: r, n: &a.bytesIn}
+}
+
+// output returns a writer counting the bytes written into w as output.
+func (a *auditSession) output(w io.Writer) io.Writer {
+	if a == nil {
+		return w
+	}
+	return &countingWriter{w: w, n: &a.bytesOut}
+}
+
+type countingReader struct {
+	r io.Reader
+	n *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n.Add(int64(n))
+	return n, err //nolint:wrapcheck
+}
+
+type countingWriter struct {
+	w io.Writer
+	n *atomic.Int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n.Add(int64(n))
+	return n, err //nolint:wrapcheck
+}
@@ -0,0 +1,216 @@
+package wishlist
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestAuditLog(t *testing.T) {
+	t.Run("disabled", func(t *testing.T) {
+		audit, err := openAuditLog(Audit{})
+		require.NoError(t, err)
+		require.Nil(t, audit)
+		audit.write(auditEvent{Event: auditLoginDenied})
+		require.Nil(t, audit.session(nil, nil, ""))
+		require.NoError(t, audit.Close())
+	})
+
+	t.Run("write", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "logs", "audit.log")
+		audit, err := openAuditLog(Audit{Path: path})
+		require.NoError(t, err)
+		audit.write(auditEvent{Event: auditLoginDenied, User: "carlos"})
+		require.NoError(t, audit.Close())
+
+		// appends to existing logs
+		audit, err = openAuditLog(Audit{Path: path})
+		require.NoError(t, err)
+		audit.write(auditEvent{Event: auditLoginDenied, User: "andrey"})
+		require.NoError(t, audit.Close())
+
+		events := readAuditLog(t, path)
+		require.Len(t, events, 2)
+		require.Equal(t, "carlos", events[0]["user"])
+		require.Equal(t, "andrey", events[1]["user"])
+		require.NotEmpty(t, events[0]["time"])
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		audit, err := openAuditLog(Audit{Path: path, MaxBackups: 2})
+		require.NoError(t, err)
+		audit.maxSize = 100
+		for range 8 {
+			audit.write(auditEvent{Event: auditLoginDenied, User: "carlos"})
+		}
+		require.NoError(t, audit.Close())
+
+		backups, err := filepath.Glob(path + ".*")
+		require.NoError(t, err)
+		require.Len(t, backups, 2)
+		for _, p := range append(backups, path) {
+			require.Len(t, readAuditLog(t, p), 1)
+		}
+	})
+}
+
+func TestAuditSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(Audit{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = audit.Close() })
+
+	e := &Endpoint{Name: "db1", Group: "prod", Address: "db1.local:22", RemoteCommand: "uptime"}
+	srv := &ssh.Server{
+		Handler: func(s ssh.Session) {
+			session := audit.session(s, e, e.RemoteCommand)
+			session.connected()
+			_, _ = io.Copy(session.output(s), session.input(strings.NewReader("hello")))
+			session.end(errors.New("session failed"))
+		},
+		PublicKeyHandler: func(ssh.Context, ssh.PublicKey) bool { return true },
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	signer := newTestSigner(t)
+	client, err := gossh.Dial("tcp", l.Addr().String(), &gossh.ClientConfig{
+		User:            "carlos",
+		Auth:            []gossh.AuthMethod{gossh.PublicKeys(signer)},
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	out, err := session.Output("")
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(out))
+
+	events := readAuditLog(t, path)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		require.Equal(t, "carlos", event["user"])
+		require.Equal(t, gossh.FingerprintSHA256(signer.PublicKey()), event["key_fingerprint"])
+		require.Equal(t, authModePublicKey, event["auth_method"])
+		require.Equal(t, "prod/db1", event["endpoint"])
+		require.Equal(t, "db1.local:22", event["address"])
+		require.Equal(t, "uptime", event["command"])
+		require.Contains(t, event["remote_addr"], "127.0.0.1:")
+		require.NotEmpty(t, event["start"])
+	}
+	require.Equal(t, auditSessionStart, events[0]["event"])
+	require.NotContains(t, events[0], "end")
+	require.Equal(t, auditSessionEnd, events[1]["event"])
+	require.NotEmpty(t, events[1]["end"])
+	require.InDelta(t, 5, events[1]["bytes_in"], 0)
+	require.InDelta(t, 5, events[1]["bytes_out"], 0)
+	require.Equal(t, "session failed", events[1]["error"])
+	require.NotContains(t, events[1], "exit_status")
+}
+
+func TestAuditRunCommand(t *testing.T) {
+	t.Chdir(t.TempDir()) // the client key is written in the working directory
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(Audit{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = audit.Close() })
+
+	e := &Endpoint{Name: "db1", Address: forwardingServer(t, func(s ssh.Session) {
+		_, _ = s.Write([]byte("out"))
+		_, _ = s.Stderr().Write([]byte("err"))
+		_ = s.Exit(3)
+	})}
+	srv := &ssh.Server{
+		Handler: func(s ssh.Session) {
+			client := &remoteClient{session: s, audit: audit}
+			for result := range client.RunCommand(context.Background(), []*Endpoint{e}, "uptime") {
+				_, _ = s.Write(result.stdout)
+			}
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	client, err := gossh.Dial("tcp", l.Addr().String(), &gossh.ClientConfig{
+		User:            "carlos",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+	session, err := client.NewSession()
+	require.NoError(t, err)
+	out, err := session.Output("")
+	require.NoError(t, err)
+	require.Equal(t, "out", string(out))
+
+	events := readAuditLog(t, path)
+	require.Len(t, events, 2)
+	for _, event := range events {
+		require.Equal(t, "carlos", event["user"])
+		require.Equal(t, "db1", event["endpoint"])
+		require.Equal(t, "uptime", event["command"])
+	}
+	require.Equal(t, auditSessionStart, events[0]["event"])
+	require.Equal(t, auditSessionEnd, events[1]["event"])
+	require.InDelta(t, 3, events[1]["exit_status"], 0)
+	require.InDelta(t, 6, events[1]["bytes_out"], 0)
+}
+
+func TestAuditDenied(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(Audit{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = audit.Close() })
+
+	pubkey := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIMYKQ6pT3+iZBROfFKKT/4GVc1Xws776bE67cF3zUQPS foo@bar"
+	pubkey2 := "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIDfBMpbghW82c1zk9LauP7G/LqXtTeQrU6Do9FUY1FJ5 foo@bar"
+	k, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubkey))
+	require.NoError(t, err)
+	handler := publicKeyAccessOption([]User{{Name: "test", PublicKeys: []string{pubkey2}}}, nil, nil, audit)
+	require.False(t, handler(fakeCtx{}, k))
+
+	events := readAuditLog(t, path)
+	require.Len(t, events, 1)
+	require.Equal(t, auditLoginDenied, events[0]["event"])
+	require.Equal(t, "test", events[0]["user"])
+	require.Equal(t, gossh.FingerprintSHA256(k), events[0]["key_fingerprint"])
+	require.Equal(t, "unauthorized", events[0]["error"])
+}
+
+// readAuditLog returns the events in the audit log in the given path.
+func readAuditLog(tb testing.TB, path string) []map[string]any {
+	tb.Helper()
+	f, err := os.Open(path)
+	require.NoError(tb, err)
+	defer f.Close() //nolint:errcheck
+	var events []map[string]any
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event map[string]any
+		require.NoError(tb, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	require.NoError(tb, scanner.Err())
+	return events
+}
@@ -0,0 +1,439 @@
+package wishlist
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wishlist/home"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// defaultAuthorizedKeysRefresh is how often authorized keys are reloaded from
+// their sources by default.
+const defaultAuthorizedKeysRefresh = 5 * time.Minute
+
+// max size of authorized keys fetched from URLs.
+const maxAuthorizedKeysSize = 1024 * 1024
+
+// authorizedKey is a key allowed to log in, along with the restrictions of
+// its authorized_keys options.
+type authorizedKey struct {
+	key    gossh.PublicKey
+	from   []string  // patterns of the allowed remote addresses, if any.
+	expiry time.Time // time after which the key can't be used, if any.
+}
+
+// newAuthorizedKey returns the key with the given authorized_keys options.
+// Options restricting what the key can do once logged in, e.g. `no-pty`, are
+// ignored, as users can only list and connect to endpoints, but options that
+// can't be honored, e.g. `command=`, return an error, so the key is ignored.
+func newAuthorizedKey(key gossh.PublicKey, options []string) (authorizedKey, error) {
+	result := authorizedKey{key: key}
+	for _, option := range options {
+		name, value, _ := strings.Cut(option, "=")
+		value = strings.Trim(value, `"`)
+		switch strings.ToLower(name) {
+		case "from":
+			result.from = strings.Split(value, ",")
+		case "expiry-time":
+			expiry, err := parseExpiryTime(value)
+			if err != nil {
+				return result, err
+			}
+			result.expiry = expiry
+		case "command", "cert-authority", "principals":
+			return result, fmt.Errorf("unsupported option %q", name)
+		}
+	}
+	return result, nil
+}
+
+// parseExpiryTime parses an expiry-time option, in the YYYYMMDD[HHMM[SS]]
+// format, in local time unless suffixed by Z.
+func parseExpiryTime(s string) (time.Time, error) {
+	loc := time.Local
+	if v, ok := strings.CutSuffix(s, "Z"); ok {
+		s, loc = v, time.UTC
+	}
+	for _, layout := range []string{"20060102", "200601021504", "20060102150405"} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid expiry-time: %q", s)
+}
+
+// check returns an error if the key can't be used now from the given remote
+// address.
+func (k authorizedKey) check(remote net.Addr, now time.Time) error {
+	if !k.expiry.IsZero() && !now.Before(k.expiry) {
+		return fmt.Errorf("key expired at %s", k.expiry.Format(time.RFC3339))
+	}
+	if len(k.from) > 0 && !matchFrom(k.from, remote) {
+		return fmt.Errorf("key not allowed from %s", remote)
+	}
+	return nil
+}
+
+// matchFrom returns whether the remote address matches the patterns of a
+// from option.
+// As in OpenSSH, patterns are globs or CIDRs, and negated ones, prefixed by
+// `!`, take precedence.
+// Only IP addresses are matched, as host names aren't resolved.
+func matchFrom(patterns []string, remote net.Addr) bool {
+	host, _, err := net.SplitHostPort(remote.String())
+	if err != nil {
+		host = remote.String()
+	}
+	ip := net.ParseIP(host)
+	matched := false
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		negated := strings.HasPrefix(pattern, "!")
+		pattern = strings.TrimPrefix(pattern, "!")
+
+		var match bool
+		if _, ipnet, err := net.ParseCIDR(pattern); err == nil {
+			match = ip != nil && ipnet.Contains(ip)
+		} else {
+			match = matchGlob(strings.ToLower(pattern), host)
+		}
+		if match && negated {
+			return false
+		}
+		matched = matched || match
+	}
+	return matched
+}
+
+// parseAuthorizedKeys parses keys in the authorized_keys format.
+// Invalid lines are logged and skipped, as OpenSSH does.
+func parseAuthorizedKeys(source string, data []byte) []authorizedKey {
+	var keys []authorizedKey
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 || text[0] == '#' {
+			continue
+		}
+		key, _, options, _, err := gossh.ParseAuthorizedKey(text)
+		if err == nil {
+			var ak authorizedKey
+			ak, err = newAuthorizedKey(key, options)
+			if err == nil {
+				keys = append(keys, ak)
+				continue
+			}
+		}
+		log.Warn("ignoring authorized key", "source", source, "line", line, "err", err)
+	}
+	return keys
+}
+
+// loadAuthorizedKeys loads the keys from the given source: an HTTP(S) URL, a
+// file in the authorized_keys format, or a directory of .pub files.
+func loadAuthorizedKeys(ctx context.Context, client *http.Client, source string) ([]authorizedKey, error) {
+	if strings.HasPrefix(source, "https://") || strings.HasPrefix(source, "http://") {
+		bts, err := fetchAuthorizedKeys(ctx, client, source)
+		if err != nil {
+			return nil, err
+		}
+		return parseAuthorizedKeys(source, bts), nil
+	}
+
+	path, err := home.ExpandPath(source)
+	if err != nil {
+		return nil, fmt.Errorf("could not expand %q: %w", source, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not load authorized keys: %w", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.pub"))
+		if err != nil {
+			return nil, fmt.Errorf("could not list %q: %w", path, err)
+		}
+	}
+	var keys []authorizedKey
+	for _, file := range files {
+		bts, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not load authorized keys: %w", err)
+		}
+		keys = append(keys, parseAuthorizedKeys(file, bts)...)
+	}
+	return keys, nil
+}
+
+func fetchAuthorizedKeys(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch authorized keys: %w", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch authorized keys: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch authorized keys from %q: %s", url, resp.Status)
+	}
+	bts, err := io.ReadAll(io.LimitReader(resp.Body, maxAuthorizedKeysSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch authorized keys: %w", err)
+	}
+	if len(bts) > maxAuthorizedKeysSize {
+		return nil, fmt.Errorf("could not fetch authorized keys from %q: too large", url)
+	}
+	return bts, nil
+}
+
+// expandAuthorizedKeysSource expands the tokens of a source of authorized
+// keys of the given user, as OpenSSH's AuthorizedKeysFile: %u is replaced by
+// the user name, and %% by %.
+func expandAuthorizedKeysSource(source, user string) string {
+	return strings.NewReplacer("%%", "%", "%u", user).Replace(source)
+}
+
+// max number of sources loaded on login kept at the same time.
+const maxLoginSources = 1024
+
+// keyStore keeps the authorized keys of the users loaded from their sources,
+// so they are not loaded on each login.
+// Sources that fail to load keep their last loaded keys.
+//
+// If there are no users in the config, the sources of the login names are
+// loaded on login instead, and kept for the refresh interval, even if they
+// fail to load, so retries don't load them again.
+// Only the ones a login succeeded with since are refreshed, the others are
+// dropped, and at most maxLoginSources are kept, dropping the oldest ones.
+type keyStore struct {
+	client *http.Client
+	ttl    time.Duration
+
+	// own sources of each user in the config, if any.
+	users map[string][]string
+	// sources of all users, with %u expanded on login.
+	global []string
+
+	mu    sync.RWMutex
+	keys  map[string][]authorizedKey // of the users in the config, by source
+	login map[string]*loginSource    // by source
+}
+
+// loginSource is a source loaded on login.
+type loginSource struct {
+	keys   []authorizedKey
+	loaded time.Time
+	// whether a login succeeded with the keys since they were loaded.
+	used bool
+}
+
+// newKeyStore returns a store of the keys of the given users, from their own
+// sources and from the ones of the whole config, or nil if there are none.
+// Keys are kept for the given refresh interval.
+func newKeyStore(users []User, sources []string, ttl time.Duration) *keyStore {
+	store := &keyStore{
+		client: &http.Client{Timeout: 10 * time.Second}, //nolint:mnd
+		ttl:    ttl,
+		users:  map[string][]string{},
+		global: sources,
+		keys:   map[string][]authorizedKey{},
+		login:  map[string]*loginSource{},
+	}
+	found := len(sources) > 0
+	for _, user := range users {
+		var own []string
+		for _, source := range user.AuthorizedKeys {
+			own = append(own, expandAuthorizedKeysSource(source, user.Name))
+		}
+		store.users[user.Name] = append(store.users[user.Name], own...)
+		found = found || len(own) > 0
+	}
+	if !found {
+		return nil
+	}
+	return store
+}
+
+// validLoginName matches the login names the sources of all users are
+// expanded with, so they can't point to other files or URLs, e.g. `../root`.
+var validLoginName = regexp.MustCompile(`^[a-zA-Z0-9_][a-zA-Z0-9._@-]*$`)
+
+// sources returns the sources of the keys of the given user, if it's in the
+// config.
+func (s *keyStore) sources(user string) []string {
+	own, ok := s.users[user]
+	if !ok {
+		return nil
+	}
+	result := slices.Clone(own)
+	for _, source := range s.global {
+		result = append(result, expandAuthorizedKeysSource(source, user))
+	}
+	return result
+}
+
+// loginSources returns the sources of the keys of the given login name, which
+// are loaded on login.
+// If there are users in the config, the user must be one of them, so their
+// permissions apply, as with certificates.
+func (s *keyStore) loginSources(user string) []string {
+	if len(s.users) > 0 || !validLoginName.MatchString(user) {
+		return nil
+	}
+	result := make([]string, 0, len(s.global))
+	for _, source := range s.global {
+		result = append(result, expandAuthorizedKeysSource(source, user))
+	}
+	return result
+}
+
+// refresh reloads the keys from the sources of the users in the config, and
+// from the ones loaded on login a login succeeded with since.
+func (s *keyStore) refresh(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	var sources []string
+	for user := range s.users {
+		sources = append(sources, s.sources(user)...)
+	}
+	slices.Sort(sources)
+	for _, source := range slices.Compact(sources) {
+		keys, err := loadAuthorizedKeys(ctx, s.client, source)
+		if err != nil {
+			log.Warn("could not load authorized keys, keeping the last ones", "source", source, "err", err)
+			continue
+		}
+		log.Debug("loaded authorized keys", "source", source, "keys", len(keys))
+		s.mu.Lock()
+		s.keys[source] = keys
+		s.mu.Unlock()
+	}
+
+	var used []string
+	s.mu.Lock()
+	for source, ls := range s.login {
+		if !ls.used {
+			delete(s.login, source)
+			continue
+		}
+		used = append(used, source)
+	}
+	s.mu.Unlock()
+	for _, source := range used {
+		s.loadOnLogin(ctx, source, true)
+	}
+}
+
+// loadOnLogin loads the keys from the source, unless they were loaded less
+// than the refresh interval ago, or force is set.
+func (s *keyStore) loadOnLogin(ctx context.Context, source string, force bool) {
+	s.mu.RLock()
+	last, ok := s.login[source]
+	s.mu.RUnlock()
+	if ok && !force && time.Since(last.loaded) < s.ttl {
+		return
+	}
+
+	keys, err := loadAuthorizedKeys(ctx, s.client, source)
+	if err != nil {
+		// logged at debug level, as anyone can try any login name.
+		log.Debug("could not load authorized keys", "source", source, "err", err)
+		if ok {
+			keys = last.keys
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.login[source] = &loginSource{keys: keys, loaded: time.Now()}
+	for len(s.login) > maxLoginSources {
+		var oldest string
+		for source, ls := range s.login {
+			if oldest == "" || ls.loaded.Before(s.login[oldest].loaded) {
+				oldest = source
+			}
+		}
+		delete(s.login, oldest)
+	}
+}
+
+// run refreshes the keys periodically, until the context is done.
+func (s *keyStore) run(ctx context.Context, interval time.Duration) {
+	if s == nil {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.refresh(ctx)
+		}
+	}
+}
+
+// check returns whether the key is one of the user's authorized keys, and if
+// so, an error if it can't be used from the remote address.
+func (s *keyStore) check(ctx context.Context, user string, key ssh.PublicKey, remote net.Addr) (bool, error) {
+	if s == nil {
+		return false, nil
+	}
+	login := s.loginSources(user)
+	for _, source := range login {
+		s.loadOnLogin(ctx, source, false)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	match := func(keys []authorizedKey) bool {
+		for _, ak := range keys {
+			if !ssh.KeysEqual(ak.key, key) {
+				continue
+			}
+			err := ak.check(remote, time.Now())
+			if err == nil {
+				return true
+			}
+			errs = append(errs, err)
+		}
+		return false
+	}
+	for _, source := range s.sources(user) {
+		if match(s.keys[source]) {
+			return true, nil
+		}
+	}
+	for _, source := range login {
+		if ls := s.login[source]; ls != nil && match(ls.keys) {
+			ls.used = true
+			return true, nil
+		}
+	}
+	return len(errs) > 0, errors.Join(errs...)
+}
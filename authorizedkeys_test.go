@@ -0,0 +1,312 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestParseExpiryTime(t *testing.T) {
+	for s, expected := range map[string]time.Time{
+		"20240102":        time.Date(2024, 1, 2, 0, 0, 0, 0, time.Local),
+		"202401021504":    time.Date(2024, 1, 2, 15, 4, 0, 0, time.Local),
+		"20240102150405":  time.Date(2024, 1, 2, 15, 4, 5, 0, time.Local),
+		"20240102150405Z": time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC),
+	} {
+		t.Run(s, func(t *testing.T) {
+			got, err := parseExpiryTime(s)
+			require.NoError(t, err)
+			require.True(t, expected.Equal(got), got)
+		})
+	}
+
+	for _, s := range []string{"", "2024", "2024010", "20241301", "tomorrow"} {
+		t.Run(s, func(t *testing.T) {
+			_, err := parseExpiryTime(s)
+			require.EqualError(t, err, `invalid expiry-time: "`+s+`"`)
+		})
+	}
+}
+
+func TestMatchFrom(t *testing.T) {
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}
+	for patterns, expected := range map[string]bool{
+		"10.0.0.5":              true,
+		"10.0.0.*":              true,
+		"10.0.0.0/24":           true,
+		"192.168.0.1,10.0.0.?":  true,
+		"192.168.0.0/16":        false,
+		"10.0.0.*,!10.0.0.5":    false,
+		"!10.0.0.0/8,10.0.0.5":  false,
+		"!192.168.0.1,10.0.0.5": true,
+		"!192.168.0.1":          false,
+	} {
+		t.Run(patterns, func(t *testing.T) {
+			key, err := newAuthorizedKey(newTestSigner(t).PublicKey(), []string{`from="` + patterns + `"`})
+			require.NoError(t, err)
+			require.Equal(t, expected, matchFrom(key.from, addr))
+		})
+	}
+}
+
+func TestAuthorizedKeyCheck(t *testing.T) {
+	pub := newTestSigner(t).PublicKey()
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+
+	key, err := newAuthorizedKey(pub, []string{"no-pty", `expiry-time="20240102Z"`})
+	require.NoError(t, err)
+	require.EqualError(t, key.check(addr, now), "key expired at 2024-01-02T00:00:00Z")
+
+	key, err = newAuthorizedKey(pub, []string{`expiry-time="20240103Z"`, `from="192.168.0.0/16"`})
+	require.NoError(t, err)
+	require.EqualError(t, key.check(addr, now), "key not allowed from 10.0.0.5:51234")
+
+	key, err = newAuthorizedKey(pub, []string{`expiry-time="20240103Z"`, `from="10.0.0.0/8"`})
+	require.NoError(t, err)
+	require.NoError(t, key.check(addr, now))
+
+	_, err = newAuthorizedKey(pub, []string{`command="uptime"`})
+	require.EqualError(t, err, `unsupported option "command"`)
+
+	_, err = newAuthorizedKey(pub, []string{`expiry-time="soon"`})
+	require.EqualError(t, err, `invalid expiry-time: "soon"`)
+}
+
+func TestLoadAuthorizedKeys(t *testing.T) {
+	key1 := authorizedKeyLine(t, "")
+	key2 := authorizedKeyLine(t, `from="10.0.0.0/8" `)
+	ignored := authorizedKeyLine(t, `command="uptime" `)
+	content := "# comment\n\n" + key1 + "nope\n" + ignored + key2
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "authorized_keys")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		keys, err := loadAuthorizedKeys(context.Background(), http.DefaultClient, path)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+		require.Empty(t, keys[0].from)
+		require.Equal(t, []string{"10.0.0.0/8"}, keys[1].from)
+	})
+
+	t.Run("dir", func(t *testing.T) {
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "a.pub"), []byte(key1), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "b.pub"), []byte(key2), 0o600))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "c"), []byte(authorizedKeyLine(t, "")), 0o600))
+		keys, err := loadAuthorizedKeys(context.Background(), http.DefaultClient, dir)
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+	})
+
+	t.Run("url", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			_, _ = w.Write([]byte(content))
+		}))
+		t.Cleanup(srv.Close)
+		keys, err := loadAuthorizedKeys(context.Background(), srv.Client(), srv.URL+"/carlos.keys")
+		require.NoError(t, err)
+		require.Len(t, keys, 2)
+	})
+
+	t.Run("url not found", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		t.Cleanup(srv.Close)
+		_, err := loadAuthorizedKeys(context.Background(), srv.Client(), srv.URL+"/carlos.keys")
+		require.ErrorContains(t, err, "404 Not Found")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		_, err := loadAuthorizedKeys(context.Background(), http.DefaultClient, filepath.Join(t.TempDir(), "nope"))
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+func TestKeyStore(t *testing.T) {
+	require.Nil(t, newKeyStore([]User{{Name: "carlos"}}, nil, time.Minute))
+
+	signer := newTestSigner(t)
+	key := string(gossh.MarshalAuthorizedKey(signer.PublicKey()))
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.URL.Path == "/carlos.keys" {
+			_, _ = w.Write([]byte(key))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "andrey"), []byte(`expiry-time="20000101" `+key), 0o600))
+
+	store := newKeyStore([]User{
+		{Name: "carlos"},
+		{Name: "andrey"},
+		{Name: "ayman", AuthorizedKeys: []string{filepath.Join(dir, "andrey")}},
+	}, []string{srv.URL + "/%u.keys", filepath.Join(dir, "%u")}, time.Minute)
+	require.Equal(t, []string{filepath.Join(dir, "andrey"), srv.URL + "/ayman.keys", filepath.Join(dir, "ayman")}, store.sources("ayman"))
+	store.client = srv.Client()
+	store.refresh(context.Background())
+
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}
+	ok, err := store.check(context.Background(), "carlos", signer.PublicKey(), addr)
+	require.True(t, ok)
+	require.NoError(t, err)
+
+	ok, err = store.check(context.Background(), "andrey", signer.PublicKey(), addr)
+	require.True(t, ok)
+	require.ErrorContains(t, err, "key expired")
+
+	ok, err = store.check(context.Background(), "nope", signer.PublicKey(), addr)
+	require.False(t, ok)
+	require.NoError(t, err)
+
+	t.Run("keeps the last keys", func(t *testing.T) {
+		fail.Store(true)
+		store.refresh(context.Background())
+		ok, err := store.check(context.Background(), "carlos", signer.PublicKey(), addr)
+		require.True(t, ok)
+		require.NoError(t, err)
+	})
+
+	t.Run("access", func(t *testing.T) {
+		handler := publicKeyAccessOption([]User{{Name: "carlos"}, {Name: "andrey"}}, nil, store, nil)
+		ctx := addrCtx{addr: addr}
+		require.False(t, handler(ctx, signer.PublicKey())) // fakeCtx logs in as test
+		require.False(t, handler(userCtx{ctx, "andrey"}, signer.PublicKey()))
+		require.True(t, handler(userCtx{ctx, "carlos"}, signer.PublicKey()))
+	})
+}
+
+func TestKeyStoreLoginSources(t *testing.T) {
+	signer := newTestSigner(t)
+	key := string(gossh.MarshalAuthorizedKey(signer.PublicKey()))
+	var requests atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/carlos.keys" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(key))
+	}))
+	t.Cleanup(srv.Close)
+
+	store := newKeyStore(nil, []string{srv.URL + "/%u.keys"}, time.Minute)
+	store.client = srv.Client()
+	addr := &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}
+	check := func(user string) bool {
+		ok, err := store.check(context.Background(), user, signer.PublicKey(), addr)
+		require.NoError(t, err)
+		return ok
+	}
+
+	t.Run("failed loads are kept", func(t *testing.T) {
+		require.False(t, check("nobody"))
+		require.False(t, check("nobody"))
+		require.Equal(t, int64(1), requests.Load())
+	})
+
+	t.Run("loaded keys are kept", func(t *testing.T) {
+		require.True(t, check("carlos"))
+		require.True(t, check("carlos"))
+		require.Equal(t, int64(2), requests.Load())
+	})
+
+	t.Run("refresh only the used ones", func(t *testing.T) {
+		store.refresh(context.Background())
+		require.Equal(t, int64(3), requests.Load())
+		require.Len(t, store.login, 1)
+		require.Contains(t, store.login, srv.URL+"/carlos.keys")
+
+		// not used since the last refresh
+		store.refresh(context.Background())
+		require.Equal(t, int64(3), requests.Load())
+		require.Empty(t, store.login)
+	})
+
+	t.Run("bounded", func(t *testing.T) {
+		for i := range maxLoginSources + 10 {
+			require.False(t, check(fmt.Sprintf("user%d", i)))
+		}
+		require.Len(t, store.login, maxLoginSources)
+	})
+}
+
+func TestPublicKeyHandlerAuthorizedKeysOnly(t *testing.T) {
+	signer := newTestSigner(t)
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "carlos"), gossh.MarshalAuthorizedKey(signer.PublicKey()), 0o600))
+
+	// no users in the config: any login name with a key in its source.
+	store := newKeyStore(nil, []string{filepath.Join(dir, "%u")}, time.Minute)
+	require.NotNil(t, store)
+	store.refresh(context.Background())
+	handler := publicKeyAccessOption(nil, nil, store, nil)
+	require.NotNil(t, handler)
+
+	ctx := addrCtx{addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}}
+	require.True(t, handler(userCtx{ctx, "carlos"}, signer.PublicKey()))
+	require.False(t, handler(userCtx{ctx, "carlos"}, newTestSigner(t).PublicKey()))
+	require.False(t, handler(userCtx{ctx, "andrey"}, signer.PublicKey()))
+	require.False(t, handler(userCtx{ctx, "../" + filepath.Base(dir) + "/carlos"}, signer.PublicKey()))
+	require.True(t, authenticates(&Config{AuthorizedKeys: []string{filepath.Join(dir, "%u")}}))
+}
+
+func TestPublicKeyHandlerOptions(t *testing.T) {
+	signer := newTestSigner(t)
+	ctx := addrCtx{addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}}
+	for options, expected := range map[string]bool{
+		`from="10.0.0.0/8" `:      true,
+		`from="192.168.0.0/16" `:  false,
+		`expiry-time="20000101" `: false,
+		`command="uptime" `:       false,
+	} {
+		t.Run(options, func(t *testing.T) {
+			handler := publicKeyAccessOption([]User{{
+				Name:       "test",
+				PublicKeys: []string{options + string(gossh.MarshalAuthorizedKey(signer.PublicKey()))},
+			}}, nil, nil, nil)
+			require.Equal(t, expected, handler(ctx, signer.PublicKey()))
+		})
+	}
+
+	t.Run("unsupported options are ignored", func(t *testing.T) {
+		key := string(gossh.MarshalAuthorizedKey(signer.PublicKey()))
+		handler := publicKeyAccessOption([]User{{
+			Name:       "test",
+			PublicKeys: []string{`command="uptime" ` + key, key},
+		}}, nil, nil, nil)
+		require.True(t, handler(ctx, signer.PublicKey()))
+	})
+}
+
+// authorizedKeyLine returns an authorized_keys line of a new key, with the
+// given options.
+func authorizedKeyLine(tb testing.TB, options string) string {
+	tb.Helper()
+	return options + string(gossh.MarshalAuthorizedKey(newTestSigner(tb).PublicKey()))
+}
+
+// userCtx is a context of the given user.
+type userCtx struct {
+	addrCtx
+	user string
+}
+
+func (ctx userCtx) User() string {
+	return ctx.user
+}
@@ -0,0 +1,299 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// durationPrecision is the precision command durations are shown with.
+const durationPrecision = time.Millisecond
+
+// commandTimeout is how long commands can run on several endpoints before
+// being stopped.
+const commandTimeout = 10 * time.Minute
+
+// broadcastRun holds the state of a command being run on several endpoints.
+type broadcastRun struct {
+	command   string
+	endpoints []*Endpoint
+	results   []*commandResult
+	viewport  viewport.Model
+
+	// ctx is done once the results are closed, or they time out.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func newBroadcastRun(command string, endpoints []*Endpoint) *broadcastRun {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	return &broadcastRun{
+		command:   command,
+		endpoints: endpoints,
+		results:   make([]*commandResult, len(endpoints)),
+		viewport:  viewport.New(0, 0),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// commandResultMsg is sent when a command finishes running on an endpoint.
+type commandResultMsg struct {
+	broadcast *broadcastRun
+	result    commandResult
+	ch        <-chan commandResult
+}
+
+// waitForResult waits for the next result of the given broadcast.
+func waitForResult(b *broadcastRun, ch <-chan commandResult) tea.Cmd {
+	return func() tea.Msg {
+		result, ok := <-ch
+		if !ok {
+			return nil
+		}
+		return commandResultMsg{
+			broadcast: b,
+			result:    result,
+			ch:        ch,
+		}
+	}
+}
+
+// set sets the result for its endpoint.
+func (b *broadcastRun) set(result commandResult) {
+	for i, e := range b.endpoints {
+		if e == result.endpoint {
+			b.results[i] = &result
+			return
+		}
+	}
+}
+
+// pending returns how many endpoints are still running the command.
+func (b *broadcastRun) pending() int {
+	var n int
+	for _, r := range b.results {
+		if r == nil {
+			n++
+		}
+	}
+	return n
+}
+
+// title returns the title of the results view.
+func (b *broadcastRun) title() string {
+	title := fmt.Sprintf("Ran %q on %d endpoints", b.command, len(b.endpoints))
+	if n := b.pending(); n > 0 {
+		title = fmt.Sprintf("Running %q on %d endpoints, %d pending", b.command, len(b.endpoints), n)
+	}
+	return title
+}
+
+// render renders the results of all endpoints.
+func (b *broadcastRun) render(styles styles) string {
+	var sb strings.Builder
+	for i, e := range b.endpoints {
+		result := b.results[i]
+		sb.WriteString(styles.Header.Render(e.Name) + " ")
+		switch {
+		case result == nil:
+			sb.WriteString(styles.NoContent.Render("running..."))
+		case result.err != nil:
+			sb.WriteString(styles.Err.Render(rootCause(result.err).Error()))
+		case result.exitStatus != 0:
+			sb.WriteString(styles.Err.Render(fmt.Sprintf("exit status %d", result.exitStatus)))
+		default:
+			sb.WriteString(styles.Success.Render("exit status 0"))
+		}
+		if result != nil {
+			sb.WriteString(styles.NoContent.Render(fmt.Sprintf(" (%s)", result.duration.Round(durationPrecision))))
+			if out := strings.TrimRight(string(result.stdout), "\n"); out != "" {
+				sb.WriteString("\n" + out)
+			}
+			if out := strings.TrimRight(string(result.stderr), "\n"); out != "" {
+				sb.WriteString("\n" + styles.Err.Render(out))
+			}
+		}
+		sb.WriteString("\n\n")
+	}
+	return sb.String()
+}
+
+// plain returns the output of all endpoints as plain text.
+func (b *broadcastRun) plain() string {
+	var sb strings.Builder
+	for i, e := range b.endpoints {
+		result := b.results[i]
+		if result == nil {
+			continue
+		}
+		fmt.Fprintf(&sb, "# %s (exit status %d)\n", e.Name, result.exitStatus)
+		if result.err != nil {
+			fmt.Fprintf(&sb, "# error: %s\n", result.err)
+		}
+		sb.Write(result.stdout)
+		sb.Write(result.stderr)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// markedEndpoints returns the marked endpoints, or the selected one if none
+// is marked.
+func (m *ListModel) markedEndpoints() []*Endpoint {
+	var result []*Endpoint
+	for _, e := range m.endpoints {
+		if e.Valid() && m.marked[e.FullName()] {
+			result = append(result, e)
+		}
+	}
+	if len(result) == 0 {
+		if w := m.selected(); w != nil {
+			result = append(result, w.endpoint)
+		}
+	}
+	return result
+}
+
+// markedNames returns the full names of the marked endpoints.
+func (m *ListModel) markedNames() []string {
+	names := make([]string, 0, len(m.marked))
+	for name := range m.marked {
+		names = append(names, name)
+	}
+	return names
+}
+
+// unmarkMissing unmarks endpoints which are not listed anymore.
+func (m *ListModel) unmarkMissing() {
+	for name := range m.marked {
+		if e := FindEndpoint(m.endpoints, name); e == nil || !e.Valid() {
+			delete(m.marked, name)
+		}
+	}
+}
+
+// toggleMark marks the selected endpoint if it is not marked, unmarks it
+// otherwise.
+func (m *ListModel) toggleMark() tea.Cmd {
+	w := m.selected()
+	if w == nil {
+		return nil
+	}
+	name := w.endpoint.FullName()
+	if m.marked[name] {
+		delete(m.marked, name)
+	} else {
+		m.marked[name] = true
+	}
+	cmd := m.refresh()
+	m.selectEndpoint(w.endpoint)
+	m.list.CursorDown()
+	return cmd
+}
+
+// startPrompt asks for a command to run in the marked endpoints.
+func (m *ListModel) startPrompt() tea.Cmd {
+	if _, ok := m.client.(commandRunner); !ok {
+		return m.list.NewStatusMessage("running commands is not supported by this client")
+	}
+	if len(m.markedEndpoints()) == 0 {
+		return nil
+	}
+	m.view = viewPrompt
+	m.prompt.Reset()
+	return m.prompt.Focus()
+}
+
+func (m *ListModel) updatePrompt(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.view = viewList
+		m.prompt.Blur()
+		return nil
+	case key.Matches(msg, m.keys.Confirm):
+		command := strings.TrimSpace(m.prompt.Value())
+		if command == "" {
+			return nil
+		}
+		m.prompt.Blur()
+		runner, _ := m.client.(commandRunner)
+		b := newBroadcastRun(command, m.markedEndpoints())
+		b.viewport.KeyMap.Up = m.keys.List.CursorUp
+		b.viewport.KeyMap.Down = m.keys.List.CursorDown
+		m.broadcast = b
+		m.view = viewResults
+		m.resizeResults()
+		b.viewport.SetContent(b.render(m.styles))
+		return waitForResult(b, runner.RunCommand(b.ctx, b.endpoints, command))
+	}
+	var cmd tea.Cmd
+	m.prompt, cmd = m.prompt.Update(msg)
+	return cmd
+}
+
+func (m *ListModel) updateResults(msg tea.KeyMsg) tea.Cmd {
+	switch {
+	case key.Matches(msg, m.keys.CopyOutput):
+		m.clipboard(m.broadcast.plain())
+		return nil
+	case key.Matches(msg, m.keys.Cancel), key.Matches(msg, m.keys.List.Quit):
+		m.view = viewList
+		m.broadcast.cancel()
+		m.broadcast = nil
+		return nil
+	}
+	var cmd tea.Cmd
+	m.broadcast.viewport, cmd = m.broadcast.viewport.Update(msg)
+	return cmd
+}
+
+func (m *ListModel) handleCommandResult(msg commandResultMsg) tea.Cmd {
+	if msg.broadcast != m.broadcast {
+		// results of a broadcast that is not being shown anymore
+		return waitForResult(msg.broadcast, msg.ch)
+	}
+	m.broadcast.set(msg.result)
+	m.broadcast.viewport.SetContent(m.broadcast.render(m.styles))
+	return waitForResult(msg.broadcast, msg.ch)
+}
+
+// resizeResults sets the results viewport size to fit the window.
+func (m *ListModel) resizeResults() {
+	if m.broadcast == nil {
+		return
+	}
+	top, right, bottom, left := m.styles.Doc.GetMargin()
+	m.broadcast.viewport.Width = m.width - left - right
+	m.broadcast.viewport.Height = max(m.height-top-bottom-4, 1) //nolint:mnd
+}
+
+func (m *ListModel) promptView() string {
+	return m.styles.Doc.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.Logo.String()+"\n",
+		fmt.Sprintf("Run a command on %d endpoints:", len(m.markedEndpoints())),
+		m.prompt.View()+"\n",
+		m.styles.Footer.Render(m.list.Help.ShortHelpView([]key.Binding{m.keys.Confirm, m.keys.Cancel})),
+	))
+}
+
+func (m *ListModel) resultsView() string {
+	return m.styles.Doc.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.Logo.Render(m.broadcast.title())+"\n",
+		m.broadcast.viewport.View(),
+		m.styles.Footer.Render(m.list.Help.ShortHelpView([]key.Binding{
+			m.broadcast.viewport.KeyMap.Up,
+			m.broadcast.viewport.KeyMap.Down,
+			m.keys.CopyOutput,
+			m.keys.Cancel,
+		})),
+	))
+}
@@ -0,0 +1,103 @@
+package wishlist
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct{}
+
+func (fakeClient) For(*Endpoint) tea.ExecCommand { return nil }
+
+type fakeRunner struct{}
+
+func (fakeRunner) For(*Endpoint) tea.ExecCommand { return nil }
+
+func (fakeRunner) RunCommand(_ context.Context, endpoints []*Endpoint, command string) <-chan commandResult {
+	return runEach(endpoints, func(e *Endpoint) commandResult {
+		if e.Name == "bad" {
+			return commandResult{err: errors.New("failed to connect")}
+		}
+		return commandResult{stdout: []byte(e.Name + ": " + command + "\n")}
+	})
+}
+
+func TestMarkedEndpoints(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "a", Address: "a:22"},
+		{Name: "b", Address: "b:22"},
+		{Name: "c", Address: "c:22"},
+	}
+	m := NewListing(endpoints, fakeRunner{}, testRenderer)
+
+	t.Run("selected when none marked", func(t *testing.T) {
+		require.Equal(t, []*Endpoint{endpoints[0]}, m.markedEndpoints())
+	})
+
+	t.Run("marked", func(t *testing.T) {
+		m.toggleMark() // marks a, moves to b
+		m.list.CursorDown()
+		m.toggleMark() // marks c
+		require.Equal(t, []*Endpoint{endpoints[0], endpoints[2]}, m.markedEndpoints())
+		require.Equal(t, "Directory Listing (2 marked)", m.list.Title)
+		require.True(t, m.list.Items()[0].(ItemWrapper).marked)
+	})
+
+	t.Run("unmark missing", func(t *testing.T) {
+		m.SetItems(endpoints[1:])
+		require.Equal(t, []*Endpoint{endpoints[2]}, m.markedEndpoints())
+	})
+}
+
+func TestBroadcastRun(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "good", Address: "good:22"},
+		{Name: "bad", Address: "bad:22"},
+	}
+	b := newBroadcastRun("uptime", endpoints)
+	require.Equal(t, 2, b.pending())
+	require.Equal(t, `Running "uptime" on 2 endpoints, 2 pending`, b.title())
+
+	for result := range (fakeRunner{}).RunCommand(context.Background(), endpoints, "uptime") {
+		b.set(result)
+	}
+	require.Equal(t, 0, b.pending())
+	require.Equal(t, `Ran "uptime" on 2 endpoints`, b.title())
+
+	// results are kept in the endpoints order, regardless of when they finish.
+	require.Equal(t, "# good (exit status 0)\ngood: uptime\n\n# bad (exit status 0)\n# error: failed to connect\n\n", b.plain())
+
+	out := b.render(makeStyles(testRenderer, Theme{}))
+	require.Contains(t, out, "good: uptime")
+	require.Contains(t, out, "failed to connect")
+}
+
+func TestRunCommandPrompt(t *testing.T) {
+	endpoints := []*Endpoint{{Name: "a", Address: "a:22"}}
+
+	t.Run("unsupported client", func(t *testing.T) {
+		m := NewListing(endpoints, fakeClient{}, testRenderer)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		require.Equal(t, viewList, m.view)
+	})
+
+	t.Run("run and go back", func(t *testing.T) {
+		m := NewListing(endpoints, fakeRunner{}, testRenderer)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("r")})
+		require.Equal(t, viewPrompt, m.view)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("ls")})
+		_, cmd := m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewResults, m.view)
+		require.NotNil(t, m.broadcast)
+		_, _ = m.Update(cmd())
+		require.Equal(t, 0, m.broadcast.pending())
+		require.Contains(t, m.broadcast.plain(), "a: ls")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		require.Equal(t, viewList, m.view)
+		require.Nil(t, m.broadcast)
+	})
+}
@@ -1,10 +1,14 @@
 package wishlist
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"sync"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
@@ -17,26 +21,46 @@ type SSHClient interface {
 	For(e *Endpoint) tea.ExecCommand
 }
 
-func createSession(conf *gossh.ClientConfig, e *Endpoint, abort <-chan os.Signal, env ...string) (*gossh.Session, *gossh.Client, closers, error) {
+func createSession(conf *gossh.ClientConfig, e *Endpoint, hops []jumpHop, abort <-chan os.Signal, env ...string) (*gossh.Session, *gossh.Client, closers, error) {
+	conn, cl, err := dialEndpoint(conf, e, hops, abort)
+	if err != nil {
+		return nil, nil, cl, err
+	}
+
+	session, err := conn.NewSession()
+	if err != nil {
+		return nil, conn, cl, fmt.Errorf("failed to open session: %w", err)
+	}
+	cl = append(cl, session.Close)
+	for k, v := range e.Environment(env...) {
+		if err := session.Setenv(k, v); err != nil {
+			log.Warn("could not set env", "key", k, "value", v, "err", err)
+			continue
+		}
+		log.Info("setting env", "key", k, "value", v)
+	}
+	return session, conn, cl, nil
+}
+
+// dialEndpoint connects to the endpoint, through its hops, if any.
+// The returned closers should be closed even on errors.
+func dialEndpoint(conf *gossh.ClientConfig, e *Endpoint, hops []jumpHop, abort <-chan os.Signal) (*gossh.Client, closers, error) {
 	var cl closers
 	var conn *gossh.Client
 	var err error
 	connected := make(chan bool, 1)
 
-	if jump := e.ProxyJump; jump == "" {
+	if len(hops) == 0 {
 		go func() {
-			conn, err = gossh.Dial("tcp", e.Address, conf)
+			conn, err = dial(e, conf)
 			connected <- true
 		}()
 	} else {
-		username, addr := splitJump(jump)
-		jumpConf := &gossh.ClientConfig{
-			User:            FirstNonEmpty(username, conf.User),
-			Auth:            conf.Auth,
-			HostKeyCallback: conf.HostKeyCallback,
+		if proxyCommand(e) != "" {
+			log.Warn("both ProxyJump and ProxyCommand are set, ignoring ProxyCommand", "endpoint", e.Name)
 		}
 		go func() {
-			conn, cl, err = proxyJump(addr, e.Address, jumpConf, conf)
+			conn, cl, err = dialJumps(hops, e.Address, conf)
 			connected <- true
 		}()
 	}
@@ -49,27 +73,22 @@ func createSession(conf *gossh.ClientConfig, e *Endpoint, abort <-chan os.Signal
 		if conn != nil {
 			cl = append(cl, conn.Close)
 		}
-		return nil, nil, cl, fmt.Errorf("connection aborted")
+		return nil, cl, fmt.Errorf("connection aborted")
 	}
 
 	if err != nil {
-		return nil, nil, cl, fmt.Errorf("connection failed: %w", err)
+		return nil, cl, fmt.Errorf("connection failed: %w", err)
 	}
-	cl = append(cl, conn.Close)
+	return conn, append(cl, conn.Close), nil
+}
 
-	session, err := conn.NewSession()
-	if err != nil {
-		return nil, conn, cl, fmt.Errorf("failed to open session: %w", err)
+// dial connects to the endpoint, either directly or through its
+// ProxyCommand.
+func dial(e *Endpoint, conf *gossh.ClientConfig) (*gossh.Client, error) {
+	if proxyCommand(e) != "" {
+		return dialProxyCommand(e, conf)
 	}
-	cl = append(cl, session.Close)
-	for k, v := range e.Environment(env...) {
-		if err := session.Setenv(k, v); err != nil {
-			log.Warn("could not set env", "key", k, "value", v, "err", err)
-			continue
-		}
-		log.Info("setting env", "key", k, "value", v)
-	}
-	return session, conn, cl, nil
+	return gossh.Dial("tcp", e.Address, conf) //nolint:wrapcheck
 }
 
 func shellAndWait(session *gossh.Session) error {
@@ -95,6 +114,126 @@ func runAndWait(session *gossh.Session, cmd string) error {
 	return nil
 }
 
+// commandResult is the result of running a command on an endpoint.
+type commandResult struct {
+	endpoint   *Endpoint
+	stdout     []byte
+	stderr     []byte
+	exitStatus int
+	duration   time.Duration
+	err        error
+}
+
+// commandRunner is implemented by clients that can run a command
+// non-interactively on several endpoints.
+type commandRunner interface {
+	// RunCommand runs the command on all the given endpoints concurrently,
+	// sending the results to the returned channel as they finish.
+	// The channel is closed once all of them are done.
+	// Commands still running when the context is done are stopped.
+	RunCommand(ctx context.Context, endpoints []*Endpoint, command string) <-chan commandResult
+}
+
+// runEach calls fn for each endpoint concurrently, sending the results to the
+// returned channel, which is closed when all of them are done.
+func runEach(endpoints []*Endpoint, fn func(e *Endpoint) commandResult) <-chan commandResult {
+	ch := make(chan commandResult, len(endpoints))
+	var wg sync.WaitGroup
+	for _, e := range endpoints {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			result := fn(e)
+			result.endpoint = e
+			result.duration = time.Since(start)
+			ch <- result
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
+
+// closeWhenDone closes the given closers once the channel is closed, while
+// still relaying its values.
+func closeWhenDone(ch <-chan commandResult, cl closers) <-chan commandResult {
+	out := make(chan commandResult, cap(ch))
+	go func() {
+		defer cl.close()
+		defer close(out)
+		for result := range ch {
+			out <- result
+		}
+	}()
+	return out
+}
+
+// maxCommandOutput is how much of the stdout and stderr of each command run
+// on several endpoints is kept, the rest being discarded.
+const maxCommandOutput = 1024 * 1024
+
+// truncatedMarker is appended to the output that was truncated.
+const truncatedMarker = "\n[output truncated]\n"
+
+// runCapturing runs the command in the given session, capturing its output,
+// and closing the session if the context is done first.
+// The whole output is counted in the audit session, if any.
+func runCapturing(ctx context.Context, session *gossh.Session, cmd string, audit *auditSession) commandResult {
+	stdout := &cappedBuffer{max: maxCommandOutput}
+	stderr := &cappedBuffer{max: maxCommandOutput}
+	session.Stdout = audit.output(stdout)
+	session.Stderr = audit.output(stderr)
+	stop := context.AfterFunc(ctx, func() { _ = session.Close() })
+	defer stop()
+
+	log.Info("running", "command", cmd)
+	err := session.Run(cmd)
+	result := commandResult{
+		stdout: stdout.Bytes(),
+		stderr: stderr.Bytes(),
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		result.err = fmt.Errorf("failed to run %q: %w", cmd, ctxErr)
+		return result
+	}
+	var exitErr *gossh.ExitError
+	if errors.As(err, &exitErr) {
+		result.exitStatus = exitErr.ExitStatus()
+		return result
+	}
+	if err != nil {
+		result.err = fmt.Errorf("failed to run %q: %w", cmd, err)
+	}
+	return result
+}
+
+// cappedBuffer keeps up to max bytes written into it, discarding the rest.
+type cappedBuffer struct {
+	buf       bytes.Buffer
+	max       int
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); len(p) > room {
+		b.buf.Write(p[:max(room, 0)])
+		b.truncated = true
+		return len(p), nil
+	}
+	return b.buf.Write(p) //nolint:wrapcheck
+}
+
+// Bytes returns the bytes kept, followed by a marker if any were discarded.
+func (b *cappedBuffer) Bytes() []byte {
+	if !b.truncated {
+		return b.buf.Bytes()
+	}
+	return append(b.buf.Bytes(), truncatedMarker...)
+}
+
 type closers []func() error
 
 func (c closers) close() {
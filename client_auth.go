@@ -1,6 +1,7 @@
 package wishlist
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
@@ -269,17 +270,7 @@ func parsePrivateKey(path string, password []byte) (gossh.AuthMethod, error) {
 		return nil, fmt.Errorf("could not find key: %q: %w", path, err)
 	}
 
-	bts, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read key: %q: %w", path, err)
-	}
-
-	var signer gossh.Signer
-	if len(password) == 0 {
-		signer, err = gossh.ParsePrivateKey(bts)
-	} else {
-		signer, err = gossh.ParsePrivateKeyWithPassphrase(bts, password)
-	}
+	signer, err := readPrivateKey(path, password)
 	if err != nil {
 		pwderr := &gossh.PassphraseMissingError{}
 		if errors.As(err, &pwderr) {
@@ -292,7 +283,7 @@ func parsePrivateKey(path string, password []byte) (gossh.AuthMethod, error) {
 			}
 			return parsePrivateKey(path, password)
 		}
-		return nil, fmt.Errorf("failed to parse private key: %q: %w", path, err)
+		return nil, err
 	}
 
 	log.Info(
@@ -304,36 +295,171 @@ func parsePrivateKey(path string, password []byte) (gossh.AuthMethod, error) {
 	return gossh.PublicKeys(signer), nil
 }
 
+// readPrivateKey reads and parses the private key in the given path, without
+// asking for passwords.
+func readPrivateKey(path string, password []byte) (gossh.Signer, error) {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key: %q: %w", path, err)
+	}
+
+	var signer gossh.Signer
+	if len(password) == 0 {
+		signer, err = gossh.ParsePrivateKey(bts)
+	} else {
+		signer, err = gossh.ParsePrivateKeyWithPassphrase(bts, password)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key: %q: %w", path, err)
+	}
+	return signer, nil
+}
+
+// localNonInteractiveAuthMethods returns the public key auth methods that can
+// be used without user input, i.e. the endpoint IdentityFiles and the common
+// key filenames under ~/.ssh/ that are not password protected, and the local
+// ssh agent.
+func localNonInteractiveAuthMethods(agt agent.Agent, e *Endpoint) ([]gossh.AuthMethod, error) {
+	var paths []string
+	for _, id := range e.IdentityFiles {
+		path, err := home.ExpandPath(id)
+		if err != nil {
+			return nil, err //nolint: wrapcheck
+		}
+		paths = append(paths, path)
+	}
+	for _, name := range []string{"id_rsa", "id_ecdsa", "id_ecdsa_sk", "id_ed25519", "id_ed25519_sk"} {
+		path, err := home.ExpandPath(filepath.Join("~/.ssh", name))
+		if err != nil {
+			return nil, err //nolint: wrapcheck
+		}
+		paths = append(paths, path)
+	}
+
+	var signers []gossh.Signer
+	for _, path := range paths {
+		signer, err := readPrivateKey(path, nil)
+		if err != nil {
+			log.Debug("skipping key", "key.path", path, "err", err)
+			continue
+		}
+		signers = append(signers, signer)
+	}
+
+	var methods []gossh.AuthMethod
+	if len(signers) > 0 {
+		methods = append(methods, gossh.PublicKeys(signers...))
+	}
+	if method := agentAuthMethod(agt); method != nil {
+		methods = append(methods, method)
+	}
+	return methods, nil
+}
+
+// remoteNonInteractiveAuthMethods returns the public key auth methods that
+// can be used without user input, i.e. the forwarded agent, if any, and the
+// wishlist client key.
+func remoteNonInteractiveAuthMethods(s ssh.Session) ([]gossh.AuthMethod, closers, error) {
+	var methods []gossh.AuthMethod
+	agt, cl, err := getRemoteAgent(s)
+	if err != nil && !errors.Is(err, errNoRemoteAgent) {
+		return nil, cl, err
+	}
+	if method := agentAuthMethod(agt); method != nil {
+		methods = append(methods, method)
+	}
+	newKey, err := tryNewKey()
+	if err != nil {
+		return nil, cl, err
+	}
+	return append(methods, newKey), cl, nil
+}
+
 // hostKeyCallback returns a callback that will be used to verify the host key.
 //
-// it creates a file in the given path, and uses that to verify hosts and keys.
-// if the host does not exist there, it adds it so its available next time, as plain old `ssh` does.
-func hostKeyCallback(e *Endpoint, path string) gossh.HostKeyCallback {
+// Keys are checked against the given known hosts files, according to the
+// endpoint's StrictHostKeyChecking.
+// If trusted is not nil, that key is accepted regardless of the known hosts
+// files.
+// If ask is nil and the host needs to be confirmed, a *HostKeyUnknownError is
+// returned instead.
+func hostKeyCallback(e *Endpoint, kh knownHosts, trusted gossh.PublicKey, ask hostKeyAsker) gossh.HostKeyCallback {
+	return hopHostKeyCallback(e, e, kh, trusted, ask)
+}
+
+// hopHostKeyCallback is like hostKeyCallback, but for a ProxyJump hop to
+// reach the endpoint, whose StrictHostKeyChecking is used instead.
+// Errors still refer to the endpoint, so it can be connected to again once
+// the user decides what to do with the key.
+func hopHostKeyCallback(e, hop *Endpoint, kh knownHosts, trusted gossh.PublicKey, ask hostKeyAsker) gossh.HostKeyCallback {
 	return func(hostname string, remote net.Addr, key gossh.PublicKey) error {
-		kh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:mnd
+		if trusted != nil && bytes.Equal(trusted.Marshal(), key.Marshal()) {
+			log.Warn("trusting host key once", "host", hostname, "fingerprint", gossh.FingerprintSHA256(key))
+			return nil
+		}
+
+		mode, err := ParseHostKeyChecking(hop.StrictHostKeyChecking)
 		if err != nil {
-			return fmt.Errorf("failed to open known_hosts: %w", err)
+			return err
 		}
-		defer func() { _ = kh.Close() }()
 
-		callback, err := knownhosts.New(kh.Name())
+		callback, err := knownhosts.New(kh.existing()...)
 		if err != nil {
 			return fmt.Errorf("failed to check known_hosts: %w", err)
 		}
 
-		if err := callback(hostname, remote, key); err != nil {
-			var kerr *knownhosts.KeyError
-			if errors.As(err, &kerr) {
-				if len(kerr.Want) > 0 {
-					return fmt.Errorf("possible man-in-the-middle attack: %w - if your host's key changed, you might need to edit %q", err, kh.Name())
-				}
-				// if want is empty, it means the host was not in the known_hosts file, so lets add it there.
-				fmt.Fprintln(kh, knownhosts.Line([]string{e.Address}, key)) //nolint: errcheck
+		err = callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+		var kerr *knownhosts.KeyError
+		if !errors.As(err, &kerr) {
+			// revoked keys, or certificates not signed by a known authority.
+			return fmt.Errorf("failed to check known_hosts: %w", err)
+		}
+
+		if len(kerr.Want) > 0 {
+			if mode == HostKeyCheckingNo {
+				log.Warn("host key changed, ignoring", "host", hostname, "fingerprint", gossh.FingerprintSHA256(key))
 				return nil
 			}
-			return fmt.Errorf("failed to check known_hosts: %w", err)
+			return &HostKeyChangedError{
+				Endpoint: e,
+				Hostname: hostname,
+				Path:     kh.writable(),
+				Key:      key,
+				Want:     kerr.Want,
+			}
 		}
-		return nil
+
+		// the host is not in any of the known hosts files.
+		switch mode {
+		case HostKeyCheckingYes:
+			return fmt.Errorf("no host key is known for %q and strict host key checking is enabled", hostname)
+		case HostKeyCheckingAsk:
+			if ask == nil {
+				return &HostKeyUnknownError{
+					Endpoint: e,
+					Hostname: hostname,
+					Path:     kh.writable(),
+					Key:      key,
+				}
+			}
+			ok, err := ask(hostname, key)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				return fmt.Errorf("host key verification failed for %q", hostname)
+			}
+		}
+
+		path := kh.writable()
+		if path == "" {
+			log.Warn("no known_hosts file to add host key to", "host", hostname)
+			return nil
+		}
+		return appendKnownHost(path, hostname, key)
 	}
 }
 
@@ -7,32 +7,203 @@ import (
 	"os"
 	"os/signal"
 	"os/user"
-	"path/filepath"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/log"
 	"github.com/muesli/cancelreader"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 	"golang.org/x/term"
 )
 
+// LocalClientOption can be used to customize the local SSH client.
+type LocalClientOption func(*localClient)
+
+// WithClientState records connections into the given state.
+func WithClientState(state *State) LocalClientOption {
+	return func(c *localClient) {
+		c.state = state
+	}
+}
+
+// WithClientEndpoints sets all the endpoints, so ProxyJump hops can reference
+// them by name.
+func WithClientEndpoints(endpoints []*Endpoint) LocalClientOption {
+	return func(c *localClient) {
+		c.endpoints = endpoints
+	}
+}
+
 // NewLocalSSHClient returns a SSH Client for local usage.
-func NewLocalSSHClient() SSHClient {
-	return &localClient{}
+func NewLocalSSHClient(opts ...LocalClientOption) SSHClient {
+	c := &localClient{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-type localClient struct{}
+type localClient struct {
+	// state in which connections are recorded, might be nil
+	state *State
+
+	// all the endpoints, used to resolve ProxyJump hops by name
+	endpoints []*Endpoint
+}
 
 func (c *localClient) For(e *Endpoint) tea.ExecCommand {
 	return &localSession{
-		endpoint: e,
+		endpoint:  e,
+		state:     c.state,
+		endpoints: c.endpoints,
+	}
+}
+
+// forTrustedKey implements hostKeyTruster.
+func (c *localClient) forTrustedKey(e *Endpoint, key ssh.PublicKey) tea.ExecCommand {
+	return &localSession{
+		endpoint:   e,
+		state:      c.state,
+		endpoints:  c.endpoints,
+		trustedKey: key,
+	}
+}
+
+// RunCommand implements commandRunner.
+func (c *localClient) RunCommand(ctx context.Context, endpoints []*Endpoint, command string) <-chan commandResult {
+	agt, cls, err := getLocalAgent()
+	if err != nil {
+		log.Warn("could not get local agent", "err", err)
+	}
+	ch := runEach(endpoints, func(e *Endpoint) commandResult {
+		user, err := user.Current()
+		if err != nil {
+			return commandResult{err: fmt.Errorf("failed to get current username: %w", err)}
+		}
+		methods, err := localNonInteractiveAuthMethods(agt, e)
+		if err != nil {
+			return commandResult{err: fmt.Errorf("failed to setup a authentication method: %w", err)}
+		}
+		conf := &ssh.ClientConfig{
+			User:            FirstNonEmpty(e.User, user.Username),
+			Auth:            methods,
+			HostKeyCallback: hostKeyCallback(e, localKnownHosts(e, user.HomeDir), nil, nil),
+			Timeout:         e.Timeout,
+		}
+		hops, hcl, err := jumpHops(e, c.endpoints, func(hop *Endpoint) (*ssh.ClientConfig, closers, error) {
+			methods, err := localNonInteractiveAuthMethods(agt, hop)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to setup a authentication method: %w", err)
+			}
+			return &ssh.ClientConfig{
+				User:            FirstNonEmpty(hop.User, user.Username),
+				Auth:            methods,
+				HostKeyCallback: hopHostKeyCallback(e, hop, localKnownHosts(hop, user.HomeDir), nil, nil),
+				Timeout:         hop.Timeout,
+			}, nil, nil
+		})
+		defer hcl.close()
+		if err != nil {
+			return commandResult{err: err}
+		}
+		session, _, cl, err := createSession(conf, e, hops, nil, os.Environ()...)
+		defer cl.close()
+		if err != nil {
+			return commandResult{err: fmt.Errorf("failed to create session: %w", err)}
+		}
+		return runCapturing(ctx, session, command, nil)
+	})
+	return closeWhenDone(ch, cls)
+}
+
+// Copy implements Copier.
+func (c *localClient) Copy(src, dst CopyPath) error {
+	e, err := copyEndpoint(src, dst)
+	if err != nil {
+		return err
+	}
+
+	abort := make(chan os.Signal, 1)
+	signal.Notify(abort, os.Interrupt)
+	defer func() {
+		signal.Stop(abort)
+		close(abort)
+	}()
+
+	agt, cls, err := getLocalAgent()
+	if err != nil {
+		return err
+	}
+	defer cls.close()
+
+	conf, hops, hcl, err := localConfigs(agt, e, c.endpoints, nil)
+	defer hcl.close()
+	if err != nil {
+		return err
+	}
+	client, cl, err := dialEndpoint(conf, e, hops, abort)
+	defer cl.close()
+	if err != nil {
+		return err
+	}
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		return fmt.Errorf("failed to start sftp: %w", err)
 	}
+	defer sc.Close() //nolint:errcheck
+	return transfer(sc, src, dst)
+}
+
+// localConfigs returns the configs to connect to the endpoint and its hops,
+// asking for passwords and unknown host keys in the terminal.
+// The returned closers should be closed even on errors.
+func localConfigs(agt agent.Agent, e *Endpoint, endpoints []*Endpoint, trusted ssh.PublicKey) (*ssh.ClientConfig, []jumpHop, closers, error) {
+	user, err := user.Current()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to get current username: %w", err)
+	}
+
+	methods, err := localBestAuthMethod(agt, e, os.Stdin, os.Stdout)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to setup a authentication method: %w", err)
+	}
+
+	conf := &ssh.ClientConfig{
+		User:            FirstNonEmpty(e.User, user.Username),
+		Auth:            methods,
+		HostKeyCallback: hostKeyCallback(e, localKnownHosts(e, user.HomeDir), trusted, askHostKey(os.Stdin, os.Stdout)),
+		Timeout:         e.Timeout,
+	}
+
+	hops, hcl, err := jumpHops(e, endpoints, func(hop *Endpoint) (*ssh.ClientConfig, closers, error) {
+		methods, err := localBestAuthMethod(agt, hop, os.Stdin, os.Stdout)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to setup a authentication method: %w", err)
+		}
+		return &ssh.ClientConfig{
+			User:            FirstNonEmpty(hop.User, user.Username),
+			Auth:            methods,
+			HostKeyCallback: hopHostKeyCallback(e, hop, localKnownHosts(hop, user.HomeDir), trusted, askHostKey(os.Stdin, os.Stdout)),
+			Timeout:         hop.Timeout,
+		}, nil, nil
+	})
+	return conf, hops, hcl, err
 }
 
 type localSession struct {
 	// endpoint we are connecting to
 	endpoint *Endpoint
 
+	// state in which the connection is recorded
+	state *State
+
+	// all the endpoints, used to resolve ProxyJump hops by name
+	endpoints []*Endpoint
+
+	// host key to trust once, might be nil
+	trustedKey ssh.PublicKey
+
 	stdin          io.Reader
 	stdout, stderr io.Writer
 }
@@ -59,34 +230,30 @@ func (s *localSession) Run() error {
 		close(abort)
 	}()
 
-	user, err := user.Current()
-	if err != nil {
-		return fmt.Errorf("failed to get current username: %w", err)
-	}
-
 	agt, cls, err := getLocalAgent()
 	if err != nil {
 		return err
 	}
 	defer cls.close()
 
-	methods, err := localBestAuthMethod(agt, s.endpoint, os.Stdin, os.Stdout)
+	conf, hops, hcl, err := localConfigs(agt, s.endpoint, s.endpoints, s.trustedKey)
+	defer hcl.close()
 	if err != nil {
-		return fmt.Errorf("failed to setup a authentication method: %w", err)
-	}
-
-	conf := &ssh.ClientConfig{
-		User:            FirstNonEmpty(s.endpoint.User, user.Username),
-		Auth:            methods,
-		HostKeyCallback: hostKeyCallback(s.endpoint, filepath.Join(user.HomeDir, ".ssh/known_hosts")),
-		Timeout:         s.endpoint.Timeout,
+		return err
 	}
-
-	session, client, cls, err := createSession(conf, s.endpoint, abort, os.Environ()...)
+	session, client, cls, err := createSession(conf, s.endpoint, hops, abort, os.Environ()...)
 	defer cls.close()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
+	if err := s.state.Touch(s.endpoint); err != nil {
+		log.Warn("could not record connection", "endpoint", s.endpoint.Name, "err", err)
+	}
+	fwd, err := startForwards(client, s.endpoint)
+	defer fwd.close()
+	if err != nil {
+		return fmt.Errorf("failed to start port forwarding: %w", err)
+	}
 	defer closers{func() error {
 		rc, ok := session.Stdin.(cancelreader.CancelReader)
 		if ok && !rc.Cancel() {
@@ -1,6 +1,7 @@
 package wishlist
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -18,18 +19,139 @@ type remoteClient struct {
 	// stdin, which is usually multiplexed from the session stdin
 	stdin io.Reader
 
+	// state in which connections are recorded, might be nil
+	state *State
+
+	// whether to ask about unknown host keys in the session, instead of
+	// letting the listing ask
+	askHostKeys bool
+
+	// all the endpoints, used to resolve ProxyJump hops by name, might be nil
+	endpoints func() []*Endpoint
+
+	// whether endpoints can be connected to through their ProxyCommand
+	allowProxyCommand bool
+
+	// how sessions are recorded
+	recording Recording
+
+	// audit log of the sessions, might be nil
+	audit *auditLog
+
 	cleanup func()
 }
 
+// allEndpoints returns all the endpoints, if known.
+func (c *remoteClient) allEndpoints() []*Endpoint {
+	if c.endpoints == nil {
+		return nil
+	}
+	return c.endpoints()
+}
+
 func (c *remoteClient) For(e *Endpoint) tea.ExecCommand {
 	return &remoteSession{
-		endpoint:      e,
-		parentSession: c.session,
-		stdin:         c.stdin,
-		cleanup:       c.cleanup,
+		endpoint:          e,
+		parentSession:     c.session,
+		stdin:             c.stdin,
+		state:             c.state,
+		askHostKeys:       c.askHostKeys,
+		endpoints:         c.allEndpoints(),
+		allowProxyCommand: c.allowProxyCommand,
+		recording:         c.recording,
+		audit:             c.audit,
+		cleanup:           c.cleanup,
+	}
+}
+
+// forTrustedKey implements hostKeyTruster.
+func (c *remoteClient) forTrustedKey(e *Endpoint, key gossh.PublicKey) tea.ExecCommand {
+	return &remoteSession{
+		endpoint:          e,
+		parentSession:     c.session,
+		stdin:             c.stdin,
+		state:             c.state,
+		askHostKeys:       c.askHostKeys,
+		endpoints:         c.allEndpoints(),
+		allowProxyCommand: c.allowProxyCommand,
+		recording:         c.recording,
+		audit:             c.audit,
+		cleanup:           c.cleanup,
+		trustedKey:        key,
 	}
 }
 
+// RunCommand implements commandRunner.
+// Commands are also stopped if the user disconnects.
+// They are audited, but not recorded, as they have no terminal.
+func (c *remoteClient) RunCommand(ctx context.Context, endpoints []*Endpoint, command string) <-chan commandResult {
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(c.session.Context(), cancel)
+	methods, cls, err := remoteNonInteractiveAuthMethods(c.session)
+	cls = append(cls, func() error {
+		stop()
+		cancel()
+		return nil
+	})
+	if err != nil {
+		return closeWhenDone(runEach(endpoints, func(*Endpoint) commandResult {
+			return commandResult{err: fmt.Errorf("failed to find an auth method: %w", err)}
+		}), cls)
+	}
+	ch := runEach(endpoints, func(e *Endpoint) commandResult {
+		audit := c.audit.session(c.session, e, command)
+		conf, hops, err := c.nonInteractiveConfigs(e, methods)
+		if err != nil {
+			audit.end(err)
+			return commandResult{err: err}
+		}
+		session, _, cl, err := createSession(conf, e, hops, nil, c.session.Environ()...)
+		defer cl.close()
+		if err != nil {
+			err = fmt.Errorf("failed to create session: %w", err)
+			audit.end(err)
+			return commandResult{err: err}
+		}
+		log.Info(
+			"run",
+			"user", c.session.User(),
+			"endpoint", e.Name,
+			"remote.addr", c.session.RemoteAddr().String(),
+		)
+		audit.connected()
+		result := runCapturing(ctx, session, command, audit)
+		audit.exited(result.exitStatus, result.err)
+		return result
+	})
+	return closeWhenDone(ch, cls)
+}
+
+// nonInteractiveConfigs returns the configs to connect to the endpoint and
+// its hops with the given auth methods, without asking the user anything.
+func (c *remoteClient) nonInteractiveConfigs(e *Endpoint, methods []gossh.AuthMethod) (*gossh.ClientConfig, []jumpHop, error) {
+	conf := &gossh.ClientConfig{
+		User:            FirstNonEmpty(e.User, c.session.User()),
+		HostKeyCallback: hostKeyCallback(e, remoteKnownHosts(e), nil, nil),
+		Auth:            methods,
+		Timeout:         e.Timeout,
+	}
+	hops, _, err := jumpHops(e, c.allEndpoints(), func(hop *Endpoint) (*gossh.ClientConfig, closers, error) {
+		return &gossh.ClientConfig{
+			User:            FirstNonEmpty(hop.User, c.session.User()),
+			HostKeyCallback: hopHostKeyCallback(e, hop, remoteKnownHosts(hop), nil, nil),
+			Auth:            methods,
+			Timeout:         hop.Timeout,
+		}, nil, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkProxyCommand(e, hops, c.allowProxyCommand); err != nil {
+		return nil, nil, err
+	}
+	return conf, hops, nil
+}
+
 type remoteSession struct {
 	// endpoint we are connecting to
 	endpoint *Endpoint
@@ -37,8 +159,17 @@ type remoteSession struct {
 	// the parent session (ie the session running the listing)
 	parentSession ssh.Session
 
-	stdin   io.Reader
-	cleanup func()
+	stdin             io.Reader
+	state             *State
+	askHostKeys       bool
+	endpoints         []*Endpoint
+	allowProxyCommand bool
+	recording         Recording
+	audit             *auditLog
+	cleanup           func()
+
+	// host key to trust once, might be nil
+	trustedKey gossh.PublicKey
 }
 
 func (s *remoteSession) SetStdin(_ io.Reader)  {}
@@ -46,6 +177,13 @@ func (s *remoteSession) SetStdout(_ io.Writer) {}
 func (s *remoteSession) SetStderr(_ io.Writer) {}
 
 func (s *remoteSession) Run() error {
+	audit := s.audit.session(s.parentSession, s.endpoint, s.endpoint.RemoteCommand)
+	err := s.run(audit)
+	audit.end(err)
+	return err
+}
+
+func (s *remoteSession) run(audit *auditSession) error {
 	if s.cleanup != nil {
 		s.cleanup()
 		defer s.cleanup()
@@ -54,23 +192,49 @@ func (s *remoteSession) Run() error {
 
 	stdin := blocking.New(s.stdin)
 
-	method, agt, closers, err := remoteBestAuthMethod(s.endpoint, s.parentSession, stdin)
+	method, agt, cls, err := remoteBestAuthMethod(s.endpoint, s.parentSession, stdin)
 	if err != nil {
 		return fmt.Errorf("failed to find an auth method: %w", err)
 	}
-	defer closers.close()
+	defer cls.close()
 
+	var ask hostKeyAsker
+	if s.askHostKeys {
+		ask = askHostKey(stdin, s.parentSession)
+	}
 	conf := &gossh.ClientConfig{
 		User:            FirstNonEmpty(s.endpoint.User, s.parentSession.User()),
-		HostKeyCallback: hostKeyCallback(s.endpoint, ".wishlist/known_hosts"),
+		HostKeyCallback: hostKeyCallback(s.endpoint, remoteKnownHosts(s.endpoint), s.trustedKey, ask),
 		Auth:            method,
 		Timeout:         s.endpoint.Timeout,
 	}
-	session, client, cl, err := createSession(conf, s.endpoint, nil, s.parentSession.Environ()...)
+	hops, hcl, err := jumpHops(s.endpoint, s.endpoints, func(hop *Endpoint) (*gossh.ClientConfig, closers, error) {
+		methods, _, cl, err := remoteBestAuthMethod(hop, s.parentSession, stdin)
+		if err != nil {
+			return nil, cl, fmt.Errorf("failed to find an auth method: %w", err)
+		}
+		return &gossh.ClientConfig{
+			User:            FirstNonEmpty(hop.User, s.parentSession.User()),
+			HostKeyCallback: hopHostKeyCallback(s.endpoint, hop, remoteKnownHosts(hop), s.trustedKey, ask),
+			Auth:            methods,
+			Timeout:         hop.Timeout,
+		}, cl, nil
+	})
+	defer hcl.close()
+	if err != nil {
+		return err
+	}
+	if err := checkProxyCommand(s.endpoint, hops, s.allowProxyCommand); err != nil {
+		return err
+	}
+	session, client, cl, err := createSession(conf, s.endpoint, hops, nil, s.parentSession.Environ()...)
 	defer cl.close()
 	if err != nil {
 		return fmt.Errorf("failed to create session: %w", err)
 	}
+	if len(s.endpoint.LocalForward)+len(s.endpoint.RemoteForward)+len(s.endpoint.DynamicForward) > 0 {
+		log.Warn("port forwarding is only supported in local mode, ignoring", "endpoint", s.endpoint.Name)
+	}
 
 	log.Info(
 		"connect",
@@ -78,10 +242,32 @@ func (s *remoteSession) Run() error {
 		"endpoint", s.endpoint.Name,
 		"remote.addr", s.parentSession.RemoteAddr().String(),
 	)
+	audit.connected()
+	if err := s.state.Touch(s.endpoint); err != nil {
+		log.Warn("could not record connection", "endpoint", s.endpoint.Name, "err", err)
+	}
+
+	pty, winch, hasPty := s.parentSession.Pty()
+	width, height := castWidth, castHeight
+	if hasPty {
+		width, height = pty.Window.Width, pty.Window.Height
+	}
+	rec, err := startRecording(s.recording, s.parentSession.User(), s.endpoint, pty.Term, width, height)
+	if err != nil {
+		return err
+	}
+	defer rec.Close() //nolint:errcheck
 
-	session.Stdout = s.parentSession
-	session.Stderr = s.parentSession.Stderr()
-	session.Stdin = stdin
+	session.Stdout = audit.output(s.parentSession)
+	session.Stderr = audit.output(s.parentSession.Stderr())
+	session.Stdin = audit.input(stdin)
+	if rec != nil {
+		session.Stdout = io.MultiWriter(session.Stdout, rec.output())
+		session.Stderr = io.MultiWriter(session.Stderr, rec.output())
+		if s.recording.Input {
+			session.Stdin = io.TeeReader(session.Stdin, rec.input())
+		}
+	}
 
 	if s.endpoint.ForwardAgent {
 		if err := forwardAgent(agt, session, client); err != nil {
@@ -91,8 +277,7 @@ func (s *remoteSession) Run() error {
 
 	if s.endpoint.RemoteCommand == "" || s.endpoint.RequestTTY {
 		log.Info("requesting tty")
-		pty, winch, ok := s.parentSession.Pty()
-		if !ok {
+		if !hasPty {
 			return fmt.Errorf("requested a tty, but current session doesn't allow one")
 		}
 		w := pty.Window
@@ -102,7 +287,7 @@ func (s *remoteSession) Run() error {
 
 		done := make(chan bool, 1)
 		defer func() { done <- true }()
-		go s.notifyWindowChanges(session, done, winch)
+		go s.notifyWindowChanges(session, done, winch, rec)
 	}
 
 	if s.endpoint.RemoteCommand == "" {
@@ -111,7 +296,7 @@ func (s *remoteSession) Run() error {
 	return runAndWait(session, s.endpoint.RemoteCommand)
 }
 
-func (s *remoteSession) notifyWindowChanges(session *gossh.Session, done <-chan bool, winch <-chan ssh.Window) {
+func (s *remoteSession) notifyWindowChanges(session *gossh.Session, done <-chan bool, winch <-chan ssh.Window, rec *recorder) {
 	for {
 		select {
 		case <-done:
@@ -125,6 +310,7 @@ func (s *remoteSession) notifyWindowChanges(session *gossh.Session, done <-chan
 				log.Warn("failed to notify window change", "err", err)
 				return
 			}
+			rec.resize(w.Width, w.Height)
 		}
 	}
 }
@@ -1,11 +1,17 @@
 package wishlist
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"net"
 	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/ssh"
 	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 func TestClosers(t *testing.T) {
@@ -38,3 +44,73 @@ func TestClosers(t *testing.T) {
 		require.True(t, b.Load())
 	}
 }
+
+func TestCappedBuffer(t *testing.T) {
+	b := &cappedBuffer{max: 5}
+	n, err := b.Write([]byte("abc"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, "abc", string(b.Bytes()))
+
+	n, err = b.Write([]byte("defgh"))
+	require.NoError(t, err)
+	require.Equal(t, 5, n)
+	n, err = b.Write([]byte("ijk"))
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, "abcde"+truncatedMarker, string(b.Bytes()))
+}
+
+func TestRunCapturing(t *testing.T) {
+	session := func(t *testing.T, handler ssh.Handler) *gossh.Session {
+		t.Helper()
+		srv := &ssh.Server{Handler: handler}
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		go func() { _ = srv.Serve(l) }()
+		t.Cleanup(func() { _ = srv.Close() })
+		client, err := gossh.Dial("tcp", l.Addr().String(), &gossh.ClientConfig{
+			User:            "carlos",
+			HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+		})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+		session, err := client.NewSession()
+		require.NoError(t, err)
+		return session
+	}
+
+	t.Run("exit status", func(t *testing.T) {
+		result := runCapturing(context.Background(), session(t, func(s ssh.Session) {
+			_, _ = s.Write([]byte("out"))
+			_, _ = s.Stderr().Write([]byte("err"))
+			_ = s.Exit(3)
+		}), "uptime", nil)
+		require.NoError(t, result.err)
+		require.Equal(t, 3, result.exitStatus)
+		require.Equal(t, "out", string(result.stdout))
+		require.Equal(t, "err", string(result.stderr))
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		result := runCapturing(context.Background(), session(t, func(s ssh.Session) {
+			chunk := bytes.Repeat([]byte("y\n"), 1024)
+			for range maxCommandOutput/len(chunk) + 2 {
+				_, _ = s.Write(chunk)
+			}
+			_ = s.Exit(0)
+		}), "yes", nil)
+		require.NoError(t, result.err)
+		require.Len(t, result.stdout, maxCommandOutput+len(truncatedMarker))
+		require.True(t, bytes.HasSuffix(result.stdout, []byte(truncatedMarker)))
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		result := runCapturing(ctx, session(t, func(s ssh.Session) {
+			<-s.Context().Done()
+		}), "sleep infinity", nil)
+		require.ErrorIs(t, result.err, context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/spf13/cobra"
+)
+
+var cpCmd = &cobra.Command{
+	Use:   "cp <src> <dst>",
+	Args:  cobra.ExactArgs(2), //nolint:mnd
+	Short: "Copy files from and to endpoints over SFTP.",
+	Long: `Copy a file from or to an endpoint over SFTP, using the same settings as
+connecting to it, e.g. its user, identity files, jump hosts and known hosts.
+
+Paths on endpoints are in the name:path form, relative to the home directory
+unless absolute.
+If the destination is a directory, the file is copied into it.
+`,
+	Example: `  wishlist cp ./dump.sql db1:/tmp/dump.sql
+  wishlist cp prod/app1:logs/app.log .`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if cache, err := os.UserCacheDir(); err == nil {
+			closeLog, err := logToFile(cache)
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+		}
+
+		seed, err := getSeedEndpoints(cmd.Context())
+		if err != nil {
+			return err
+		}
+		config, _, err := getConfig(configFile, seed)
+		if err != nil {
+			return err
+		}
+
+		client := wishlist.NewLocalSSHClient(wishlist.WithClientEndpoints(config.Endpoints))
+		copier, ok := client.(wishlist.Copier)
+		if !ok {
+			return fmt.Errorf("copying files is not supported")
+		}
+		src := wishlist.ParseCopyPath(config.Endpoints, args[0])
+		dst := wishlist.ParseCopyPath(config.Endpoints, args[1])
+		if err := copier.Copy(src, dst); err != nil {
+			return fmt.Errorf("copy failed: %w", err)
+		}
+		return nil
+	},
+}
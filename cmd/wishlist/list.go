@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listOutput string
+	listJSON   bool
+	listFields []string
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Args:    cobra.NoArgs,
+	Short:   "List the endpoints in a machine-readable format.",
+	Long: `List the endpoints in a machine-readable format, after discovery and hints
+are applied, e.g. to drive other tools.
+`,
+	Example: `  wishlist list --json
+  wishlist list -o table --fields name,address,tags
+  wishlist list -o names --tailscale.net example.com`,
+	RunE: func(cmd *cobra.Command, _ []string) error {
+		if cache, err := os.UserCacheDir(); err == nil {
+			closeLog, err := logToFile(cache)
+			if err != nil {
+				return err
+			}
+			defer closeLog()
+		}
+
+		seed, err := getSeedEndpoints(cmd.Context())
+		if err != nil {
+			return err
+		}
+		config, _, err := getConfig(configFile, seed)
+		if err != nil {
+			return err
+		}
+
+		output := listOutput
+		if listJSON {
+			output = wishlist.OutputJSON
+		}
+		return wishlist.WriteEndpoints(os.Stdout, config.Endpoints, output, listFields...) //nolint: wrapcheck
+	},
+}
+
+func init() {
+	listCmd.Flags().StringVarP(&listOutput, "output", "o", wishlist.OutputTable, fmt.Sprintf("Output format, one of: %s", strings.Join(wishlist.OutputFormats, ", ")))
+	listCmd.Flags().BoolVar(&listJSON, "json", false, "Shorthand for --output json")
+	listCmd.Flags().StringSliceVar(&listFields, "fields", nil, fmt.Sprintf("Fields to output, any of: %s", strings.Join(wishlist.OutputFields, ", ")))
+	listCmd.MarkFlagsMutuallyExclusive("output", "json")
+}
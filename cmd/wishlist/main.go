@@ -55,7 +55,7 @@ It's also possible to serve the TUI over SSH using the server command.
 	Version:       Version,
 	SilenceUsage:  true,
 	SilenceErrors: false,
-	Args:          cobra.MaximumNArgs(1),
+	Args:          cobra.ArbitraryArgs,
 	CompletionOptions: cobra.CompletionOptions{
 		HiddenDefaultCmd: true,
 	},
@@ -78,33 +78,49 @@ It's also possible to serve the TUI over SSH using the server command.
 		if err != nil {
 			return fmt.Errorf("could not create log file: %w", err)
 		}
-		f, err := os.OpenFile(filepath.Join(cache, "wishlist.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) //nolint:mnd
+		closeLog, err := logToFile(cache)
 		if err != nil {
-			return err //nolint: wrapcheck
+			return err
 		}
-		log.SetOutput(f)
-		log.SetLevel(log.DebugLevel)
-		log.SetFormatter(log.JSONFormatter)
-
-		defer func() {
-			if err := f.Close(); err != nil {
-				log.Info("failes to close wishlist.log", "err", err)
-			}
-		}()
+		defer closeLog()
 
 		seed, err := getSeedEndpoints(cmd.Context())
 		if err != nil {
 			return err
 		}
-		config, _, err := getConfig(configFile, seed)
+		config, path, err := getConfig(configFile, seed)
 		if err != nil {
 			return err
 		}
+		config.EndpointWriter = getEndpointWriter(path)
+
+		state, err := wishlist.OpenState(filepath.Join(cache, "wishlist", "state.json"))
+		if err != nil {
+			log.Warn("could not open state", "err", err)
+		}
 
-		return workLocally(config, args)
+		return workLocally(config, state, args)
 	},
 }
 
+// logToFile sends the logs to the wishlist.log file in the given directory,
+// so they don't mess with the TUI or the output.
+func logToFile(dir string) (func(), error) {
+	f, err := os.OpenFile(filepath.Join(dir, "wishlist.log"), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644) //nolint:mnd
+	if err != nil {
+		return nil, err //nolint: wrapcheck
+	}
+	log.SetOutput(f)
+	log.SetLevel(log.DebugLevel)
+	log.SetFormatter(log.JSONFormatter)
+
+	return func() {
+		if err := f.Close(); err != nil {
+			log.Info("failes to close wishlist.log", "err", err)
+		}
+	}, nil
+}
+
 var manCmd = &cobra.Command{
 	Use:          "man",
 	Args:         cobra.NoArgs,
@@ -138,6 +154,7 @@ var serverCmd = &cobra.Command{
 		if err != nil {
 			return err
 		}
+		config.EndpointWriter = getEndpointWriter(path)
 
 		if refreshInterval > 0 {
 			log.Info("endpoints", "refresh.interval", refreshInterval)
@@ -174,16 +191,16 @@ var serverCmd = &cobra.Command{
 		}
 
 		config.Factory = func(e wishlist.Endpoint) (*ssh.Server, error) {
+			middlewares := append(e.Middlewares, lm.Middleware())
+			if e.Name != "list" {
+				// the listing checks for a PTY itself, as `list --json`
+				// doesn't need one.
+				middlewares = append(middlewares, activeterm.Middleware())
+			}
 			return wish.NewServer(
 				wish.WithAddress(e.Address),
 				wish.WithHostKeyPath(".wishlist/server_ed25519"),
-				wish.WithMiddleware(
-					append(
-						e.Middlewares,
-						lm.Middleware(),
-						activeterm.Middleware(),
-					)...,
-				),
+				wish.WithMiddleware(middlewares...),
 			)
 		}
 
@@ -221,7 +238,7 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&tailscaleClientSecret, "tailscale.client.secret", "", "Tailscale client Secret [$TAILSCALE_CLIENT_SECRET]")
 	rootCmd.MarkFlagsMutuallyExclusive("tailscale.key", "tailscale.client.id")
 	rootCmd.MarkFlagsRequiredTogether("tailscale.client.id", "tailscale.client.secret")
-	rootCmd.AddCommand(serverCmd, manCmd)
+	rootCmd.AddCommand(serverCmd, listCmd, cpCmd, replayCmd, manCmd)
 }
 
 func main() {
@@ -292,13 +309,33 @@ func applyHints(seed []*wishlist.Endpoint, hints []wishlist.EndpointHint) []*wis
 			if s := hint.ProxyJump; s != "" {
 				end.ProxyJump = s
 			}
+			if s := hint.ProxyCommand; s != "" {
+				end.ProxyCommand = s
+			}
+			if s := hint.Group; s != "" {
+				end.Group = s
+			}
+			end.Tags = append(end.Tags, hint.Tags...)
 			end.SendEnv = append(end.SendEnv, hint.SendEnv...)
 			end.SetEnv = append(end.SetEnv, hint.SetEnv...)
 			end.PreferredAuthentications = append(end.PreferredAuthentications, hint.PreferredAuthentications...)
 			end.IdentityFiles = append(end.IdentityFiles, hint.IdentityFiles...)
+			end.Commands = append(end.Commands, hint.Commands...)
+			end.LocalForward = append(end.LocalForward, hint.LocalForward...)
+			end.RemoteForward = append(end.RemoteForward, hint.RemoteForward...)
+			end.DynamicForward = append(end.DynamicForward, hint.DynamicForward...)
 			if s := hint.Timeout; s != 0 {
 				end.Timeout = s
 			}
+			if s := hint.StrictHostKeyChecking; s != "" {
+				end.StrictHostKeyChecking = s
+			}
+			if s := hint.UserKnownHostsFile; len(s) > 0 {
+				end.UserKnownHostsFile = s
+			}
+			if s := hint.GlobalKnownHostsFile; len(s) > 0 {
+				end.GlobalKnownHostsFile = s
+			}
 			seed[i] = end
 		}
 	}
@@ -377,6 +414,9 @@ func getYAMLConfig(path string, seed []*wishlist.Endpoint) (wishlist.Config, err
 	if err := yaml.Unmarshal(bts, &config); err != nil {
 		return config, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := config.Validate(); err != nil {
+		return config, fmt.Errorf("invalid config: %w", err)
+	}
 
 	config.Endpoints = append(config.Endpoints, applyHints(seed, config.Hints)...)
 	return config, nil
@@ -392,34 +432,62 @@ func getSSHConfig(path string, seed []*wishlist.Endpoint) (wishlist.Config, erro
 	return config, nil
 }
 
-func workLocally(config wishlist.Config, args []string) error {
-	// either no args or arg is a list
-	if len(args) == 0 || args[0] == "list" {
+func workLocally(config wishlist.Config, state *wishlist.State, args []string) error {
+	client := wishlist.NewLocalSSHClient(
+		wishlist.WithClientState(state),
+		wishlist.WithClientEndpoints(config.Endpoints),
+	)
+
+	// no args, show the list
+	if len(args) == 0 {
 		m := wishlist.NewListing(
 			config.Endpoints,
-			wishlist.NewLocalSSHClient(),
+			client,
 			lipgloss.NewRenderer(os.Stderr),
+			wishlist.WithFilterFields(config.Filter.Fields...),
+			wishlist.WithState(state),
+			wishlist.WithTheme(config.Theme),
+			wishlist.WithKeys(config.Keys),
+			wishlist.WithEndpointWriter(config.EndpointWriter),
 		)
-		_, err := tea.NewProgram(
+		p := tea.NewProgram(
 			m,
 			tea.WithOutput(os.Stderr),
 			tea.WithAltScreen(),
-		).Run()
+		)
+		if config.Probe.Enabled {
+			prober := wishlist.NewProber(config.Probe)
+			prober.SetEndpoints(config.Endpoints)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go prober.Run(ctx, func(results wishlist.ProbeResults) {
+				p.Send(wishlist.ProbeResultsMsg{Results: results})
+			})
+		}
+		_, err := p.Run()
 		return err //nolint: wrapcheck
 	}
 
 	// ssh directly into something by its name
-	for _, e := range config.Endpoints {
-		if e.Name == args[0] {
-			return connect(e)
-		}
+	e := wishlist.FindEndpoint(config.Endpoints, args[0])
+	if e == nil {
+		return fmt.Errorf("invalid endpoint name: %q", args[0])
+	}
+	if len(args) == 1 {
+		return connect(client, e)
 	}
 
-	return fmt.Errorf("invalid endpoint name: %q", args[0])
+	// or run one of its commands, whose name might have spaces
+	name := strings.Join(args[1:], " ")
+	c, err := e.FindCommand(name)
+	if err != nil {
+		return err //nolint: wrapcheck
+	}
+	return connect(client, e.WithCommand(*c))
 }
 
-func connect(e *wishlist.Endpoint) error {
-	cmd := wishlist.NewLocalSSHClient().For(e)
+func connect(client wishlist.SSHClient, e *wishlist.Endpoint) error {
+	cmd := client.For(e)
 	cmd.SetStdout(os.Stdout)
 	cmd.SetStderr(os.Stderr)
 	cmd.SetStdin(os.Stdin)
@@ -20,21 +20,34 @@ func TestParseExampleYaml(t *testing.T) {
 	require.Len(t, cfg.Endpoints, 1)
 	require.Equal(t, wishlist.Endpoint{
 		Name:    "thename",
+		Group:   "examples",
 		Address: "foo.local:2234",
+		Tags:    []string{"prod", "web"},
 		Link: wishlist.Link{
 			Name: "Optional link name",
 			URL:  "https://github.com/charmbracelet/wishlist",
 		},
-		Desc:          "A description of this endpoint.\nCan have multiple lines.",
-		User:          "notme",
-		RemoteCommand: "uptime -a",
-		ForwardAgent:  true,
-		IdentityFiles: []string{"~/.ssh/id_rsa", "~/.ssh/id_ed25519"},
-		RequestTTY:    true,
-		Timeout:       10 * time.Second,
-		SetEnv:        []string{"FOO=bar", "BAR=baz"},
-		SendEnv:       []string{"LC_*", "LANG", "SOME_ENV"},
-		ProxyJump:     "user@host:22",
+		Desc:                  "A description of this endpoint.\nCan have multiple lines.",
+		User:                  "notme",
+		RemoteCommand:         "uptime -a",
+		ForwardAgent:          true,
+		IdentityFiles:         []string{"~/.ssh/id_rsa", "~/.ssh/id_ed25519"},
+		RequestTTY:            true,
+		Timeout:               10 * time.Second,
+		SetEnv:                []string{"FOO=bar", "BAR=baz"},
+		SendEnv:               []string{"LC_*", "LANG", "SOME_ENV"},
+		ProxyJump:             "user@host:22",
+		StrictHostKeyChecking: "ask",
+		UserKnownHostsFile:    []string{"~/.ssh/known_hosts"},
+		GlobalKnownHostsFile:  []string{"/etc/ssh/ssh_known_hosts"},
+		Commands: []wishlist.Command{
+			{Name: "psql", Command: "psql -U app", TTY: true},
+			{Name: "tail logs", Command: "tail -f /var/log/app.log"},
+			{Name: "shell"},
+		},
+		LocalForward:   []string{"5432 localhost:5432"},
+		RemoteForward:  []string{"8080:localhost:80"},
+		DynamicForward: []string{"1080"},
 	}, *cfg.Endpoints[0])
 	require.Len(t, cfg.Users, 1)
 	require.Equal(t, wishlist.User{
@@ -43,7 +56,13 @@ func TestParseExampleYaml(t *testing.T) {
 			"ssh-rsa AAAAB3Nz...",
 			"ssh-ed25519 AAAA...",
 		},
+		AuthorizedKeys: []string{"https://github.com/caarlos0.keys"},
+		Roles:          []string{"dba"},
+		Deny:           []string{"prod/secret-*"},
 	}, cfg.Users[0])
+	require.Equal(t, []wishlist.Role{
+		{Name: "dba", Allow: []string{"tag:db"}, Deny: []string{"tag:secret"}},
+	}, cfg.Roles)
 }
 
 func TestParseExampleSSHConfig(t *testing.T) {
@@ -56,7 +75,9 @@ func TestParseExampleSSHConfig(t *testing.T) {
 	require.Len(t, cfg.Endpoints, 2)
 	require.Equal(t, wishlist.Endpoint{
 		Name:          "foo",
+		Group:         "examples/foo",
 		Address:       "foo.bar:2223",
+		Tags:          []string{"prod", "web"},
 		User:          "notme",
 		IdentityFiles: []string{"~/.ssh/foo_ed25519"},
 		ForwardAgent:  true,
@@ -175,6 +196,7 @@ func TestApplyHints(t *testing.T) {
 		},
 		{
 			Match:         "*.local",
+			Group:         "local",
 			Port:          "2345",
 			User:          "carlos",
 			ForwardAgent:  boolPtr(true),
@@ -185,6 +207,7 @@ func TestApplyHints(t *testing.T) {
 				Name: "foo.bar",
 				URL:  "https://github.com/charmbracelet/wishlist",
 			},
+			Tags:                     []string{"local"},
 			SendEnv:                  []string{"FOO_*"},
 			SetEnv:                   []string{"FOO_TEST=bar"},
 			PreferredAuthentications: []string{"publickey"},
@@ -202,6 +225,7 @@ func TestApplyHints(t *testing.T) {
 	require.Len(t, result, 1)
 	require.Equal(t, wishlist.Endpoint{
 		Name:          "foo.bar.local",
+		Group:         "local",
 		Address:       "foo.bar.local:22234",
 		User:          "carlos",
 		ForwardAgent:  true,
@@ -212,6 +236,7 @@ func TestApplyHints(t *testing.T) {
 			Name: "foo.bar",
 			URL:  "https://github.com/charmbracelet/wishlist",
 		},
+		Tags:                     []string{"local"},
 		SendEnv:                  []string{"FOO_*"},
 		SetEnv:                   []string{"FOO_TEST=bar"},
 		PreferredAuthentications: []string{"publickey"},
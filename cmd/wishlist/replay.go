@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/spf13/cobra"
+)
+
+var (
+	replaySpeed   float64
+	replayMaxWait time.Duration
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay <file>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Replay a session recording.",
+	Long: `Replay a session recorded in server mode, in the asciicast format, with its
+original timing.
+`,
+	Example: `  wishlist replay .wishlist/recordings/carlos/app1/2024-01-01T10-00-00.000000Z.cast
+  wishlist replay --speed 2 --max-wait 1s session.cast`,
+	RunE: func(_ *cobra.Command, args []string) error {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return fmt.Errorf("could not open recording: %w", err)
+		}
+		defer f.Close()                                                  //nolint:errcheck
+		return wishlist.Replay(os.Stdout, f, replaySpeed, replayMaxWait) //nolint:wrapcheck
+	},
+}
+
+func init() {
+	replayCmd.Flags().Float64VarP(&replaySpeed, "speed", "s", 1, "Playback speed, e.g. 2 for twice as fast")
+	replayCmd.Flags().DurationVar(&replayMaxWait, "max-wait", 0, "Maximum pause between outputs, with 0 disabling it")
+}
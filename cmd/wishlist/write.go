@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/charmbracelet/wishlist/atomicfile"
+	"github.com/charmbracelet/wishlist/sshconfig"
+	"gopkg.in/yaml.v3"
+)
+
+// getEndpointWriter returns the writer of endpoints for the config file in
+// the given path, which can be either in the SSH config or YAML format.
+func getEndpointWriter(path string) wishlist.EndpointWriter {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		return func(old, e *wishlist.Endpoint) error {
+			return writeYAMLEndpoint(path, old, e)
+		}
+	default:
+		return func(old, e *wishlist.Endpoint) error {
+			return sshconfig.WriteEndpoint(path, old, e) //nolint: wrapcheck
+		}
+	}
+}
+
+// writeYAMLEndpoint adds or updates the given endpoint in the YAML config
+// file in the given path.
+// If old is nil, the endpoint is added at the end of the endpoints list,
+// otherwise, old is updated.
+//
+// Only the fields that can be edited from the listing are changed,
+// everything else, including comments, is kept as is.
+func writeYAMLEndpoint(path string, old, e *wishlist.Endpoint) error {
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bts, &doc); err != nil {
+		return fmt.Errorf("failed to parse config: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode}},
+		}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("failed to parse config: %q is not a mapping", path)
+	}
+
+	endpoints := yamlValue(root, "endpoints")
+	if endpoints == nil {
+		endpoints = &yaml.Node{Kind: yaml.SequenceNode}
+		setYAMLValue(root, "endpoints", endpoints)
+	}
+
+	var node *yaml.Node
+	if old != nil {
+		node = findYAMLEndpoint(endpoints, old)
+		if node == nil {
+			return fmt.Errorf("endpoint %q is not defined in %q", old.FullName(), path)
+		}
+	}
+	if (old == nil || old.FullName() != e.FullName()) && findYAMLEndpoint(endpoints, e) != nil {
+		return fmt.Errorf("endpoint %q is already defined in %q", e.FullName(), path)
+	}
+	if node == nil {
+		node = &yaml.Node{Kind: yaml.MappingNode}
+		endpoints.Content = append(endpoints.Content, node)
+	}
+
+	setYAMLString(node, "name", e.Name)
+	setYAMLString(node, "group", wishlist.CleanGroup(e.Group))
+	setYAMLString(node, "address", e.Address)
+	setYAMLString(node, "user", e.User)
+	setYAMLString(node, "proxy_jump", e.ProxyJump)
+	setYAMLString(node, "description", e.Desc)
+	if e.Link.URL == "" {
+		setYAMLValue(node, "link", nil)
+	} else {
+		link := yamlValue(node, "link")
+		if link == nil || link.Kind != yaml.MappingNode {
+			link = &yaml.Node{Kind: yaml.MappingNode}
+			setYAMLValue(node, "link", link)
+		}
+		setYAMLString(link, "name", e.Link.Name)
+		setYAMLString(link, "url", e.Link.URL)
+	}
+	if len(e.Tags) == 0 {
+		setYAMLValue(node, "tags", nil)
+	} else {
+		tags := &yaml.Node{Kind: yaml.SequenceNode}
+		if current := yamlValue(node, "tags"); current != nil {
+			tags.Style = current.Style
+		}
+		for _, tag := range e.Tags {
+			tags.Content = append(tags.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: tag})
+		}
+		setYAMLValue(node, "tags", tags)
+	}
+
+	var out bytes.Buffer
+	enc := yaml.NewEncoder(&out)
+	enc.SetIndent(2) //nolint:mnd
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := atomicfile.Write(path, out.Bytes(), 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// findYAMLEndpoint finds the node of the given endpoint in the endpoints
+// sequence node.
+func findYAMLEndpoint(endpoints *yaml.Node, e *wishlist.Endpoint) *yaml.Node {
+	for _, node := range endpoints.Content {
+		name := yamlValue(node, "name")
+		if name == nil || name.Value != e.Name {
+			continue
+		}
+		var group string
+		if g := yamlValue(node, "group"); g != nil {
+			group = g.Value
+		}
+		if wishlist.CleanGroup(group) == wishlist.CleanGroup(e.Group) {
+			return node
+		}
+	}
+	return nil
+}
+
+// yamlValue returns the value of the given key in a mapping node.
+func yamlValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setYAMLValue sets the value of the given key in a mapping node, keeping
+// the comments of the previous value.
+// The key is removed if the value is nil, and added at the end if missing.
+func setYAMLValue(node *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != key {
+			continue
+		}
+		if value == nil {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			return
+		}
+		current := node.Content[i+1]
+		value.HeadComment = current.HeadComment
+		value.LineComment = current.LineComment
+		value.FootComment = current.FootComment
+		node.Content[i+1] = value
+		return
+	}
+	if value == nil {
+		return
+	}
+	node.Content = append(
+		node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: key},
+		value,
+	)
+}
+
+// setYAMLString sets the given string value, keeping the style of the
+// previous value.
+// Empty values are removed.
+func setYAMLString(node *yaml.Node, key, value string) {
+	if value == "" {
+		setYAMLValue(node, key, nil)
+		return
+	}
+	if current := yamlValue(node, key); current != nil && current.Kind == yaml.ScalarNode {
+		current.Value = value
+		current.Tag = ""
+		if strings.Contains(value, "\n") && current.Style&(yaml.LiteralStyle|yaml.DoubleQuotedStyle) == 0 {
+			current.Style = yaml.LiteralStyle
+		}
+		return
+	}
+	scalar := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	if strings.Contains(value, "\n") {
+		scalar.Style = yaml.LiteralStyle
+	}
+	setYAMLValue(node, key, scalar)
+}
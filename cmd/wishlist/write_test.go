@@ -0,0 +1,160 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/stretchr/testify/require"
+)
+
+const writeYAMLConfig = `# wishlist config
+listen: 127.0.0.1 # only local
+endpoints:
+  # the database
+  - name: db1
+    group: prod
+    address: db1.local:22
+    user: postgres # the admin
+    description: |
+      The database.
+      Don't drop it.
+    tags: [db, prod]
+    forward_agent: true
+  - name: web1
+    address: web1.local:22
+    link:
+      name: Site
+      url: https://web1.local
+# end
+`
+
+func writeTestYAML(tb testing.TB, content string) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "config.yaml")
+	require.NoError(tb, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func requireFileContent(tb testing.TB, path, expected string) {
+	tb.Helper()
+	bts, err := os.ReadFile(path)
+	require.NoError(tb, err)
+	require.Equal(tb, expected, string(bts))
+}
+
+func TestWriteYAMLEndpoint(t *testing.T) {
+	t.Run("edit", func(t *testing.T) {
+		path := writeTestYAML(t, writeYAMLConfig)
+		cfg, err := getYAMLConfig(path, nil)
+		require.NoError(t, err)
+		old := wishlist.FindEndpoint(cfg.Endpoints, "prod/db1")
+		e := *old
+		e.User = "root"
+		e.Address = "db1.local:2222"
+		e.Tags = []string{"db"}
+		e.Link = wishlist.Link{URL: "https://db1.local"}
+		require.NoError(t, writeYAMLEndpoint(path, old, &e))
+		requireFileContent(t, path, `# wishlist config
+listen: 127.0.0.1 # only local
+endpoints:
+  # the database
+  - name: db1
+    group: prod
+    address: db1.local:2222
+    user: root # the admin
+    description: |
+      The database.
+      Don't drop it.
+    tags: [db]
+    forward_agent: true
+    link:
+      url: https://db1.local
+  - name: web1
+    address: web1.local:22
+    link:
+      name: Site
+      url: https://web1.local
+# end
+`)
+
+		cfg, err = getYAMLConfig(path, nil)
+		require.NoError(t, err)
+		require.Equal(t, &e, wishlist.FindEndpoint(cfg.Endpoints, "prod/db1"))
+	})
+
+	t.Run("remove fields", func(t *testing.T) {
+		path := writeTestYAML(t, writeYAMLConfig)
+		old := &wishlist.Endpoint{Name: "web1", Address: "web1.local:22"}
+		require.NoError(t, writeYAMLEndpoint(path, old, &wishlist.Endpoint{
+			Name:    "web2",
+			Address: "web2.local:22",
+			Desc:    "The new web.",
+		}))
+		cfg, err := getYAMLConfig(path, nil)
+		require.NoError(t, err)
+		require.Len(t, cfg.Endpoints, 2)
+		require.Equal(t, &wishlist.Endpoint{
+			Name:    "web2",
+			Address: "web2.local:22",
+			Desc:    "The new web.",
+		}, cfg.Endpoints[1])
+	})
+
+	t.Run("add", func(t *testing.T) {
+		path := writeTestYAML(t, writeYAMLConfig)
+		e := &wishlist.Endpoint{
+			Name:    "cache1",
+			Group:   "prod/cache",
+			Address: "cache1.local:6379",
+			Desc:    "The cache.\nDon't flush it.",
+			Tags:    []string{"cache"},
+		}
+		require.NoError(t, writeYAMLEndpoint(path, nil, e))
+		cfg, err := getYAMLConfig(path, nil)
+		require.NoError(t, err)
+		require.Len(t, cfg.Endpoints, 3)
+		require.Equal(t, e, cfg.Endpoints[2])
+
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Contains(t, string(bts), "# the database\n")
+		require.Contains(t, string(bts), "listen: 127.0.0.1 # only local\n")
+	})
+
+	t.Run("add without endpoints", func(t *testing.T) {
+		path := writeTestYAML(t, "port: 2222\n")
+		require.NoError(t, writeYAMLEndpoint(path, nil, &wishlist.Endpoint{
+			Name:    "foo",
+			Address: "foo:22",
+		}))
+		requireFileContent(t, path, "port: 2222\nendpoints:\n  - name: foo\n    address: foo:22\n")
+	})
+
+	t.Run("add existing", func(t *testing.T) {
+		path := writeTestYAML(t, writeYAMLConfig)
+		err := writeYAMLEndpoint(path, nil, &wishlist.Endpoint{Name: "db1", Group: "prod", Address: "db1:22"})
+		require.EqualError(t, err, `endpoint "prod/db1" is already defined in "`+path+`"`)
+		requireFileContent(t, path, writeYAMLConfig)
+	})
+
+	t.Run("edit missing", func(t *testing.T) {
+		path := writeTestYAML(t, writeYAMLConfig)
+		old := &wishlist.Endpoint{Name: "db1", Address: "db1:22"}
+		err := writeYAMLEndpoint(path, old, old)
+		require.EqualError(t, err, `endpoint "db1" is not defined in "`+path+`"`)
+	})
+}
+
+func TestGetEndpointWriter(t *testing.T) {
+	e := &wishlist.Endpoint{Name: "foo", Address: "foo.local:22"}
+
+	yml := writeTestYAML(t, "")
+	require.NoError(t, getEndpointWriter(yml)(nil, e))
+	requireFileContent(t, yml, "endpoints:\n  - name: foo\n    address: foo.local:22\n")
+
+	sshcfg := filepath.Join(t.TempDir(), "config")
+	require.NoError(t, getEndpointWriter(sshcfg)(nil, e))
+	requireFileContent(t, sshcfg, "Host foo\n  HostName foo.local\n")
+}
@@ -0,0 +1,110 @@
+package wishlist
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// validateCommands returns an error if any command has no name, or if names
+// are repeated.
+func validateCommands(commands []Command) error {
+	seen := map[string]bool{}
+	for _, c := range commands {
+		if strings.TrimSpace(c.Name) == "" {
+			return fmt.Errorf("command %q has no name", c.Command)
+		}
+		if seen[c.Name] {
+			return fmt.Errorf("command %q is defined more than once", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// FindCommand returns the command with the given name, or an error if the
+// endpoint has no such command.
+func (e *Endpoint) FindCommand(name string) (*Command, error) {
+	names := make([]string, 0, len(e.Commands))
+	for i, c := range e.Commands {
+		if c.Name == name {
+			return &e.Commands[i], nil
+		}
+		names = append(names, fmt.Sprintf("%q", c.Name))
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%q has no commands", e.Name)
+	}
+	return nil, fmt.Errorf("%q has no command %q, valid commands are %s", e.Name, name, strings.Join(names, ", "))
+}
+
+// WithCommand returns a copy of the endpoint which runs the given command
+// instead of its RemoteCommand.
+func (e *Endpoint) WithCommand(c Command) *Endpoint {
+	cp := *e
+	cp.RemoteCommand = c.Command
+	cp.RequestTTY = c.TTY
+	return &cp
+}
+
+// commandMenu is the menu of commands of an endpoint.
+type commandMenu struct {
+	menu
+	endpoint *Endpoint
+}
+
+func newCommandMenu(e *Endpoint) *commandMenu {
+	items := make([]menuItem, 0, len(e.Commands))
+	for _, c := range e.Commands {
+		items = append(items, menuItem{
+			title: c.Name,
+			desc:  FirstNonEmpty(c.Command, "shell"),
+		})
+	}
+	return &commandMenu{
+		menu: menu{
+			title: fmt.Sprintf("Commands of %q", e.FullName()),
+			items: items,
+		},
+		endpoint: e,
+	}
+}
+
+// connect connects to the given endpoint, or shows its commands menu if it
+// has any.
+func (m *ListModel) connect(e *Endpoint) tea.Cmd {
+	if len(e.Commands) > 0 {
+		m.commands = newCommandMenu(e)
+		m.view = viewCommands
+		return nil
+	}
+	return m.exec(e)
+}
+
+// exec hands the terminal over to a session to the given endpoint.
+func (m *ListModel) exec(e *Endpoint) tea.Cmd {
+	return tea.Exec(m.client.For(e), func(err error) tea.Msg {
+		return errMsg{err}
+	})
+}
+
+func (m *ListModel) updateCommands(msg tea.KeyMsg) tea.Cmd {
+	menu := m.commands
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.view = viewList
+		m.commands = nil
+	case menu.move(msg, m.keys):
+	case key.Matches(msg, m.keys.Confirm):
+		m.view = viewList
+		m.commands = nil
+		return m.exec(menu.endpoint.WithCommand(menu.endpoint.Commands[menu.cursor]))
+	}
+	return nil
+}
+
+func (m *ListModel) commandsView() string {
+	return m.menuView(&m.commands.menu, m.keys.Confirm)
+}
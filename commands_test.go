@@ -0,0 +1,116 @@
+package wishlist
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnectingClient records the endpoints it connects to.
+type fakeConnectingClient struct {
+	fakeClient
+	connected *Endpoint
+}
+
+func (c *fakeConnectingClient) For(e *Endpoint) tea.ExecCommand {
+	c.connected = e
+	return nil
+}
+
+func TestValidateCommands(t *testing.T) {
+	require.NoError(t, validateCommands(nil))
+	require.NoError(t, validateCommands([]Command{
+		{Name: "psql", Command: "psql", TTY: true},
+		{Name: "shell"},
+	}))
+	require.EqualError(t, validateCommands([]Command{
+		{Command: "uptime"},
+	}), `command "uptime" has no name`)
+	require.EqualError(t, validateCommands([]Command{
+		{Name: "logs", Command: "tail -f /var/log/syslog"},
+		{Name: "logs", Command: "journalctl -f"},
+	}), `command "logs" is defined more than once`)
+}
+
+func TestWithCommand(t *testing.T) {
+	e := &Endpoint{
+		Name:          "db1",
+		RemoteCommand: "tmux attach",
+		RequestTTY:    true,
+		Commands: []Command{
+			{Name: "tail logs", Command: "tail -f /var/log/postgres.log"},
+			{Name: "psql", Command: "psql", TTY: true},
+		},
+	}
+
+	_, err := e.FindCommand("nope")
+	require.EqualError(t, err, `"db1" has no command "nope", valid commands are "tail logs", "psql"`)
+	_, err = (&Endpoint{Name: "web1"}).FindCommand("nope")
+	require.EqualError(t, err, `"web1" has no commands`)
+	c, err := e.FindCommand("tail logs")
+	require.NoError(t, err)
+
+	got := e.WithCommand(*c)
+	require.Equal(t, "tail -f /var/log/postgres.log", got.RemoteCommand)
+	require.False(t, got.RequestTTY)
+	require.Equal(t, "db1", got.Name)
+
+	// the original endpoint is not changed.
+	require.Equal(t, "tmux attach", e.RemoteCommand)
+	require.True(t, e.RequestTTY)
+}
+
+func TestCommandMenu(t *testing.T) {
+	endpoints := []*Endpoint{
+		{
+			Name:    "db1",
+			Address: "db1:22",
+			Commands: []Command{
+				{Name: "psql", Command: "psql", TTY: true},
+				{Name: "tail logs", Command: "tail -f /var/log/postgres.log"},
+				{Name: "shell"},
+			},
+		},
+	}
+
+	t.Run("run", func(t *testing.T) {
+		client := &fakeConnectingClient{}
+		m := NewListing(endpoints, client, testRenderer)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewCommands, m.view)
+		require.Nil(t, client.connected)
+
+		view := m.View()
+		require.Contains(t, view, `Commands of "db1"`)
+		require.Contains(t, view, "tail logs")
+		require.Contains(t, view, "shell")
+
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyUp})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewList, m.view)
+		require.NotNil(t, client.connected)
+		require.Equal(t, "tail -f /var/log/postgres.log", client.connected.RemoteCommand)
+		require.False(t, client.connected.RequestTTY)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		client := &fakeConnectingClient{}
+		m := NewListing(endpoints, client, testRenderer)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		require.Equal(t, viewList, m.view)
+		require.Nil(t, client.connected)
+	})
+
+	t.Run("no commands", func(t *testing.T) {
+		client := &fakeConnectingClient{}
+		m := NewListing([]*Endpoint{{Name: "web1", Address: "web1:22"}}, client, testRenderer)
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewList, m.view)
+		require.Equal(t, "web1", client.connected.Name)
+	})
+}
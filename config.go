@@ -35,10 +35,18 @@ func (l Link) String() string {
 	return fmt.Sprintf("%s %s", l.Name, l.URL)
 }
 
+// Command is a predefined command that can be run on an endpoint.
+type Command struct {
+	Name    string `yaml:"name"`    // Command name, e.g. `tail logs`.
+	Command string `yaml:"command"` // Command to run, if empty, a shell is started.
+	TTY     bool   `yaml:"tty"`     // TTY defines whether to request a TTY. Anologous to SSH's config RequestTTY.
+}
+
 // Endpoint represents an endpoint to list.
 // If it has a Handler, wishlist will start an SSH server on the given address.
 type Endpoint struct {
 	Name                     string            `yaml:"name"`                      // Endpoint name.
+	Group                    string            `yaml:"group"`                     // Group in which the endpoint is listed, path-like, e.g. `prod/eu/db`.
 	Address                  string            `yaml:"address"`                   // Endpoint address in the `host:port` format, if empty, will be the same address as the list, increasing the port number.
 	User                     string            `yaml:"user"`                      // User to authenticate as.
 	ForwardAgent             bool              `yaml:"forward_agent"`             // ForwardAgent defines whether to forward the current agent. Anologous to SSH's config ForwardAgent.
@@ -46,12 +54,21 @@ type Endpoint struct {
 	RemoteCommand            string            `yaml:"remote_command"`            // RemoteCommand defines whether to request a TTY. Anologous to SSH's config RemoteCommand.
 	Desc                     string            `yaml:"description"`               // Description describes an optional description of the item.
 	Link                     Link              `yaml:"link"`                      // Links can be used to add a link to the item description using OSC8.
+	Tags                     []string          `yaml:"tags"`                      // Tags can be used to filter endpoints, e.g. `tag:prod`.
 	ProxyJump                string            `yaml:"proxy_jump"`                // Analogous to SSH's ProxyJump
+	ProxyCommand             string            `yaml:"proxy_command"`             // Analogous to SSH's ProxyCommand, only used in server mode if allowed in the config.
 	SendEnv                  []string          `yaml:"send_env"`                  // Analogous to SSH's SendEnv
 	SetEnv                   []string          `yaml:"set_env"`                   // Analogous to SSH's SetEnv
 	PreferredAuthentications []string          `yaml:"preferred_authentications"` // Analogous to SSH's PreferredAuthentications
 	IdentityFiles            []string          `yaml:"identity_files"`            // IdentityFiles is only used when in local mode.
 	Timeout                  time.Duration     `yaml:"connect_timeout"`           // Connection timeout.
+	StrictHostKeyChecking    string            `yaml:"strict_host_key_checking"`  // Analogous to SSH's StrictHostKeyChecking, defaults to accept-new.
+	UserKnownHostsFile       []string          `yaml:"user_known_hosts_file"`     // Analogous to SSH's UserKnownHostsFile, new host keys are added to the first one.
+	GlobalKnownHostsFile     []string          `yaml:"global_known_hosts_file"`   // Analogous to SSH's GlobalKnownHostsFile, only used when in local mode.
+	Commands                 []Command         `yaml:"commands"`                  // Commands offered in a menu when connecting to the endpoint.
+	LocalForward             []string          `yaml:"local_forward"`             // Analogous to SSH's LocalForward, only used when in local mode.
+	RemoteForward            []string          `yaml:"remote_forward"`            // Analogous to SSH's RemoteForward, only used when in local mode.
+	DynamicForward           []string          `yaml:"dynamic_forward"`           // Analogous to SSH's DynamicForward, only used when in local mode.
 	Middlewares              []wish.Middleware `yaml:"-"`                         // wish middlewares you can use in the factory method.
 }
 
@@ -59,6 +76,7 @@ type Endpoint struct {
 // for example) and set additional options into it.
 type EndpointHint struct {
 	Match                    string        `yaml:"match"`
+	Group                    string        `yaml:"group"`
 	Port                     string        `yaml:"port"`
 	User                     string        `yaml:"user"`
 	ForwardAgent             *bool         `yaml:"forward_agent"`
@@ -66,12 +84,21 @@ type EndpointHint struct {
 	RemoteCommand            string        `yaml:"remote_command"`
 	Desc                     string        `yaml:"description"`
 	Link                     Link          `yaml:"link"`
+	Tags                     []string      `yaml:"tags"`
 	ProxyJump                string        `yaml:"proxy_jump"`
+	ProxyCommand             string        `yaml:"proxy_command"`
 	SendEnv                  []string      `yaml:"send_env"`
 	SetEnv                   []string      `yaml:"set_env"`
 	PreferredAuthentications []string      `yaml:"preferred_authentications"`
 	IdentityFiles            []string      `yaml:"identity_files"`
 	Timeout                  time.Duration `yaml:"connect_timeout"`
+	StrictHostKeyChecking    string        `yaml:"strict_host_key_checking"`
+	UserKnownHostsFile       []string      `yaml:"user_known_hosts_file"`
+	GlobalKnownHostsFile     []string      `yaml:"global_known_hosts_file"`
+	Commands                 []Command     `yaml:"commands"`
+	LocalForward             []string      `yaml:"local_forward"`
+	RemoteForward            []string      `yaml:"remote_forward"`
+	DynamicForward           []string      `yaml:"dynamic_forward"`
 }
 
 // Authentications returns either the client preferred authentications or the
@@ -128,6 +155,45 @@ func (e Endpoint) shouldSend(k string) bool {
 	return false
 }
 
+// FullName returns the endpoint name prefixed by its group, if any, e.g.
+// `prod/eu/db1`.
+func (e Endpoint) FullName() string {
+	if g := CleanGroup(e.Group); g != "" {
+		return g + "/" + e.Name
+	}
+	return e.Name
+}
+
+// FindEndpoint finds an endpoint by either its name or its full name, as in
+// `group/name`.
+// Full name matches take precedence.
+func FindEndpoint(endpoints []*Endpoint, name string) *Endpoint {
+	for _, e := range endpoints {
+		if e.FullName() == name {
+			return e
+		}
+	}
+	for _, e := range endpoints {
+		if e.Name == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// CleanGroup normalizes a group path, removing duplicated, leading and
+// trailing slashes.
+func CleanGroup(group string) string {
+	parts := strings.Split(group, "/")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return strings.Join(result, "/")
+}
+
 // String returns the endpoint in a friendly string format.
 func (e *Endpoint) String() string {
 	return fmt.Sprintf(`%q => "%s@%s"`, e.Name, e.User, e.Address)
@@ -145,22 +211,91 @@ func (e Endpoint) ShouldListen() bool {
 
 // Config represents the wishlist configuration.
 type Config struct {
-	Listen       string                              `yaml:"listen"`    // Address to listen on.
-	Port         int64                               `yaml:"port"`      // Port to start the first server on.
-	Endpoints    []*Endpoint                         `yaml:"endpoints"` // Endpoints to list.
-	Hints        []EndpointHint                      `yaml:"hints"`     // Endpoints hints to apply to discovered hosts.
-	Factory      func(Endpoint) (*ssh.Server, error) `yaml:"-"`         // Factory used to create the SSH server for the given endpoint.
-	Users        []User                              `yaml:"users"`     // Users allowed to access the list.
-	Metrics      Metrics                             `yaml:"metrics"`   // Metrics configuration.
-	EndpointChan chan []*Endpoint                    `yaml:"-"`         // Channel to update the endpoints. Used only in server mode.
+	Listen                string                              `yaml:"listen"`                   // Address to listen on.
+	Port                  int64                               `yaml:"port"`                     // Port to start the first server on.
+	Endpoints             []*Endpoint                         `yaml:"endpoints"`                // Endpoints to list.
+	Hints                 []EndpointHint                      `yaml:"hints"`                    // Endpoints hints to apply to discovered hosts.
+	Factory               func(Endpoint) (*ssh.Server, error) `yaml:"-"`                        // Factory used to create the SSH server for the given endpoint.
+	Users                 []User                              `yaml:"users"`                    // Users allowed to access the list.
+	TrustedUserCAKeys     []string                            `yaml:"trusted_user_ca_keys"`     // CA keys whose user certificates are allowed to access the list. Analogous to SSH's TrustedUserCAKeys.
+	AuthorizedKeys        []string                            `yaml:"authorized_keys"`          // Sources of the keys of all users, as in User.AuthorizedKeys, %u being replaced by the user name, or of any login name if there are no users. Analogous to SSH's AuthorizedKeysFile.
+	AuthorizedKeysRefresh time.Duration                       `yaml:"authorized_keys_refresh"`  // Interval to reload the keys from their sources. Defaults to 5m.
+	Admins                []string                            `yaml:"admins"`                   // Users allowed to add and edit endpoints. Used only in server mode.
+	Roles                 []Role                              `yaml:"roles"`                    // Roles restricting the endpoints users can access. Used only in server mode.
+	HideDeniedEndpoints   bool                                `yaml:"hide_denied_endpoints"`    // Reports endpoints users can't access as not found, instead of denied, so they are not disclosed. Used only in server mode.
+	Metrics               Metrics                             `yaml:"metrics"`                  // Metrics configuration.
+	Filter                Filter                              `yaml:"filter"`                   // Filter configuration.
+	Probe                 Probe                               `yaml:"probe"`                    // Reachability probe configuration.
+	Theme                 Theme                               `yaml:"theme"`                    // Theme of the listing UI.
+	Keys                  Keys                                `yaml:"keys"`                     // Key bindings of the listing UI.
+	DisableHostKeyReplace bool                                `yaml:"disable_host_key_replace"` // Prevents users from replacing changed host keys from the listing. Used only in server mode.
+	AllowProxyCommand     bool                                `yaml:"allow_proxy_command"`      // Allows connecting to endpoints through their ProxyCommand, which runs it in the server. Used only in server mode.
+	Recording             Recording                           `yaml:"recording"`                // Session recording configuration. Used only in server mode.
+	Audit                 Audit                               `yaml:"audit"`                    // Audit log configuration. Used only in server mode.
+	EndpointChan          chan []*Endpoint                    `yaml:"-"`                        // Channel to update the endpoints. Used only in server mode.
+	EndpointWriter        EndpointWriter                      `yaml:"-"`                        // Writer of endpoints added or edited from the listing, nil disables editing.
 
 	lastPort int64
+	audit    *auditLog
+	keys     *keyStore
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	if err := c.Theme.Validate(); err != nil {
+		return err
+	}
+	if err := c.Keys.Validate(); err != nil {
+		return err
+	}
+	for _, e := range c.Endpoints {
+		if _, err := ParseHostKeyChecking(e.StrictHostKeyChecking); err != nil {
+			return fmt.Errorf("endpoint %q: %w", e.Name, err)
+		}
+		if err := validateCommands(e.Commands); err != nil {
+			return fmt.Errorf("endpoint %q: %w", e.Name, err)
+		}
+		if err := validateForwards(e.LocalForward, e.RemoteForward, e.DynamicForward); err != nil {
+			return fmt.Errorf("endpoint %q: %w", e.Name, err)
+		}
+		if _, err := resolveJumps(e, c.Endpoints); err != nil {
+			return fmt.Errorf("endpoint %q: %w", e.Name, err)
+		}
+		if err := ValidateProxyCommand(e.ProxyCommand); err != nil {
+			return fmt.Errorf("endpoint %q: %w", e.Name, err)
+		}
+	}
+	if _, err := parseUserCAKeys(c.TrustedUserCAKeys); err != nil {
+		return err
+	}
+	if err := validateAccess(c.Roles, c.Users); err != nil {
+		return err
+	}
+	for _, h := range c.Hints {
+		if _, err := ParseHostKeyChecking(h.StrictHostKeyChecking); err != nil {
+			return fmt.Errorf("hint %q: %w", h.Match, err)
+		}
+		if err := validateCommands(h.Commands); err != nil {
+			return fmt.Errorf("hint %q: %w", h.Match, err)
+		}
+		if err := validateForwards(h.LocalForward, h.RemoteForward, h.DynamicForward); err != nil {
+			return fmt.Errorf("hint %q: %w", h.Match, err)
+		}
+		if err := ValidateProxyCommand(h.ProxyCommand); err != nil {
+			return fmt.Errorf("hint %q: %w", h.Match, err)
+		}
+	}
+	return nil
 }
 
 // User contains user-level configuration for a repository.
 type User struct {
-	Name       string   `yaml:"name"`
-	PublicKeys []string `yaml:"public-keys"`
+	Name           string   `yaml:"name"`
+	PublicKeys     []string `yaml:"public-keys"`
+	AuthorizedKeys []string `yaml:"authorized-keys"` // Sources of more keys: authorized_keys files, directories of .pub files, or HTTP(S) URLs, e.g. https://github.com/<user>.keys.
+	Roles          []string `yaml:"roles"`           // Roles of the user, whose access rules apply to them.
+	Allow          []string `yaml:"allow"`           // Rules of the endpoints allowed, if any, all others are denied.
+	Deny           []string `yaml:"deny"`            // Rules of the endpoints denied, taking precedence over allow rules.
 }
 
 // Metrics configuration.
@@ -169,3 +304,23 @@ type Metrics struct {
 	Name    string `yaml:"name"`
 	Address string `yaml:"address"`
 }
+
+// Filter configuration.
+type Filter struct {
+	// Fields free text filter terms are matched against, in order of
+	// preference. Defaults to DefaultFilterFields.
+	Fields []string `yaml:"fields"`
+}
+
+// Probe configuration.
+type Probe struct {
+	// Enabled enables periodically probing whether endpoints are reachable.
+	Enabled bool `yaml:"enabled"`
+	// Interval between probes. Defaults to 30s.
+	Interval time.Duration `yaml:"interval"`
+	// Timeout of each probe. Defaults to 5s.
+	Timeout time.Duration `yaml:"timeout"`
+	// SSH also completes the SSH version exchange, instead of only
+	// establishing a TCP connection.
+	SSH bool `yaml:"ssh"`
+}
@@ -130,3 +130,56 @@ func TestAuth(t *testing.T) {
 		}.Authentications(),
 	)
 }
+
+func TestFullName(t *testing.T) {
+	require.Equal(t, "foo", Endpoint{Name: "foo"}.FullName())
+	require.Equal(t, "prod/eu/foo", Endpoint{Name: "foo", Group: "/prod//eu/"}.FullName())
+}
+
+func TestFindEndpoint(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "foo", Group: "prod"},
+		{Name: "prod/foo"},
+		{Name: "bar"},
+	}
+	require.Equal(t, endpoints[0], FindEndpoint(endpoints, "prod/foo"))
+	require.Equal(t, endpoints[0], FindEndpoint(endpoints, "foo"))
+	require.Equal(t, endpoints[2], FindEndpoint(endpoints, "bar"))
+	require.Nil(t, FindEndpoint(endpoints, "nope"))
+}
+
+func TestConfigValidate(t *testing.T) {
+	require.NoError(t, Config{
+		Endpoints: []*Endpoint{{Name: "a", StrictHostKeyChecking: "ask"}},
+	}.Validate())
+	require.EqualError(t, Config{
+		Endpoints: []*Endpoint{{Name: "a", StrictHostKeyChecking: "maybe"}},
+	}.Validate(), `endpoint "a": invalid StrictHostKeyChecking: "maybe"`)
+	require.EqualError(t, Config{
+		Hints: []EndpointHint{{Match: "*.local", StrictHostKeyChecking: "sure"}},
+	}.Validate(), `hint "*.local": invalid StrictHostKeyChecking: "sure"`)
+	require.EqualError(t, Config{
+		Endpoints: []*Endpoint{{Name: "a", Commands: []Command{{Name: "x"}, {Name: "x"}}}},
+	}.Validate(), `endpoint "a": command "x" is defined more than once`)
+	require.EqualError(t, Config{
+		Endpoints: []*Endpoint{{Name: "a", LocalForward: []string{"5432"}}},
+	}.Validate(), `endpoint "a": invalid forward: "5432"`)
+	require.EqualError(t, Config{
+		Hints: []EndpointHint{{Match: "*.local", DynamicForward: []string{"socks"}}},
+	}.Validate(), `hint "*.local": invalid dynamic forward: "socks": invalid port "socks"`)
+	require.EqualError(t, Config{
+		Endpoints: []*Endpoint{
+			{Name: "a", ProxyJump: "b"},
+			{Name: "b", ProxyJump: "a"},
+		},
+	}.Validate(), `endpoint "a": ProxyJump cycle: a → b → a`)
+	require.EqualError(t, Config{
+		Endpoints: []*Endpoint{{Name: "a", ProxyCommand: "nc %h %P"}},
+	}.Validate(), `endpoint "a": invalid ProxyCommand "nc %h %P": unknown token %P`)
+	require.EqualError(t, Config{
+		Hints: []EndpointHint{{Match: "*.local", ProxyCommand: "nc %h %"}},
+	}.Validate(), `hint "*.local": invalid ProxyCommand "nc %h %": trailing %`)
+	require.ErrorContains(t, Config{
+		TrustedUserCAKeys: []string{"ssh-ed25519 nope"},
+	}.Validate(), `invalid trusted user CA key "ssh-ed25519 nope"`)
+}
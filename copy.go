@@ -0,0 +1,250 @@
+package wishlist
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyMenu is the menu of the ways an endpoint can be copied.
+type copyMenu struct {
+	menu
+	endpoint *Endpoint
+	values   []string
+}
+
+func newCopyMenu(e *Endpoint) *copyMenu {
+	host, _ := splitAddress(e.Address)
+	options := []struct {
+		title, value string
+	}{
+		{"Host", host},
+		{"Address", e.Address},
+		{"SSH URL", sshURL(e)},
+		{"SSH command", sshCommand(e)},
+		{"Host block", sshConfigBlock(e)},
+	}
+
+	c := &copyMenu{
+		menu:     menu{title: fmt.Sprintf("Copy %q", e.FullName())},
+		endpoint: e,
+	}
+	for _, o := range options {
+		desc, _, multiline := strings.Cut(o.value, "\n")
+		if multiline {
+			desc += " …"
+		}
+		c.items = append(c.items, menuItem{title: o.title, desc: desc})
+		c.values = append(c.values, o.value)
+	}
+	return c
+}
+
+// splitAddress splits the given address into its host and port, the port
+// being empty if the address has none.
+func splitAddress(address string) (string, string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address, ""
+	}
+	return host, port
+}
+
+// sshURL returns the `ssh://` URL of the endpoint.
+func sshURL(e *Endpoint) string {
+	return "ssh://" + e.Address
+}
+
+// sshCommand returns the OpenSSH command line equivalent to connecting to
+// the endpoint.
+func sshCommand(e *Endpoint) string {
+	host, port := splitAddress(e.Address)
+	args := []string{"ssh"}
+	if e.ProxyJump != "" {
+		args = append(args, "-J", e.ProxyJump)
+	}
+	if port != "" && port != "22" {
+		args = append(args, "-p", port)
+	}
+	if e.ForwardAgent {
+		args = append(args, "-A")
+	}
+	if e.RequestTTY {
+		args = append(args, "-t")
+	}
+	for _, f := range e.IdentityFiles {
+		args = append(args, "-i", f)
+	}
+	// forwards can be in the config format, which has a space instead of
+	// the colon the flags expect.
+	for _, f := range e.LocalForward {
+		args = append(args, "-L", strings.Join(strings.Fields(f), ":"))
+	}
+	for _, f := range e.RemoteForward {
+		args = append(args, "-R", strings.Join(strings.Fields(f), ":"))
+	}
+	for _, f := range e.DynamicForward {
+		args = append(args, "-D", f)
+	}
+	for _, opt := range sshOptions(e) {
+		args = append(args, "-o", opt[0]+"="+opt[1])
+	}
+	args = append(args, userHost(e.User, host))
+	if e.RemoteCommand != "" {
+		args = append(args, e.RemoteCommand)
+	}
+
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, shellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// sshConfigBlock returns a `Host` block for the SSH config file equivalent
+// to the endpoint.
+func sshConfigBlock(e *Endpoint) string {
+	host, port := splitAddress(e.Address)
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Host %s\n", e.Name)
+	option := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "  %s %s\n", name, value)
+		}
+	}
+	annotation := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "  # wishlist.%s: %s\n", name, value)
+		}
+	}
+
+	annotation("group", CleanGroup(e.Group))
+	annotation("tags", strings.Join(e.Tags, ", "))
+	if e.Desc != "" {
+		for _, line := range strings.Split(e.Desc, "\n") {
+			annotation("description", line)
+		}
+	}
+	annotation("link", strings.TrimSpace(e.Link.URL+" "+e.Link.Name))
+	if host != e.Name {
+		option("HostName", host)
+	}
+	if port != "22" {
+		option("Port", port)
+	}
+	option("User", e.User)
+	option("ProxyJump", e.ProxyJump)
+	if e.ForwardAgent {
+		option("ForwardAgent", "yes")
+	}
+	if e.RequestTTY {
+		option("RequestTTY", "yes")
+	}
+	option("RemoteCommand", e.RemoteCommand)
+	for _, f := range e.IdentityFiles {
+		option("IdentityFile", f)
+	}
+	for _, f := range e.LocalForward {
+		option("LocalForward", f)
+	}
+	for _, f := range e.RemoteForward {
+		option("RemoteForward", f)
+	}
+	for _, f := range e.DynamicForward {
+		option("DynamicForward", f)
+	}
+	for _, opt := range sshOptions(e) {
+		option(opt[0], opt[1])
+	}
+	return sb.String()
+}
+
+// sshOptions returns the options of the endpoint which have no command line
+// flag, as name and value pairs.
+func sshOptions(e *Endpoint) [][2]string {
+	var opts [][2]string
+	option := func(name, value string) {
+		if value != "" {
+			opts = append(opts, [2]string{name, value})
+		}
+	}
+	option("ProxyCommand", e.ProxyCommand)
+	if e.Timeout > 0 {
+		option("ConnectTimeout", fmt.Sprintf("%d", int(math.Ceil(e.Timeout.Seconds()))))
+	}
+	option("StrictHostKeyChecking", e.StrictHostKeyChecking)
+	option("UserKnownHostsFile", strings.Join(e.UserKnownHostsFile, " "))
+	option("GlobalKnownHostsFile", strings.Join(e.GlobalKnownHostsFile, " "))
+	option("PreferredAuthentications", strings.Join(e.PreferredAuthentications, ","))
+	for _, env := range e.SendEnv {
+		option("SendEnv", env)
+	}
+	for _, env := range e.SetEnv {
+		option("SetEnv", env)
+	}
+	return opts
+}
+
+// userHost returns `user@host`, or just the host if there's no user.
+func userHost(user, host string) string {
+	if user == "" {
+		return host
+	}
+	return user + "@" + host
+}
+
+var shellSafe = regexp.MustCompile(`^[a-zA-Z0-9@%_+=:,./~-]+$`)
+
+// shellQuote quotes the given argument for POSIX shells, if needed.
+func shellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// startCopy shows the copy menu of the selected endpoint.
+func (m *ListModel) startCopy() tea.Cmd {
+	w := m.selected()
+	if w == nil {
+		return nil
+	}
+	m.copying = newCopyMenu(w.endpoint)
+	m.view = viewCopy
+	return nil
+}
+
+func (m *ListModel) updateCopy(msg tea.KeyMsg) tea.Cmd {
+	menu := m.copying
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.view = viewList
+		m.copying = nil
+	case menu.move(msg, m.keys):
+	case key.Matches(msg, m.keys.Confirm):
+		m.view = viewList
+		m.copying = nil
+		value := menu.values[menu.cursor]
+		m.clipboard(value)
+		if strings.Contains(value, "\n") {
+			return m.list.NewStatusMessage(fmt.Sprintf(
+				"copied the %s of %q to the clipboard",
+				menu.items[menu.cursor].title,
+				menu.endpoint.Name,
+			))
+		}
+		return m.list.NewStatusMessage(fmt.Sprintf("copied %q to the clipboard", value))
+	}
+	return nil
+}
+
+func (m *ListModel) copyView() string {
+	confirm := m.keys.Confirm
+	confirm.SetHelp(confirm.Help().Key, "copy")
+	return m.menuView(&m.copying.menu, confirm)
+}
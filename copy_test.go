@@ -0,0 +1,159 @@
+package wishlist
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSHCommand(t *testing.T) {
+	t.Run("simple", func(t *testing.T) {
+		require.Equal(t, "ssh db1.local", sshCommand(&Endpoint{
+			Name:    "db1",
+			Address: "db1.local:22",
+		}))
+	})
+
+	t.Run("proxy command", func(t *testing.T) {
+		require.Equal(t, "ssh -o 'ProxyCommand=nc %h %p' db1.local", sshCommand(&Endpoint{
+			Name:         "db1",
+			Address:      "db1.local:22",
+			ProxyCommand: "nc %h %p",
+		}))
+	})
+
+	t.Run("full", func(t *testing.T) {
+		require.Equal(
+			t,
+			"ssh -J jump@bastion:2222 -p 2234 -A -t -i ~/.ssh/id_ed25519 "+
+				"-L 5432:localhost:5432 -R '*:8080:localhost:80' -D 1080 "+
+				"-o ConnectTimeout=2 -o StrictHostKeyChecking=ask "+
+				"-o 'UserKnownHostsFile=~/.ssh/known_hosts ~/.ssh/known_hosts2' "+
+				"-o 'SendEnv=LC_*' -o SetEnv=FOO=bar "+
+				"app@db1.local 'tail -f /var/log/app.log'",
+			sshCommand(&Endpoint{
+				Name:                  "db1",
+				Address:               "db1.local:2234",
+				User:                  "app",
+				ProxyJump:             "jump@bastion:2222",
+				ForwardAgent:          true,
+				RequestTTY:            true,
+				RemoteCommand:         "tail -f /var/log/app.log",
+				IdentityFiles:         []string{"~/.ssh/id_ed25519"},
+				LocalForward:          []string{"5432 localhost:5432"},
+				RemoteForward:         []string{"*:8080:localhost:80"},
+				DynamicForward:        []string{"1080"},
+				Timeout:               1500 * time.Millisecond,
+				StrictHostKeyChecking: "ask",
+				UserKnownHostsFile:    []string{"~/.ssh/known_hosts", "~/.ssh/known_hosts2"},
+				SendEnv:               []string{"LC_*"},
+				SetEnv:                []string{"FOO=bar"},
+			}),
+		)
+	})
+}
+
+func TestSSHConfigBlock(t *testing.T) {
+	require.Equal(t, "Host db1\n  HostName db1.local\n", sshConfigBlock(&Endpoint{
+		Name:    "db1",
+		Address: "db1.local:22",
+	}))
+	require.Equal(t, "Host db1\n", sshConfigBlock(&Endpoint{
+		Name:    "db1",
+		Address: "db1:22",
+	}))
+	require.Equal(t, `Host db1
+  # wishlist.group: prod/eu
+  # wishlist.tags: prod, db
+  # wishlist.description: The database.
+  # wishlist.description: Don't drop it.
+  # wishlist.link: https://db1 Dashboard
+  HostName db1.local
+  Port 2234
+  User app
+  ProxyJump bastion
+  ForwardAgent yes
+  IdentityFile ~/.ssh/id_ed25519
+  LocalForward 5432 localhost:5432
+  ConnectTimeout 10
+  SetEnv FOO=bar
+`, sshConfigBlock(&Endpoint{
+		Name:          "db1",
+		Group:         "/prod/eu/",
+		Address:       "db1.local:2234",
+		User:          "app",
+		ProxyJump:     "bastion",
+		ForwardAgent:  true,
+		Desc:          "The database.\nDon't drop it.",
+		Link:          Link{Name: "Dashboard", URL: "https://db1"},
+		Tags:          []string{"prod", "db"},
+		IdentityFiles: []string{"~/.ssh/id_ed25519"},
+		LocalForward:  []string{"5432 localhost:5432"},
+		Timeout:       10 * time.Second,
+		SetEnv:        []string{"FOO=bar"},
+	}))
+}
+
+func TestShellQuote(t *testing.T) {
+	for in, out := range map[string]string{
+		"db1.local":          "db1.local",
+		"~/.ssh/id_rsa":      "~/.ssh/id_rsa",
+		"user@host:22":       "user@host:22",
+		"uptime -a":          "'uptime -a'",
+		"echo 'hi'":          `'echo '\''hi'\'''`,
+		"LC_*":               "'LC_*'",
+		"$HOME":              "'$HOME'",
+		"SetEnv=FOO=bar,baz": "SetEnv=FOO=bar,baz",
+	} {
+		t.Run(in, func(t *testing.T) {
+			require.Equal(t, out, shellQuote(in))
+		})
+	}
+}
+
+func TestCopyMenu(t *testing.T) {
+	e := &Endpoint{
+		Name:    "db1",
+		Address: "db1.local:2234",
+		User:    "app",
+	}
+	var copied string
+	newModel := func() *ListModel {
+		m := NewListing([]*Endpoint{e}, fakeClient{}, testRenderer)
+		m.clipboard = func(s string) { copied = s }
+		return m
+	}
+
+	for i, expected := range []string{
+		"db1.local",
+		"db1.local:2234",
+		"ssh://db1.local:2234",
+		"ssh -p 2234 app@db1.local",
+		"Host db1\n  HostName db1.local\n  Port 2234\n  User app\n",
+	} {
+		t.Run(expected, func(t *testing.T) {
+			copied = ""
+			m := newModel()
+			typeKeys(m, "y")
+			require.Equal(t, viewCopy, m.view)
+			require.Contains(t, m.View(), `Copy "db1"`)
+			for range i {
+				_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+			}
+			_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+			require.Equal(t, viewList, m.view)
+			require.Equal(t, expected, copied)
+		})
+	}
+
+	t.Run("cancel", func(t *testing.T) {
+		copied = ""
+		m := newModel()
+		typeKeys(m, "y")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		require.Equal(t, viewList, m.view)
+		require.Empty(t, copied)
+	})
+}
@@ -0,0 +1,247 @@
+package wishlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+)
+
+// detailMinSideWidth is the minimum window width in which the detail pane is
+// shown at the side of the list. On narrower windows, it's shown at the
+// bottom.
+const detailMinSideWidth = 100
+
+// detailGutter is the space between the list and the detail pane when it's
+// shown at the side.
+const detailGutter = 2
+
+// detailPane renders the full configuration of an endpoint.
+// It caches the last rendered endpoint, as rendering Markdown is expensive.
+type detailPane struct {
+	renderer *lipgloss.Renderer
+	endpoint *Endpoint
+	width    int
+	content  string
+}
+
+// render renders the given endpoint to fit the given width.
+func (d *detailPane) render(e *Endpoint, width int) string {
+	if d.endpoint == e && d.width == width && d.content != "" {
+		return d.content
+	}
+	d.endpoint = e
+	d.width = width
+	d.content = renderMarkdown(d.renderer, detailMarkdown(e), width)
+	return d.content
+}
+
+// renderMarkdown renders the given Markdown using glamour, falling back to
+// the raw Markdown if that fails.
+func renderMarkdown(r *lipgloss.Renderer, md string, width int) string {
+	style := "light"
+	if r.HasDarkBackground() {
+		style = "dark"
+	}
+	tr, err := glamour.NewTermRenderer(
+		glamour.WithStandardStyle(style),
+		glamour.WithColorProfile(r.ColorProfile()),
+		glamour.WithWordWrap(width),
+	)
+	if err != nil {
+		log.Warn("could not create markdown renderer", "err", err)
+		return md
+	}
+	out, err := tr.Render(md)
+	if err != nil {
+		log.Warn("could not render markdown", "err", err)
+		return md
+	}
+	return strings.Trim(out, "\n")
+}
+
+// detailMarkdown returns the full configuration of the given endpoint as
+// Markdown.
+func detailMarkdown(e *Endpoint) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# %s\n\n", e.Name)
+	if e.Desc != "" {
+		sb.WriteString(e.Desc + "\n\n")
+	}
+
+	field := func(name, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "- **%s**: %s\n", name, value)
+		}
+	}
+	code := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "`" + s + "`"
+	}
+
+	field("Group", code(CleanGroup(e.Group)))
+	field("Address", code(e.Address))
+	field("User", code(e.User))
+	field("Link", linkMarkdown(e.Link))
+	if len(e.Tags) > 0 {
+		field("Tags", strings.Join(e.Tags, ", "))
+	}
+	if e.ProxyJump != "" {
+		field("Proxy jump", proxyJumpChain(e))
+	}
+	field("Proxy command", code(e.ProxyCommand))
+	field("Remote command", code(e.RemoteCommand))
+	if e.ForwardAgent {
+		field("Forward agent", "yes")
+	}
+	if e.RequestTTY {
+		field("Request TTY", "yes")
+	}
+	if e.Timeout > 0 {
+		field("Connect timeout", e.Timeout.String())
+	}
+	field("Authentications", strings.Join(e.Authentications(), ", "))
+	if len(e.IdentityFiles) > 0 {
+		files := make([]string, 0, len(e.IdentityFiles))
+		for _, f := range e.IdentityFiles {
+			files = append(files, code(f))
+		}
+		field("Identity files", strings.Join(files, ", "))
+	}
+	forwards := func(name string, specs []string) {
+		quoted := make([]string, 0, len(specs))
+		for _, s := range specs {
+			quoted = append(quoted, code(s))
+		}
+		field(name, strings.Join(quoted, ", "))
+	}
+	forwards("Local forwards", e.LocalForward)
+	forwards("Remote forwards", e.RemoteForward)
+	forwards("Dynamic forwards", e.DynamicForward)
+	field("Host key checking", code(e.StrictHostKeyChecking))
+	if len(e.UserKnownHostsFile) > 0 {
+		field("Known hosts", code(strings.Join(e.UserKnownHostsFile, " ")))
+	}
+	if len(e.SendEnv) > 0 {
+		field("Send env", code(strings.Join(e.SendEnv, " ")))
+	}
+
+	if len(e.Commands) > 0 {
+		sb.WriteString("\n## Commands\n\n")
+		for _, c := range e.Commands {
+			fmt.Fprintf(&sb, "- **%s**: %s\n", c.Name, FirstNonEmpty(code(c.Command), "shell"))
+		}
+	}
+
+	if env := e.Environment(); len(env) > 0 {
+		sb.WriteString("\n## Environment\n\n")
+		keys := make([]string, 0, len(env))
+		for k := range env {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&sb, "- `%s=%s`\n", k, env[k])
+		}
+	}
+	return sb.String()
+}
+
+// linkMarkdown returns the given link as Markdown.
+func linkMarkdown(l Link) string {
+	if l.URL == "" {
+		return ""
+	}
+	if l.Name == "" {
+		return l.URL
+	}
+	return fmt.Sprintf("[%s](%s)", l.Name, l.URL)
+}
+
+// proxyJumpChain returns the hops to reach the given endpoint, e.g.
+// `bastion → internal → db1`.
+func proxyJumpChain(e *Endpoint) string {
+	var hops []string
+	for _, hop := range strings.Split(e.ProxyJump, ",") {
+		if hop = strings.TrimSpace(hop); hop != "" {
+			hops = append(hops, "`"+hop+"`")
+		}
+	}
+	return strings.Join(append(hops, "`"+e.Name+"`"), " → ")
+}
+
+// detailView renders the detail pane of the selected item in the given size.
+func (m *ListModel) detailView(width, height int, side bool) string {
+	style := m.styles.Detail
+	if side {
+		style = style.BorderLeft(true).PaddingLeft(1)
+	} else {
+		style = style.BorderTop(true)
+	}
+	width -= style.GetHorizontalFrameSize()
+	height -= style.GetVerticalFrameSize()
+
+	content := m.styles.NoContent.Render("no endpoint selected")
+	if w := m.selected(); w != nil {
+		content = m.detail.render(w.endpoint, width)
+	}
+	return style.
+		Width(width).
+		Height(height).
+		MaxHeight(height + style.GetVerticalFrameSize()).
+		Render(content)
+}
+
+// detailLayout returns whether the detail pane should be shown at the side,
+// and its size.
+func (m *ListModel) detailLayout(width, height int) (side bool, w, h int) {
+	if width >= detailMinSideWidth {
+		return true, width / 2, height //nolint:mnd
+	}
+	return false, width, height / 2 //nolint:mnd
+}
+
+// resize sets the list size, making room for the detail pane if it's shown.
+func (m *ListModel) resize() {
+	top, right, bottom, left := m.styles.Doc.GetMargin()
+	width, height := m.width-left-right, m.height-top-bottom
+	if !m.showDetail {
+		m.list.SetSize(width, height)
+		return
+	}
+	side, w, h := m.detailLayout(width, height)
+	if side {
+		m.list.SetSize(width-w-detailGutter, height)
+		return
+	}
+	m.list.SetSize(width, height-h)
+}
+
+// listView renders the list, along with the detail pane if it's shown.
+func (m *ListModel) listView() string {
+	if !m.showDetail {
+		return m.list.View()
+	}
+	top, right, bottom, left := m.styles.Doc.GetMargin()
+	side, w, h := m.detailLayout(m.width-left-right, m.height-top-bottom)
+	if side {
+		listWidth := m.width - left - right - w
+		return lipgloss.JoinHorizontal(
+			lipgloss.Top,
+			lipgloss.NewStyle().
+				MaxWidth(listWidth).
+				Render(lipgloss.PlaceHorizontal(listWidth, lipgloss.Left, m.list.View())),
+			m.detailView(w, h, true),
+		)
+	}
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.list.View(),
+		m.detailView(w, h, false),
+	)
+}
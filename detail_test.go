@@ -0,0 +1,80 @@
+package wishlist
+
+import (
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetailMarkdown(t *testing.T) {
+	md := detailMarkdown(&Endpoint{
+		Name:          "db1",
+		Group:         "/prod/eu/",
+		Address:       "db1.internal:22",
+		User:          "carlos",
+		Desc:          "The main database.\n\nDon't **drop** it.",
+		Link:          Link{Name: "Runbook", URL: "https://example.com"},
+		Tags:          []string{"prod", "db"},
+		ProxyJump:     "bastion, internal",
+		ForwardAgent:  true,
+		Timeout:       5 * time.Second,
+		IdentityFiles: []string{"~/.ssh/id_ed25519"},
+		SendEnv:       []string{"FOO_*"},
+		SetEnv:        []string{"B=2", "A=1"},
+	})
+	require.Equal(t, "# db1\n\n"+
+		"The main database.\n\nDon't **drop** it.\n\n"+
+		"- **Group**: `prod/eu`\n"+
+		"- **Address**: `db1.internal:22`\n"+
+		"- **User**: `carlos`\n"+
+		"- **Link**: [Runbook](https://example.com)\n"+
+		"- **Tags**: prod, db\n"+
+		"- **Proxy jump**: `bastion` → `internal` → `db1`\n"+
+		"- **Forward agent**: yes\n"+
+		"- **Connect timeout**: 5s\n"+
+		"- **Authentications**: publickey, keyboard-interactive\n"+
+		"- **Identity files**: `~/.ssh/id_ed25519`\n"+
+		"- **Send env**: `FOO_*`\n"+
+		"\n## Environment\n\n"+
+		"- `A=1`\n"+
+		"- `B=2`\n", md)
+}
+
+func TestDetailPane(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "foo", Address: "foo:22", Desc: "line one\nline two"},
+	}
+	m := NewListing(endpoints, fakeClient{}, testRenderer)
+
+	toggle := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("i")}
+
+	t.Run("side", func(t *testing.T) {
+		_, _ = m.Update(tea.WindowSizeMsg{Width: 120, Height: 30})
+		_, _ = m.Update(toggle)
+		require.True(t, m.showDetail)
+		require.Equal(t, 56, m.list.Width())
+		require.Equal(t, 28, m.list.Height())
+		view := m.View()
+		require.Contains(t, view, "line two")
+		require.LessOrEqual(t, lipgloss.Height(view), 30)
+	})
+
+	t.Run("bottom", func(t *testing.T) {
+		_, _ = m.Update(tea.WindowSizeMsg{Width: 80, Height: 30})
+		require.Equal(t, 76, m.list.Width())
+		require.Equal(t, 14, m.list.Height())
+		view := m.View()
+		require.Contains(t, view, "line two")
+		require.LessOrEqual(t, lipgloss.Height(view), 30)
+	})
+
+	t.Run("hidden", func(t *testing.T) {
+		_, _ = m.Update(toggle)
+		require.False(t, m.showDetail)
+		require.Equal(t, 28, m.list.Height())
+		require.NotContains(t, m.View(), "line two")
+	})
+}
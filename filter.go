@@ -0,0 +1,294 @@
+package wishlist
+
+import (
+	"net"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/log"
+	"github.com/gobwas/glob"
+	"github.com/sahilm/fuzzy"
+)
+
+// Fields free text filter terms can be matched against.
+const (
+	FilterFieldName        = "name"
+	FilterFieldAddress     = "address"
+	FilterFieldUser        = "user"
+	FilterFieldDescription = "description"
+	FilterFieldLink        = "link"
+)
+
+// DefaultFilterFields are the fields free text filter terms are matched
+// against by default, in order of preference.
+var DefaultFilterFields = []string{
+	FilterFieldName,
+	FilterFieldAddress,
+	FilterFieldUser,
+	FilterFieldDescription,
+	FilterFieldLink,
+}
+
+// validFilterFields returns the valid fields in the given list, or the default
+// fields if there are none.
+func validFilterFields(fields []string) []string {
+	var result []string
+	for _, field := range fields {
+		field = strings.ToLower(strings.TrimSpace(field))
+		if !slices.Contains(DefaultFilterFields, field) {
+			log.Warn("ignoring invalid filter field", "field", field)
+			continue
+		}
+		if !slices.Contains(result, field) {
+			result = append(result, field)
+		}
+	}
+	if len(result) == 0 {
+		return DefaultFilterFields
+	}
+	return result
+}
+
+// Fields that can be used in filter queries, e.g. `tag:prod user:root db`.
+const (
+	queryFieldTag   = "tag"
+	queryFieldUser  = "user"
+	queryFieldName  = "name"
+	queryFieldGroup = "group"
+	queryFieldHost  = "host"
+	// status of the endpoint probe: up, down or unknown.
+	queryFieldStatus = "status"
+)
+
+var queryFields = []string{
+	queryFieldTag,
+	queryFieldUser,
+	queryFieldName,
+	queryFieldGroup,
+	queryFieldHost,
+	queryFieldStatus,
+}
+
+type queryTerm struct {
+	field string
+	value string
+}
+
+// query is a parsed filter query.
+//
+// Terms in the `field:value` format must all match, values being globs.
+// All the other terms are fuzzy matched against the item's fields.
+type query struct {
+	terms []queryTerm
+	text  []string
+}
+
+func parseQuery(s string) query {
+	var q query
+	for _, word := range strings.Fields(s) {
+		field, value, ok := strings.Cut(word, ":")
+		field = strings.ToLower(field)
+		if !ok || !slices.Contains(queryFields, field) {
+			q.text = append(q.text, word)
+			continue
+		}
+		if value == "" {
+			continue
+		}
+		q.terms = append(q.terms, queryTerm{
+			field: field,
+			value: strings.ToLower(value),
+		})
+	}
+	return q
+}
+
+// matches returns true if the given item matches all the field terms of the
+// query.
+func (q query) matches(item list.Item) bool {
+	if len(q.terms) == 0 {
+		return true
+	}
+	w, ok := item.(ItemWrapper)
+	if !ok {
+		// groups have no fields to be matched against.
+		return false
+	}
+	for _, term := range q.terms {
+		if !term.matches(w) {
+			return false
+		}
+	}
+	return true
+}
+
+func (t queryTerm) matches(w ItemWrapper) bool {
+	e := w.endpoint
+	switch t.field {
+	case queryFieldTag:
+		for _, tag := range e.Tags {
+			if matchGlob(t.value, tag) {
+				return true
+			}
+		}
+		return false
+	case queryFieldUser:
+		return matchGlob(t.value, e.User)
+	case queryFieldName:
+		return matchGlob(t.value, e.Name)
+	case queryFieldGroup:
+		group := CleanGroup(e.Group)
+		return isWithin(group, CleanGroup(t.value)) || matchGlob(t.value, group)
+	case queryFieldHost:
+		host, _, err := net.SplitHostPort(e.Address)
+		if err != nil {
+			host = e.Address
+		}
+		return matchGlob(t.value, host)
+	case queryFieldStatus:
+		status := ProbeUnknown
+		if w.probe != nil {
+			status = w.probe.Status
+		}
+		return matchGlob(t.value, status.String())
+	}
+	return false
+}
+
+// matchGlob case-insensitively matches s against the given pattern, falling
+// back to a simple comparison if the pattern is not a valid glob.
+func matchGlob(pattern, s string) bool {
+	s = strings.ToLower(s)
+	g, err := glob.Compile(pattern)
+	if err != nil {
+		return pattern == s
+	}
+	return g.Match(s)
+}
+
+// textMatch is the result of matching all the free text terms of a query
+// against an item.
+type textMatch struct {
+	// sum of the positions of the matched fields, lower is better.
+	pos int
+	// sum of the fuzzy scores, higher is better.
+	score int
+	// matched indexes in the item name.
+	indexes []int
+	// fields matched by any of the terms.
+	fields []string
+}
+
+// matchText matches all the free text terms of the query against the given
+// fields of the item. Every term must match at least one field.
+func (q query) matchText(item list.Item, fields []string) (textMatch, bool) {
+	var result textMatch
+	for _, term := range q.text {
+		matched := false
+		for pos, field := range fields {
+			matches := fuzzy.Find(term, []string{fieldValue(item, field)})
+			if len(matches) == 0 {
+				continue
+			}
+			matched = true
+			result.pos += pos
+			result.score += matches[0].Score
+			if field == FilterFieldName {
+				result.indexes = mergeIndexes(result.indexes, matches[0].MatchedIndexes)
+			}
+			if !slices.Contains(result.fields, field) {
+				result.fields = append(result.fields, field)
+			}
+			break
+		}
+		if !matched {
+			return textMatch{}, false
+		}
+	}
+	return result, true
+}
+
+// fieldValue returns the value of the given field in the item.
+func fieldValue(item list.Item, field string) string {
+	w, ok := item.(ItemWrapper)
+	if !ok {
+		if field == FilterFieldName {
+			return item.FilterValue()
+		}
+		return ""
+	}
+	switch field {
+	case FilterFieldName:
+		return w.endpoint.Name
+	case FilterFieldAddress:
+		return w.endpoint.Address
+	case FilterFieldUser:
+		return w.endpoint.User
+	case FilterFieldDescription:
+		return w.endpoint.Desc
+	case FilterFieldLink:
+		return w.endpoint.Link.String()
+	}
+	return ""
+}
+
+// queryFilter returns a list.FilterFunc that filters the given items using
+// the query language, fuzzy matching free text against the given fields.
+//
+// Items are ranked by the position of the fields they matched, so matches in
+// the first fields come first, and then by their fuzzy score.
+//
+// The items must be the same the list was set with, so their indexes match
+// the filter targets.
+func queryFilter(items []list.Item, fields []string) list.FilterFunc {
+	return func(term string, targets []string) []list.Rank {
+		if len(items) != len(targets) {
+			// should never happen
+			return list.DefaultFilter(term, targets)
+		}
+
+		q := parseQuery(term)
+		type scored struct {
+			rank  list.Rank
+			match textMatch
+		}
+		var matches []scored
+		for i, item := range items {
+			if !q.matches(item) {
+				continue
+			}
+			match, ok := q.matchText(item, fields)
+			if !ok {
+				continue
+			}
+			matches = append(matches, scored{
+				rank: list.Rank{
+					Index:          i,
+					MatchedIndexes: match.indexes,
+				},
+				match: match,
+			})
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			if matches[i].match.pos != matches[j].match.pos {
+				return matches[i].match.pos < matches[j].match.pos
+			}
+			return matches[i].match.score > matches[j].match.score
+		})
+
+		ranks := make([]list.Rank, 0, len(matches))
+		for _, match := range matches {
+			ranks = append(ranks, match.rank)
+		}
+		return ranks
+	}
+}
+
+func mergeIndexes(a, b []int) []int {
+	result := append(slices.Clone(a), b...)
+	slices.Sort(result)
+	return slices.Compact(result)
+}
@@ -0,0 +1,153 @@
+package wishlist
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuery(t *testing.T) {
+	require.Equal(t, query{
+		terms: []queryTerm{
+			{field: queryFieldTag, value: "prod"},
+			{field: queryFieldUser, value: "root"},
+		},
+		text: []string{"db", "foo:bar"},
+	}, parseQuery("  tag:prod USER:Root db foo:bar tag: "))
+	require.Equal(t, query{}, parseQuery(""))
+}
+
+func TestQueryFilter(t *testing.T) {
+	items := []list.Item{
+		groupItem{name: "prod", path: "prod"},
+		ItemWrapper{endpoint: &Endpoint{
+			Name:    "db1",
+			Address: "db1.prod.local:22",
+			User:    "root",
+			Group:   "prod/eu",
+			Tags:    []string{"prod", "Database"},
+		}, probe: &ProbeResult{Status: ProbeUp}},
+		ItemWrapper{endpoint: &Endpoint{
+			Name:    "db2",
+			Address: "db2.dev.local:22",
+			User:    "app",
+			Tags:    []string{"dev", "database"},
+		}, probe: &ProbeResult{Status: ProbeDown}},
+		ItemWrapper{endpoint: &Endpoint{
+			Name:    "web",
+			Address: "web.prod.local:22",
+			User:    "root",
+			Tags:    []string{"prod"},
+		}},
+	}
+	targets := make([]string, 0, len(items))
+	for _, item := range items {
+		targets = append(targets, item.FilterValue())
+	}
+
+	filter := func(tb testing.TB, term string) []int {
+		tb.Helper()
+		var indexes []int
+		for _, rank := range queryFilter(items, DefaultFilterFields)(term, targets) {
+			indexes = append(indexes, rank.Index)
+		}
+		return indexes
+	}
+
+	for term, expected := range map[string][]int{
+		"":                         {0, 1, 2, 3},
+		"db":                       {1, 2},
+		"prod":                     {0, 1, 3},
+		"root app":                 nil,
+		"tag:prod":                 {1, 3},
+		"tag:database":             {1, 2},
+		"tag:data*":                {1, 2},
+		"tag:prod user:root db":    {1},
+		"tag:prod tag:database":    {1},
+		"user:app":                 {2},
+		"group:prod":               {1},
+		"group:prod/*":             {1},
+		"host:*.prod.local":        {1, 3},
+		"name:web":                 {3},
+		"status:up":                {1},
+		"status:down":              {2},
+		"status:unknown":           {3},
+		"tag:nope":                 nil,
+		"tag:prod user:nope":       nil,
+		"user:root nothingmatches": nil,
+	} {
+		t.Run(term, func(t *testing.T) {
+			require.ElementsMatch(t, expected, filter(t, term))
+		})
+	}
+
+	t.Run("matched indexes", func(t *testing.T) {
+		ranks := queryFilter(items, DefaultFilterFields)("tag:prod d 1", targets)
+		require.Len(t, ranks, 1)
+		require.Equal(t, []int{0, 2}, ranks[0].MatchedIndexes)
+	})
+
+	t.Run("items mismatch", func(t *testing.T) {
+		ranks := queryFilter(nil, DefaultFilterFields)("db", targets)
+		require.Len(t, ranks, 2)
+	})
+
+	t.Run("ranking", func(t *testing.T) {
+		// the name matches come first
+		require.Equal(t, []int{0, 1, 3}, filter(t, "prod"))
+	})
+
+	t.Run("custom fields", func(t *testing.T) {
+		ranks := queryFilter(items, []string{FilterFieldUser})("root", targets)
+		require.Len(t, ranks, 2)
+		require.Empty(t, ranks[0].MatchedIndexes)
+
+		require.Empty(t, queryFilter(items, []string{FilterFieldUser})("db", targets))
+	})
+}
+
+func TestMatchText(t *testing.T) {
+	item := ItemWrapper{endpoint: &Endpoint{
+		Name:    "db1",
+		Address: "10.0.0.1:22",
+		User:    "root",
+		Desc:    "the main database",
+		Link:    Link{URL: "https://example.com"},
+	}}
+
+	t.Run("multiple fields", func(t *testing.T) {
+		match, ok := parseQuery("10.0 main db1").matchText(item, DefaultFilterFields)
+		require.True(t, ok)
+		require.Equal(t, []string{FilterFieldAddress, FilterFieldDescription, FilterFieldName}, match.fields)
+		require.Equal(t, []int{0, 1, 2}, match.indexes)
+		require.Equal(t, 1+3+0, match.pos)
+	})
+
+	t.Run("link", func(t *testing.T) {
+		match, ok := parseQuery("example.com").matchText(item, DefaultFilterFields)
+		require.True(t, ok)
+		require.Equal(t, []string{FilterFieldLink}, match.fields)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		_, ok := parseQuery("db1 nope").matchText(item, DefaultFilterFields)
+		require.False(t, ok)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		match, ok := parseQuery("pr").matchText(groupItem{name: "prod"}, DefaultFilterFields)
+		require.True(t, ok)
+		require.Equal(t, []string{FilterFieldName}, match.fields)
+	})
+}
+
+func TestValidFilterFields(t *testing.T) {
+	require.Equal(t, DefaultFilterFields, validFilterFields(nil))
+	require.Equal(t, DefaultFilterFields, validFilterFields([]string{"nope"}))
+	require.Equal(
+		t,
+		[]string{FilterFieldUser, FilterFieldName},
+		validFilterFields([]string{"User", "nope", " name", "user"}),
+	)
+}
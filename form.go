@@ -0,0 +1,303 @@
+package wishlist
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// EndpointWriter persists an added or edited endpoint into the config file it
+// came from.
+// old is the endpoint being edited, or nil if e is being added.
+type EndpointWriter func(old, e *Endpoint) error
+
+// Fields of the endpoint form, in order.
+const (
+	formName = iota
+	formAddress
+	formUser
+	formProxyJump
+	formDescription
+	formLink
+	formTags
+	formFields
+)
+
+var formLabels = [formFields]string{
+	formName:        "Name",
+	formAddress:     "Address",
+	formUser:        "User",
+	formProxyJump:   "Proxy jump",
+	formDescription: "Description",
+	formLink:        "Link",
+	formTags:        "Tags",
+}
+
+// endpointForm is the form used to add or edit an endpoint.
+type endpointForm struct {
+	// endpoint being edited, nil when adding a new one.
+	editing *Endpoint
+
+	// group new endpoints are added to.
+	group string
+
+	inputs [formFields]textinput.Model
+
+	// initial values of the inputs, unchanged fields keep the original
+	// endpoint values, e.g. multi-line descriptions.
+	initial [formFields]string
+
+	focus int
+	err   error
+}
+
+func newEndpointForm(editing *Endpoint, group string) *endpointForm {
+	f := &endpointForm{
+		editing: editing,
+		group:   group,
+	}
+	if editing != nil {
+		f.initial = [formFields]string{
+			formName:        editing.Name,
+			formAddress:     editing.Address,
+			formUser:        editing.User,
+			formProxyJump:   editing.ProxyJump,
+			formDescription: editing.Desc,
+			formLink:        editing.Link.URL,
+			formTags:        strings.Join(editing.Tags, ", "),
+		}
+	}
+	for i := range f.inputs {
+		input := textinput.New()
+		input.Prompt = ""
+		input.SetValue(f.initial[i])
+		f.initial[i] = input.Value()
+		f.inputs[i] = input
+	}
+	f.inputs[formAddress].Placeholder = "host:port"
+	f.inputs[formUser].Placeholder = "defaults to the current user"
+	f.inputs[formProxyJump].Placeholder = "user@host:port"
+	f.inputs[formLink].Placeholder = "https://"
+	f.inputs[formTags].Placeholder = "comma separated"
+	return f
+}
+
+// changed returns whether the given field was changed.
+func (f *endpointForm) changed(field int) bool {
+	return f.inputs[field].Value() != f.initial[field]
+}
+
+// value returns the trimmed value of the given field.
+func (f *endpointForm) value(field int) string {
+	return strings.TrimSpace(f.inputs[field].Value())
+}
+
+// endpoint returns the endpoint described by the form.
+// The endpoint being edited is not changed.
+func (f *endpointForm) endpoint() *Endpoint {
+	var e Endpoint
+	if f.editing != nil {
+		e = *f.editing
+		e.Tags = slices.Clone(f.editing.Tags)
+	} else {
+		e.Group = f.group
+	}
+
+	if f.editing == nil || f.changed(formName) {
+		e.Name = f.value(formName)
+	}
+	if f.editing == nil || f.changed(formAddress) {
+		e.Address = f.value(formAddress)
+		if _, _, err := net.SplitHostPort(e.Address); err != nil && e.Address != "" {
+			e.Address = net.JoinHostPort(e.Address, "22")
+		}
+	}
+	if f.editing == nil || f.changed(formUser) {
+		e.User = f.value(formUser)
+	}
+	if f.editing == nil || f.changed(formProxyJump) {
+		e.ProxyJump = f.value(formProxyJump)
+	}
+	if f.editing == nil || f.changed(formDescription) {
+		e.Desc = f.value(formDescription)
+	}
+	if f.editing == nil || f.changed(formLink) {
+		e.Link.URL = f.value(formLink)
+		if e.Link.URL == "" {
+			e.Link.Name = ""
+		}
+	}
+	if f.editing == nil || f.changed(formTags) {
+		e.Tags = nil
+		for _, tag := range strings.Split(f.value(formTags), ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				e.Tags = append(e.Tags, tag)
+			}
+		}
+	}
+	return &e
+}
+
+// validate returns an error if the endpoint can't be saved.
+func (f *endpointForm) validate(e *Endpoint, endpoints []*Endpoint) error {
+	if e.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if strings.ContainsAny(e.Name, " \t/") {
+		return fmt.Errorf("name can't contain spaces or slashes")
+	}
+	if e.Address == "" {
+		return fmt.Errorf("address is required")
+	}
+	for _, other := range endpoints {
+		if other != f.editing && other.FullName() == e.FullName() {
+			return fmt.Errorf("an endpoint named %q already exists", e.FullName())
+		}
+	}
+	return nil
+}
+
+// setFocus focuses the given field.
+func (f *endpointForm) setFocus(field int) tea.Cmd {
+	f.inputs[f.focus].Blur()
+	f.focus = (field + formFields) % formFields
+	return f.inputs[f.focus].Focus()
+}
+
+// replaceEndpoint returns a copy of endpoints with old replaced by e, or e
+// appended if old is nil.
+func replaceEndpoint(endpoints []*Endpoint, old, e *Endpoint) []*Endpoint {
+	result := slices.Clone(endpoints)
+	if i := slices.Index(result, old); old != nil && i != -1 {
+		result[i] = e
+		return result
+	}
+	return append(result, e)
+}
+
+// startForm shows the form to add a new endpoint, or to edit the selected
+// one.
+func (m *ListModel) startForm(edit bool) tea.Cmd {
+	if m.writer == nil {
+		return m.list.NewStatusMessage("endpoints can't be edited")
+	}
+	var editing *Endpoint
+	if edit {
+		w := m.selected()
+		if w == nil {
+			return nil
+		}
+		editing = w.endpoint
+	}
+	m.form = newEndpointForm(editing, m.group)
+	m.view = viewForm
+	m.resizeForm()
+	return m.form.setFocus(formName)
+}
+
+func (m *ListModel) updateForm(msg tea.KeyMsg) tea.Cmd {
+	f := m.form
+	switch {
+	case key.Matches(msg, m.keys.Cancel):
+		m.view = viewList
+		m.form = nil
+		return nil
+	case key.Matches(msg, m.keys.NextField):
+		return f.setFocus(f.focus + 1)
+	case key.Matches(msg, m.keys.PrevField):
+		return f.setFocus(f.focus - 1)
+	case key.Matches(msg, m.keys.Confirm):
+		return m.saveForm()
+	}
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	return cmd
+}
+
+// saveForm writes the endpoint in the form, and updates the list.
+func (m *ListModel) saveForm() tea.Cmd {
+	f := m.form
+	e := f.endpoint()
+	if err := f.validate(e, m.endpoints); err != nil {
+		f.err = err
+		return nil
+	}
+	if err := m.writer(f.editing, e); err != nil {
+		f.err = err
+		return nil
+	}
+
+	m.view = viewList
+	m.form = nil
+	m.group = CleanGroup(e.Group)
+	cmd := m.SetItems(replaceEndpoint(m.endpoints, f.editing, e))
+	m.selectEndpoint(e)
+	return tea.Batch(cmd, m.list.NewStatusMessage(fmt.Sprintf("saved %q", e.FullName())))
+}
+
+// resizeForm sets the form inputs width to fit the window.
+func (m *ListModel) resizeForm() {
+	if m.form == nil {
+		return
+	}
+	_, right, _, left := m.styles.Doc.GetMargin()
+	width := m.width - left - right - m.formLabelWidth() - 1
+	for i := range m.form.inputs {
+		m.form.inputs[i].Width = max(width, 1)
+	}
+}
+
+func (m *ListModel) formLabelWidth() int {
+	width := 0
+	for _, label := range formLabels {
+		width = max(width, lipgloss.Width(label))
+	}
+	return width + 1
+}
+
+func (m *ListModel) formView() string {
+	f := m.form
+	title := "Add an endpoint"
+	if f.editing != nil {
+		title = fmt.Sprintf("Edit %q", f.editing.FullName())
+	} else if f.group != "" {
+		title = fmt.Sprintf("Add an endpoint to %q", f.group)
+	}
+
+	label := m.styles.Text.Width(m.formLabelWidth())
+	rows := make([]string, 0, formFields)
+	for i, input := range f.inputs {
+		l := label
+		if i == f.focus {
+			l = l.Inherit(m.styles.Header)
+		}
+		rows = append(rows, l.Render(formLabels[i]+":")+" "+input.View())
+	}
+
+	var errView string
+	if f.err != nil {
+		errView = m.styles.Err.Render(f.err.Error())
+	}
+
+	save := m.keys.Confirm
+	save.SetHelp(save.Help().Key, "save")
+	return m.styles.Doc.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.Logo.String()+"\n",
+		m.styles.Header.Render(title)+"\n",
+		strings.Join(rows, "\n")+"\n",
+		errView,
+		m.styles.Footer.Render(m.list.Help.ShortHelpView([]key.Binding{
+			m.keys.NextField,
+			m.keys.PrevField,
+			save,
+			m.keys.Cancel,
+		})),
+	))
+}
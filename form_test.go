@@ -0,0 +1,163 @@
+package wishlist
+
+import (
+	"fmt"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeWriter records the written endpoints.
+type fakeWriter struct {
+	old, e *Endpoint
+	err    error
+}
+
+func (w *fakeWriter) write(old, e *Endpoint) error {
+	w.old, w.e = old, e
+	return w.err
+}
+
+func typeKeys(m *ListModel, s string) {
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)})
+}
+
+func TestEndpointForm(t *testing.T) {
+	endpoints := func() []*Endpoint {
+		return []*Endpoint{
+			{
+				Name:    "db1",
+				Group:   "prod",
+				Address: "db1:22",
+				Desc:    "The database.\nDon't drop it.",
+				Link:    Link{Name: "Dashboard", URL: "https://db1"},
+				Tags:    []string{"db"},
+			},
+			{Name: "web1", Address: "web1:22"},
+		}
+	}
+
+	t.Run("no writer", func(t *testing.T) {
+		m := NewListing(endpoints(), fakeClient{}, testRenderer)
+		typeKeys(m, "a")
+		require.Equal(t, viewList, m.view)
+		typeKeys(m, "e")
+		require.Equal(t, viewList, m.view)
+	})
+
+	t.Run("add", func(t *testing.T) {
+		w := &fakeWriter{}
+		m := NewListing(endpoints(), fakeClient{}, testRenderer, WithEndpointWriter(w.write))
+		typeKeys(m, "a")
+		require.Equal(t, viewForm, m.view)
+		require.Contains(t, m.View(), "Add an endpoint")
+
+		typeKeys(m, "cache1")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		typeKeys(m, "cache1.local")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyShiftTab})
+		require.Equal(t, formTags, m.form.focus)
+		typeKeys(m, "cache, prod,")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+		require.Equal(t, viewList, m.view)
+		require.Nil(t, w.old)
+		require.Equal(t, &Endpoint{
+			Name:    "cache1",
+			Address: "cache1.local:22",
+			Tags:    []string{"cache", "prod"},
+		}, w.e)
+		require.Len(t, m.endpoints, 3)
+		require.Equal(t, w.e, m.selected().endpoint)
+	})
+
+	t.Run("add to group", func(t *testing.T) {
+		w := &fakeWriter{}
+		m := NewListing(endpoints(), fakeClient{}, testRenderer, WithEndpointWriter(w.write))
+		_ = m.enterGroup("prod")
+		typeKeys(m, "a")
+		require.Contains(t, m.View(), `Add an endpoint to "prod"`)
+		typeKeys(m, "db2")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyDown})
+		typeKeys(m, "db2:2222")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, &Endpoint{Name: "db2", Group: "prod", Address: "db2:2222"}, w.e)
+		require.Equal(t, "prod", m.group)
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		w := &fakeWriter{}
+		eps := endpoints()
+		m := NewListing(eps, fakeClient{}, testRenderer, WithEndpointWriter(w.write))
+		_ = m.enterGroup("prod")
+		typeKeys(m, "e")
+		require.Equal(t, viewForm, m.view)
+		require.Contains(t, m.View(), `Edit "prod/db1"`)
+
+		_ = m.form.setFocus(formUser)
+		typeKeys(m, "postgres")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+
+		require.Equal(t, viewList, m.view)
+		require.Equal(t, eps[0], w.old)
+		require.Equal(t, &Endpoint{
+			Name:    "db1",
+			Group:   "prod",
+			Address: "db1:22",
+			User:    "postgres",
+			Desc:    "The database.\nDon't drop it.",
+			Link:    Link{Name: "Dashboard", URL: "https://db1"},
+			Tags:    []string{"db"},
+		}, w.e)
+		require.Empty(t, eps[0].User, "the original endpoint should not change")
+		require.Equal(t, w.e, FindEndpoint(m.endpoints, "prod/db1"))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		w := &fakeWriter{}
+		m := NewListing(endpoints(), fakeClient{}, testRenderer, WithEndpointWriter(w.write))
+		typeKeys(m, "a")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewForm, m.view)
+		require.Contains(t, m.View(), "name is required")
+
+		typeKeys(m, "web1")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Contains(t, m.View(), "address is required")
+
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		typeKeys(m, "web1:22")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Contains(t, m.View(), `an endpoint named "web1" already exists`)
+		require.Nil(t, w.e)
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		w := &fakeWriter{err: fmt.Errorf("read-only file system")}
+		m := NewListing(endpoints(), fakeClient{}, testRenderer, WithEndpointWriter(w.write))
+		typeKeys(m, "a")
+		typeKeys(m, "foo")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyTab})
+		typeKeys(m, "foo:22")
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+		require.Equal(t, viewForm, m.view)
+		require.Contains(t, m.View(), "read-only file system")
+		require.Len(t, m.endpoints, 2)
+
+		_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEsc})
+		require.Equal(t, viewList, m.view)
+		require.Nil(t, m.form)
+	})
+}
+
+func TestReplaceEndpoint(t *testing.T) {
+	a := &Endpoint{Name: "a"}
+	b := &Endpoint{Name: "b"}
+	c := &Endpoint{Name: "c"}
+	endpoints := []*Endpoint{a, b}
+	require.Equal(t, []*Endpoint{a, c}, replaceEndpoint(endpoints, b, c))
+	require.Equal(t, []*Endpoint{a, b, c}, replaceEndpoint(endpoints, nil, c))
+	require.Equal(t, []*Endpoint{a, b}, endpoints)
+}
@@ -0,0 +1,224 @@
+package wishlist
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/wishlist/socks"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Forward is a port forwarding from a listen address to a target address.
+// Dynamic forwards have no target, as it's requested through SOCKS5 by
+// each connection.
+type Forward struct {
+	Listen string
+	Target string
+}
+
+// ParseForward parses a LocalForward or RemoteForward, in either the SSH
+// config format, `[bind_address:]port host:hostport`, or the command line
+// format, `[bind_address:]port:host:hostport`.
+func ParseForward(s string) (Forward, error) {
+	fields := strings.Fields(s)
+	var listen, target []string
+	switch len(fields) {
+	case 1:
+		parts := splitForward(fields[0])
+		if len(parts) < 3 || len(parts) > 4 { //nolint:mnd
+			return Forward{}, fmt.Errorf("invalid forward: %q", s)
+		}
+		listen, target = parts[:len(parts)-2], parts[len(parts)-2:]
+	case 2: //nolint:mnd
+		listen, target = splitForward(fields[0]), splitForward(fields[1])
+	default:
+		return Forward{}, fmt.Errorf("invalid forward: %q", s)
+	}
+	if len(listen) > 2 || len(target) != 2 { //nolint:mnd
+		return Forward{}, fmt.Errorf("invalid forward: %q", s)
+	}
+
+	l, err := forwardAddress(listen)
+	if err != nil {
+		return Forward{}, fmt.Errorf("invalid forward: %q: %w", s, err)
+	}
+	t, err := forwardAddress(target)
+	if err != nil {
+		return Forward{}, fmt.Errorf("invalid forward: %q: %w", s, err)
+	}
+	return Forward{Listen: l, Target: t}, nil
+}
+
+// ParseDynamicForward parses a DynamicForward, `[bind_address:]port`.
+func ParseDynamicForward(s string) (Forward, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 1 {
+		return Forward{}, fmt.Errorf("invalid dynamic forward: %q", s)
+	}
+	parts := splitForward(fields[0])
+	if len(parts) > 2 { //nolint:mnd
+		return Forward{}, fmt.Errorf("invalid dynamic forward: %q", s)
+	}
+	l, err := forwardAddress(parts)
+	if err != nil {
+		return Forward{}, fmt.Errorf("invalid dynamic forward: %q: %w", s, err)
+	}
+	return Forward{Listen: l}, nil
+}
+
+// splitForward splits a forward spec by colons, except the ones in IPv6
+// addresses between brackets.
+func splitForward(s string) []string {
+	var parts []string
+	var part strings.Builder
+	var bracket bool
+	for _, r := range s {
+		switch {
+		case r == '[':
+			bracket = true
+		case r == ']':
+			bracket = false
+		case r == ':' && !bracket:
+			parts = append(parts, part.String())
+			part.Reset()
+		default:
+			part.WriteRune(r)
+		}
+	}
+	return append(parts, part.String())
+}
+
+// forwardAddress returns the address of the given `[host, ]port` parts.
+// Listen addresses bind to localhost by default, and to all interfaces with
+// `*`, as in OpenSSH.
+func forwardAddress(parts []string) (string, error) {
+	host, port := "localhost", parts[len(parts)-1]
+	if len(parts) > 1 {
+		host = parts[0]
+	}
+	switch host {
+	case "*":
+		host = "0.0.0.0"
+	case "":
+		return "", fmt.Errorf("missing host")
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// validateForwards returns an error if any of the forwards is invalid.
+func validateForwards(local, remote, dynamic []string) error {
+	for _, s := range slices.Concat(local, remote) {
+		if _, err := ParseForward(s); err != nil {
+			return err
+		}
+	}
+	for _, s := range dynamic {
+		if _, err := ParseDynamicForward(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startForwards starts the local, remote and dynamic forwards of the
+// endpoint through the given client.
+// The returned closers stop them, and should be closed even on errors.
+func startForwards(client *gossh.Client, e *Endpoint) (closers, error) {
+	var cl closers
+	for _, s := range e.LocalForward {
+		f, err := ParseForward(s)
+		if err != nil {
+			return cl, err
+		}
+		l, err := net.Listen("tcp", f.Listen)
+		if err != nil {
+			return cl, fmt.Errorf("failed to listen on %q: %w", f.Listen, err)
+		}
+		cl = append(cl, l.Close)
+		log.Info("forwarding", "local", f.Listen, "remote", f.Target)
+		go serveForward(l, func(net.Conn) (net.Conn, error) {
+			return client.Dial("tcp", f.Target)
+		})
+	}
+	for _, s := range e.RemoteForward {
+		f, err := ParseForward(s)
+		if err != nil {
+			return cl, err
+		}
+		l, err := client.Listen("tcp", f.Listen)
+		if err != nil {
+			return cl, fmt.Errorf("failed to listen on remote %q: %w", f.Listen, err)
+		}
+		cl = append(cl, l.Close)
+		log.Info("forwarding", "remote", f.Listen, "local", f.Target)
+		go serveForward(l, func(net.Conn) (net.Conn, error) {
+			return net.Dial("tcp", f.Target)
+		})
+	}
+	for _, s := range e.DynamicForward {
+		f, err := ParseDynamicForward(s)
+		if err != nil {
+			return cl, err
+		}
+		l, err := net.Listen("tcp", f.Listen)
+		if err != nil {
+			return cl, fmt.Errorf("failed to listen on %q: %w", f.Listen, err)
+		}
+		cl = append(cl, l.Close)
+		log.Info("forwarding", "socks", f.Listen)
+		go serveForward(l, func(conn net.Conn) (net.Conn, error) {
+			return socks.Handshake(conn, client.Dial) //nolint:wrapcheck
+		})
+	}
+	return cl, nil
+}
+
+// serveForward accepts connections on the listener until it's closed,
+// piping each into the connection returned by dial.
+func serveForward(l net.Listener, dial func(net.Conn) (net.Conn, error)) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) && !errors.Is(err, io.EOF) {
+				log.Warn("failed to accept forwarded connection", "addr", l.Addr(), "err", err)
+			}
+			return
+		}
+		go func() {
+			target, err := dial(conn)
+			if err != nil {
+				log.Warn("failed to forward connection", "addr", l.Addr(), "err", err)
+				_ = conn.Close()
+				return
+			}
+			pipe(conn, target)
+		}()
+	}
+}
+
+// pipe copies data both ways between the connections until either of them
+// is done, and then closes both.
+func pipe(a, b io.ReadWriteCloser) {
+	done := make(chan struct{}, 2) //nolint:mnd
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	_ = a.Close()
+	_ = b.Close()
+	<-done
+}
@@ -0,0 +1,211 @@
+package wishlist
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestParseForward(t *testing.T) {
+	for spec, expected := range map[string]Forward{
+		"8080 localhost:80":           {Listen: "localhost:8080", Target: "localhost:80"},
+		"8080:localhost:80":           {Listen: "localhost:8080", Target: "localhost:80"},
+		"127.0.0.1:8080 db:5432":      {Listen: "127.0.0.1:8080", Target: "db:5432"},
+		"127.0.0.1:8080:db:5432":      {Listen: "127.0.0.1:8080", Target: "db:5432"},
+		"*:8080 db:5432":              {Listen: "0.0.0.0:8080", Target: "db:5432"},
+		"[::1]:8080:[fe80::1]:5432":   {Listen: "[::1]:8080", Target: "[fe80::1]:5432"},
+		"  8080   localhost:80      ": {Listen: "localhost:8080", Target: "localhost:80"},
+	} {
+		t.Run(spec, func(t *testing.T) {
+			f, err := ParseForward(spec)
+			require.NoError(t, err)
+			require.Equal(t, expected, f)
+		})
+	}
+
+	for _, spec := range []string{
+		"",
+		"8080",
+		"8080:localhost",
+		"8080 localhost",
+		"8080 localhost:80 extra",
+		"a:b:c:d:e",
+		"http localhost:80",
+		"8080 localhost:99999",
+		"8080 :80",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseForward(spec)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestParseDynamicForward(t *testing.T) {
+	for spec, expected := range map[string]string{
+		"1080":           "localhost:1080",
+		"127.0.0.1:1080": "127.0.0.1:1080",
+		"*:1080":         "0.0.0.0:1080",
+		"[::1]:1080":     "[::1]:1080",
+	} {
+		t.Run(spec, func(t *testing.T) {
+			f, err := ParseDynamicForward(spec)
+			require.NoError(t, err)
+			require.Equal(t, Forward{Listen: expected}, f)
+		})
+	}
+
+	for _, spec := range []string{"", "socks", "a:b:1080", "1080 localhost:80"} {
+		t.Run(spec, func(t *testing.T) {
+			_, err := ParseDynamicForward(spec)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestStartForwards(t *testing.T) {
+	echo := echoServer(t)
+	client := forwardingClient(t)
+
+	local, remote, dynamic := closedAddr(t), closedAddr(t), closedAddr(t)
+	cl, err := startForwards(client, &Endpoint{
+		LocalForward:   []string{local + ":" + echo},
+		RemoteForward:  []string{remote + " " + echo},
+		DynamicForward: []string{dynamic},
+	})
+	require.NoError(t, err)
+
+	t.Run("local", func(t *testing.T) {
+		conn, err := net.Dial("tcp", local)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		conn, err := net.Dial("tcp", remote)
+		require.NoError(t, err)
+		requireEcho(t, conn)
+	})
+
+	t.Run("dynamic", func(t *testing.T) {
+		conn, err := net.Dial("tcp", dynamic)
+		require.NoError(t, err)
+		host, port, err := net.SplitHostPort(echo)
+		require.NoError(t, err)
+		var p uint16
+		_, err = fmt.Sscan(port, &p)
+		require.NoError(t, err)
+
+		_, err = conn.Write([]byte{5, 1, 0})
+		require.NoError(t, err)
+		request := append([]byte{5, 1, 0, 1}, net.ParseIP(host).To4()...)
+		_, err = conn.Write(append(request, byte(p>>8), byte(p)))
+		require.NoError(t, err)
+		reply := make([]byte, 12)
+		_, err = io.ReadFull(conn, reply)
+		require.NoError(t, err)
+		require.Equal(t, []byte{5, 0}, reply[:2])
+		require.Equal(t, byte(0), reply[3])
+		requireEcho(t, conn)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		cl.close()
+		_, err := net.Dial("tcp", local)
+		require.Error(t, err)
+	})
+}
+
+func TestStartForwardsListenError(t *testing.T) {
+	client := forwardingClient(t)
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	cl, err := startForwards(client, &Endpoint{
+		LocalForward: []string{l.Addr().String() + ":localhost:80"},
+	})
+	t.Cleanup(cl.close)
+	require.ErrorContains(t, err, "failed to listen on")
+}
+
+// requireEcho writes a line into the connection, and expects it back.
+func requireEcho(tb testing.TB, conn net.Conn) {
+	tb.Helper()
+	defer conn.Close() //nolint:errcheck
+	_, err := fmt.Fprintln(conn, "hello")
+	require.NoError(tb, err)
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(tb, err)
+	require.Equal(tb, "hello\n", line)
+}
+
+// echoServer starts a TCP server writing back everything it reads, and
+// returns its address.
+func echoServer(tb testing.TB) string {
+	tb.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = l.Close() })
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close() //nolint:errcheck
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+// forwardingClient starts an SSH server allowing all port forwardings, and
+// returns a client connected to it.
+func forwardingClient(tb testing.TB) *gossh.Client {
+	tb.Helper()
+	client, err := gossh.Dial("tcp", forwardingServer(tb, func(ssh.Session) {}), &gossh.ClientConfig{
+		User:            "carlos",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// forwardingServer starts an SSH server allowing all port forwardings, and
+// returns its address.
+func forwardingServer(tb testing.TB, handler ssh.Handler) string {
+	tb.Helper()
+	forwards := &ssh.ForwardedTCPHandler{}
+	srv := &ssh.Server{
+		Handler: handler,
+		LocalPortForwardingCallback: func(ssh.Context, string, uint32) bool {
+			return true
+		},
+		ReversePortForwardingCallback: func(ssh.Context, string, uint32) bool {
+			return true
+		},
+		ChannelHandlers: map[string]ssh.ChannelHandler{
+			"session":      ssh.DefaultSessionHandler,
+			"direct-tcpip": ssh.DirectTCPIPHandler,
+		},
+		RequestHandlers: map[string]ssh.RequestHandler{
+			"tcpip-forward":        forwards.HandleSSHRequest,
+			"cancel-tcpip-forward": forwards.HandleSSHRequest,
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	go func() { _ = srv.Serve(l) }()
+	tb.Cleanup(func() { _ = srv.Close() })
+	return l.Addr().String()
+}
@@ -0,0 +1,103 @@
+package wishlist
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// inGroup returns the endpoints directly inside the given group.
+func inGroup(endpoints []*Endpoint, group string) []*Endpoint {
+	var result []*Endpoint
+	for _, endpoint := range endpoints {
+		if CleanGroup(endpoint.Group) == group {
+			result = append(result, endpoint)
+		}
+	}
+	return result
+}
+
+// hasGroup returns true if any valid endpoint is within the given group,
+// either directly or in any of its subgroups.
+func hasGroup(endpoints []*Endpoint, group string) bool {
+	for _, endpoint := range endpoints {
+		if !endpoint.Valid() {
+			continue
+		}
+		if isWithin(CleanGroup(endpoint.Group), group) {
+			return true
+		}
+	}
+	return false
+}
+
+// groupsToListItems returns the immediate subgroups of the given group as
+// list items.
+func groupsToListItems(endpoints []*Endpoint, group string, descLines int) []list.Item {
+	counts := map[string]int{}
+	for _, endpoint := range endpoints {
+		if !endpoint.Valid() {
+			continue
+		}
+		child := childGroup(CleanGroup(endpoint.Group), group)
+		if child == "" {
+			continue
+		}
+		counts[child]++
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	items := make([]list.Item, 0, len(names))
+	for _, name := range names {
+		items = append(items, groupItem{
+			name:      name,
+			path:      path.Join(group, name),
+			endpoints: counts[name],
+			descLines: descLines,
+		})
+	}
+	return items
+}
+
+// childGroup returns the name of the immediate child of parent that leads to
+// group, or an empty string if group is not a subgroup of parent.
+func childGroup(group, parent string) string {
+	if group == parent || !isWithin(group, parent) {
+		return ""
+	}
+	rest := group
+	if parent != "" {
+		rest = strings.TrimPrefix(group, parent+"/")
+	}
+	name, _, _ := strings.Cut(rest, "/")
+	return name
+}
+
+// isWithin returns true if group is parent or any of its subgroups.
+func isWithin(group, parent string) bool {
+	return parent == "" || group == parent || strings.HasPrefix(group, parent+"/")
+}
+
+// parentGroup returns the parent of the given group.
+func parentGroup(group string) string {
+	idx := strings.LastIndex(group, "/")
+	if idx < 0 {
+		return ""
+	}
+	return group[:idx]
+}
+
+// breadcrumb returns the list title for the given group.
+func breadcrumb(title, group string) string {
+	if group == "" {
+		return title
+	}
+	return title + " / " + strings.ReplaceAll(group, "/", " / ")
+}
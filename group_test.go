@@ -0,0 +1,80 @@
+package wishlist
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroupsToListItems(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "a", Address: "a:22", Group: "prod/eu"},
+		{Name: "b", Address: "b:22", Group: "prod/us"},
+		{Name: "c", Address: "c:22", Group: "/prod/eu/db/"},
+		{Name: "d", Address: "d:22", Group: "dev"},
+		{Name: "e", Address: "e:22"},
+		{Name: "invalid", Group: "invalid"},
+	}
+
+	t.Run("root", func(t *testing.T) {
+		require.Equal(t, []list.Item{
+			groupItem{name: "dev", path: "dev", endpoints: 1, descLines: 1},
+			groupItem{name: "prod", path: "prod", endpoints: 3, descLines: 1},
+		}, groupsToListItems(endpoints, "", 1))
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		require.Equal(t, []list.Item{
+			groupItem{name: "db", path: "prod/eu/db", endpoints: 1, descLines: 2},
+		}, groupsToListItems(endpoints, "prod/eu", 2))
+	})
+
+	t.Run("leaf", func(t *testing.T) {
+		require.Empty(t, groupsToListItems(endpoints, "prod/eu/db", 1))
+	})
+
+	t.Run("in group", func(t *testing.T) {
+		require.Equal(t, []*Endpoint{endpoints[4]}, inGroup(endpoints, ""))
+		require.Equal(t, []*Endpoint{endpoints[2]}, inGroup(endpoints, "prod/eu/db"))
+	})
+
+	t.Run("has group", func(t *testing.T) {
+		require.True(t, hasGroup(endpoints, "prod"))
+		require.True(t, hasGroup(endpoints, "prod/eu/db"))
+		require.False(t, hasGroup(endpoints, "pro"))
+		require.False(t, hasGroup(endpoints, "invalid"))
+	})
+}
+
+func TestParentGroup(t *testing.T) {
+	require.Equal(t, "", parentGroup(""))
+	require.Equal(t, "", parentGroup("prod"))
+	require.Equal(t, "prod/eu", parentGroup("prod/eu/db"))
+}
+
+func TestBreadcrumb(t *testing.T) {
+	require.Equal(t, "Directory Listing", breadcrumb("Directory Listing", ""))
+	require.Equal(t, "Directory Listing / prod / eu", breadcrumb("Directory Listing", "prod/eu"))
+}
+
+func TestGroupNavigation(t *testing.T) {
+	m := NewListing([]*Endpoint{
+		{Name: "a", Address: "a:22", Group: "prod/eu"},
+		{Name: "b", Address: "b:22"},
+	}, NewLocalSSHClient(), testRenderer)
+	require.Len(t, m.list.Items(), 2)
+	require.IsType(t, groupItem{}, m.list.SelectedItem())
+
+	m.enterGroup("prod")
+	require.Equal(t, "Directory Listing / prod", m.list.Title)
+	require.Len(t, m.list.Items(), 1)
+
+	m.enterGroup("prod/eu")
+	require.Len(t, m.list.Items(), 1)
+	require.Equal(t, "a", m.selected().endpoint.Name)
+
+	m.SetItems([]*Endpoint{{Name: "b", Address: "b:22"}})
+	require.Empty(t, m.group)
+	require.Len(t, m.list.Items(), 1)
+}
@@ -0,0 +1,476 @@
+package wishlist
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1" //nolint:gosec
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/wishlist/atomicfile"
+	"github.com/charmbracelet/wishlist/home"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// HostKeyChangedError is returned when the key presented by a host does not
+// match the one stored in the known hosts file, which might mean someone is
+// doing a man-in-the-middle attack, or simply that the host key changed.
+type HostKeyChangedError struct {
+	// Endpoint being connected to.
+	Endpoint *Endpoint
+	// Hostname whose key changed, which might be a ProxyJump host.
+	Hostname string
+	// Path of the known hosts file.
+	Path string
+	// Key presented by the host.
+	Key gossh.PublicKey
+	// Want are the keys stored in the known hosts file for the host.
+	Want []knownhosts.KnownKey
+}
+
+func (e *HostKeyChangedError) Error() string {
+	return fmt.Sprintf(
+		"the host key of %q changed, possible man-in-the-middle attack - if your host's key changed, you might need to edit %q",
+		e.Hostname,
+		e.Path,
+	)
+}
+
+// HostKeyUnknownError is returned when the host is not in any known hosts
+// file, and StrictHostKeyChecking is set to ask, but there's no way to ask
+// the user from where the connection is being made.
+type HostKeyUnknownError struct {
+	// Endpoint being connected to.
+	Endpoint *Endpoint
+	// Hostname which is unknown, which might be a ProxyJump host.
+	Hostname string
+	// Path of the known hosts file the key would be added to, might be empty.
+	Path string
+	// Key presented by the host.
+	Key gossh.PublicKey
+}
+
+func (e *HostKeyUnknownError) Error() string {
+	return fmt.Sprintf(
+		"the authenticity of host %q can't be established, its key fingerprint is %s",
+		e.Hostname,
+		gossh.FingerprintSHA256(e.Key),
+	)
+}
+
+// Host key checking modes, analogous to SSH's StrictHostKeyChecking.
+const (
+	// HostKeyCheckingYes refuses to connect to unknown hosts and to hosts
+	// whose key changed.
+	HostKeyCheckingYes = "yes"
+	// HostKeyCheckingAsk asks the user before adding unknown hosts, and
+	// refuses to connect to hosts whose key changed.
+	HostKeyCheckingAsk = "ask"
+	// HostKeyCheckingAcceptNew adds unknown hosts, and refuses to connect to
+	// hosts whose key changed.
+	HostKeyCheckingAcceptNew = "accept-new"
+	// HostKeyCheckingNo adds unknown hosts, and connects to hosts whose key
+	// changed.
+	HostKeyCheckingNo = "no"
+)
+
+// ParseHostKeyChecking parses a StrictHostKeyChecking value, returning one
+// of the HostKeyChecking* modes.
+// An empty value means HostKeyCheckingAcceptNew.
+func ParseHostKeyChecking(s string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", HostKeyCheckingAcceptNew:
+		return HostKeyCheckingAcceptNew, nil
+	case HostKeyCheckingYes, "true":
+		return HostKeyCheckingYes, nil
+	case HostKeyCheckingAsk:
+		return HostKeyCheckingAsk, nil
+	case HostKeyCheckingNo, "off", "false":
+		return HostKeyCheckingNo, nil
+	}
+	return "", fmt.Errorf("invalid StrictHostKeyChecking: %q", s)
+}
+
+// knownHosts are the known hosts files used to verify host keys.
+type knownHosts struct {
+	// user files, new host keys are added to the first one.
+	user []string
+	// global files, which are only read.
+	global []string
+}
+
+// localKnownHosts returns the known hosts files of the given endpoint in
+// local mode, defaulting to the same files as OpenSSH.
+func localKnownHosts(e *Endpoint, homeDir string) knownHosts {
+	user := e.UserKnownHostsFile
+	if len(user) == 0 {
+		user = []string{filepath.Join(homeDir, ".ssh", "known_hosts")}
+	}
+	global := e.GlobalKnownHostsFile
+	if len(global) == 0 {
+		global = []string{"/etc/ssh/ssh_known_hosts"}
+	}
+	return knownHosts{
+		user:   expandKnownHosts(user),
+		global: expandKnownHosts(global),
+	}
+}
+
+// remoteKnownHosts returns the known hosts files of the given endpoint in
+// server mode.
+// Global known hosts files are ignored, as they belong to the machine running
+// the server, not to its users.
+func remoteKnownHosts(e *Endpoint) knownHosts {
+	user := e.UserKnownHostsFile
+	if len(user) == 0 {
+		user = []string{".wishlist/known_hosts"}
+	}
+	return knownHosts{
+		user: expandKnownHosts(user),
+	}
+}
+
+// expandKnownHosts expands the given known hosts files paths, ignoring the
+// "none" value.
+func expandKnownHosts(paths []string) []string {
+	result := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if strings.EqualFold(path, "none") {
+			continue
+		}
+		expanded, err := home.ExpandPath(path)
+		if err != nil {
+			log.Warn("could not expand known_hosts path", "path", path, "err", err)
+			continue
+		}
+		result = append(result, expanded)
+	}
+	return result
+}
+
+// existing returns the known hosts files which exist.
+func (kh knownHosts) existing() []string {
+	var result []string
+	for _, path := range append(kh.user, kh.global...) {
+		if _, err := os.Stat(path); err == nil {
+			result = append(result, path)
+		}
+	}
+	return result
+}
+
+// writable returns the file new host keys are added to, might be empty.
+func (kh knownHosts) writable() string {
+	if len(kh.user) == 0 {
+		return ""
+	}
+	return kh.user[0]
+}
+
+// hostKeyAsker asks the user whether the given unknown host key should be
+// trusted.
+type hostKeyAsker func(hostname string, key gossh.PublicKey) (bool, error)
+
+// askHostKey returns a hostKeyAsker that asks the user in the terminal, the
+// same way OpenSSH does.
+func askHostKey(in io.Reader, out io.Writer) hostKeyAsker {
+	return func(hostname string, key gossh.PublicKey) (bool, error) {
+		fingerprint := gossh.FingerprintSHA256(key)
+		fmt.Fprintf(out, "The authenticity of host %q can't be established.\n\r", hostname)      //nolint: errcheck
+		fmt.Fprintf(out, "%s key fingerprint is %s.\n\r", key.Type(), fingerprint)               //nolint: errcheck
+		fmt.Fprint(out, "Are you sure you want to continue connecting (yes/no/[fingerprint])? ") //nolint: errcheck
+		for {
+			answer, err := askUser(in, true)
+			if err != nil {
+				return false, err
+			}
+			fmt.Fprint(out, "\n\r") //nolint: errcheck
+			switch strings.ToLower(answer) {
+			case "yes", strings.ToLower(fingerprint):
+				return true, nil
+			case "no":
+				return false, nil
+			}
+			fmt.Fprint(out, "Please type 'yes', 'no' or the fingerprint: ") //nolint: errcheck
+		}
+	}
+}
+
+// hostKeyTruster is implemented by clients that can connect to an endpoint
+// trusting the given host key once, regardless of the known hosts file.
+type hostKeyTruster interface {
+	forTrustedKey(e *Endpoint, key gossh.PublicKey) tea.ExecCommand
+}
+
+// appendKnownHost adds the given host key to the known hosts file, creating
+// it if needed.
+func appendKnownHost(path, hostname string, key gossh.PublicKey) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to create known_hosts directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to open known_hosts: %w", err)
+	}
+	defer func() { _ = f.Close() }()
+	if _, err := fmt.Fprintln(f, knownhosts.Line([]string{knownhosts.Normalize(hostname)}, key)); err != nil {
+		return fmt.Errorf("failed to write known_hosts: %w", err)
+	}
+	log.Info("added host key", "host", hostname, "path", path, "fingerprint", gossh.FingerprintSHA256(key))
+	return nil
+}
+
+// replaceKnownHost replaces the given stored keys of the host with the new
+// key in the known hosts file.
+//
+// Only the host is removed from the lines of the stored keys, and lines are
+// only removed once they have no hosts left, so the keys of other hosts in the
+// same lines are kept.
+// Lines with wildcards or markers, e.g. `@cert-authority`, are not changed,
+// as they might apply to other hosts, so they must be edited by hand.
+//
+// The file is written atomically, so it's never left half-written.
+func replaceKnownHost(path string, want []knownhosts.KnownKey, hostname string, key gossh.PublicKey) error {
+	stored := map[int]bool{}
+	for _, k := range want {
+		if filepath.Clean(k.Filename) == filepath.Clean(path) {
+			stored[k.Line] = true
+		}
+	}
+
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+
+	host := knownhosts.Normalize(hostname)
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(bytes.NewReader(bts))
+	scanner.Buffer(nil, len(bts)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		if stored[n] {
+			var err error
+			line, err = removeKnownHost(line, host)
+			if err != nil {
+				return fmt.Errorf("could not replace the key of %q in %s:%d: %w, edit it by hand", hostname, path, n, err)
+			}
+			log.Info("removing known host", "path", path, "line", n, "host", host)
+			if line == "" {
+				continue
+			}
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read known_hosts: %w", err)
+	}
+	out.WriteString(knownhosts.Line([]string{host}, key) + "\n")
+
+	if err := atomicfile.Write(path, out.Bytes(), 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to replace known_hosts: %w", err)
+	}
+	return nil
+}
+
+// removeKnownHost removes the normalized host from the hosts of the
+// known_hosts line, returning an empty line if it has no hosts left.
+func removeKnownHost(line, host string) (string, error) {
+	trimmed := strings.TrimLeft(line, " \t")
+	if strings.HasPrefix(trimmed, "@") {
+		return "", errors.New("the entry has a marker")
+	}
+	end := strings.IndexAny(trimmed, " \t")
+	if end < 0 {
+		return "", errors.New("invalid entry")
+	}
+	var kept []string
+	for _, pattern := range strings.Split(trimmed[:end], ",") {
+		if strings.ContainsAny(pattern, "*?!") {
+			return "", errors.New("the entry has wildcards")
+		}
+		if !knownHostMatches(pattern, host) {
+			kept = append(kept, pattern)
+		}
+	}
+	if len(kept) == 0 {
+		return "", nil
+	}
+	return strings.Join(kept, ",") + trimmed[end:], nil
+}
+
+// knownHostMatches returns whether the known_hosts pattern, which might be
+// hashed, is the normalized host.
+func knownHostMatches(pattern, host string) bool {
+	hashed, ok := strings.CutPrefix(pattern, "|1|")
+	if !ok {
+		return strings.EqualFold(knownhosts.Normalize(pattern), host)
+	}
+	salt64, hash64, ok := strings.Cut(hashed, "|")
+	if !ok {
+		return false
+	}
+	salt, err := base64.StdEncoding.DecodeString(salt64)
+	if err != nil {
+		return false
+	}
+	hash, err := base64.StdEncoding.DecodeString(hash64)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+	return hmac.Equal(mac.Sum(nil), hash)
+}
+
+// hostKeyPrompt is a host key which needs the user attention.
+type hostKeyPrompt struct {
+	endpoint *Endpoint
+	hostname string
+	path     string
+	key      gossh.PublicKey
+	// want are the stored keys, empty if the host is unknown.
+	want []knownhosts.KnownKey
+}
+
+// handleHostKeyError shows the host key view if the error is a
+// HostKeyChangedError or a HostKeyUnknownError.
+func (m *ListModel) handleHostKeyError(err error) bool {
+	var cerr *HostKeyChangedError
+	var uerr *HostKeyUnknownError
+	switch {
+	case errors.As(err, &cerr):
+		m.hostKey = &hostKeyPrompt{
+			endpoint: cerr.Endpoint,
+			hostname: cerr.Hostname,
+			path:     cerr.Path,
+			key:      cerr.Key,
+			want:     cerr.Want,
+		}
+	case errors.As(err, &uerr):
+		m.hostKey = &hostKeyPrompt{
+			endpoint: uerr.Endpoint,
+			hostname: uerr.Hostname,
+			path:     uerr.Path,
+			key:      uerr.Key,
+		}
+	default:
+		return false
+	}
+	m.view = viewHostKey
+	return true
+}
+
+// hostKeyBindings returns the key bindings available for the current host
+// key.
+func (m *ListModel) hostKeyBindings() []key.Binding {
+	keys := []key.Binding{m.keys.Cancel}
+	if _, ok := m.client.(hostKeyTruster); ok {
+		keys = append(keys, m.keys.AcceptOnce)
+	}
+	if m.hostKey.path == "" {
+		return keys
+	}
+	if len(m.hostKey.want) == 0 {
+		return append(keys, m.keys.TrustKey)
+	}
+	if m.hostKeyReplace {
+		return append(keys, m.keys.ReplaceKey)
+	}
+	return keys
+}
+
+func (m *ListModel) updateHostKey(msg tea.KeyMsg) tea.Cmd {
+	hk := m.hostKey
+	if !key.Matches(msg, m.hostKeyBindings()...) {
+		return nil
+	}
+
+	m.view = viewList
+	m.hostKey = nil
+	connect := func(cmd tea.ExecCommand) tea.Cmd {
+		return tea.Exec(cmd, func(err error) tea.Msg {
+			return errMsg{err}
+		})
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.AcceptOnce):
+		return connect(m.client.(hostKeyTruster).forTrustedKey(hk.endpoint, hk.key))
+	case key.Matches(msg, m.keys.TrustKey):
+		if err := appendKnownHost(hk.path, hk.hostname, hk.key); err != nil {
+			m.err = err
+			return nil
+		}
+		return connect(m.client.For(hk.endpoint))
+	case key.Matches(msg, m.keys.ReplaceKey):
+		if err := replaceKnownHost(hk.path, hk.want, hk.hostname, hk.key); err != nil {
+			m.err = err
+			return nil
+		}
+		log.Info("replaced host key", "host", hk.hostname, "path", hk.path)
+		return connect(m.client.For(hk.endpoint))
+	}
+	return nil
+}
+
+func (m *ListModel) hostKeyView() string {
+	hk := m.hostKey
+	width := m.width - m.styles.Doc.GetHorizontalFrameSize()
+
+	fingerprint := fmt.Sprintf("%s %s", hk.key.Type(), gossh.FingerprintSHA256(hk.key))
+	var parts []string
+	if len(hk.want) == 0 {
+		parts = []string{
+			m.styles.Header.Width(width).Render(
+				fmt.Sprintf("The authenticity of host %q (%s) can't be established.", hk.hostname, hk.endpoint.Name),
+			) + "\n",
+			m.styles.Header.Render("Received:"),
+			fingerprint + "\n",
+		}
+	} else {
+		stored := make([]string, 0, len(hk.want))
+		for _, k := range hk.want {
+			stored = append(stored, fmt.Sprintf(
+				"%s %s (%s:%d)",
+				k.Key.Type(),
+				gossh.FingerprintSHA256(k.Key),
+				k.Filename,
+				k.Line,
+			))
+		}
+		parts = []string{
+			m.styles.Err.UnsetItalic().Bold(true).Width(width).Render(
+				fmt.Sprintf("The host key of %q (%s) has changed!", hk.hostname, hk.endpoint.Name),
+			) + "\n",
+			m.styles.Text.Width(width).Render(
+				"Someone could be eavesdropping on you right now (man-in-the-middle attack), "+
+					"or the host key might have just been changed.",
+			) + "\n",
+			m.styles.Header.Render("Stored:"),
+			strings.Join(stored, "\n") + "\n",
+			m.styles.Header.Render("Received:"),
+			fingerprint + "\n",
+		}
+	}
+
+	return m.styles.Doc.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		append(
+			append([]string{m.styles.Logo.String() + "\n"}, parts...),
+			m.styles.Footer.Render(m.list.Help.ShortHelpView(m.hostKeyBindings())),
+		)...,
+	))
+}
@@ -0,0 +1,424 @@
+package wishlist
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func newTestHostKey(tb testing.TB) gossh.PublicKey {
+	tb.Helper()
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(tb, err)
+	key, err := gossh.NewPublicKey(pub)
+	require.NoError(tb, err)
+	return key
+}
+
+func TestParseHostKeyChecking(t *testing.T) {
+	for in, expected := range map[string]string{
+		"":           HostKeyCheckingAcceptNew,
+		"accept-new": HostKeyCheckingAcceptNew,
+		"yes":        HostKeyCheckingYes,
+		"true":       HostKeyCheckingYes,
+		"Ask":        HostKeyCheckingAsk,
+		"no":         HostKeyCheckingNo,
+		"off":        HostKeyCheckingNo,
+	} {
+		t.Run(in, func(t *testing.T) {
+			mode, err := ParseHostKeyChecking(in)
+			require.NoError(t, err)
+			require.Equal(t, expected, mode)
+		})
+	}
+
+	_, err := ParseHostKeyChecking("maybe")
+	require.EqualError(t, err, `invalid StrictHostKeyChecking: "maybe"`)
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	oldKey := newTestHostKey(t)
+	newKey := newTestHostKey(t)
+	otherKey := newTestHostKey(t)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(
+		"# a comment\n"+
+			knownhosts.Line([]string{"other:22"}, otherKey)+"\n"+
+			knownhosts.Line([]string{"db1:22"}, oldKey)+"\n",
+	), 0o600))
+	kh := knownHosts{user: []string{path}}
+
+	e := &Endpoint{Name: "db1", Address: "db1:22"}
+	remote := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+
+	t.Run("changed", func(t *testing.T) {
+		err := hostKeyCallback(e, kh, nil, nil)("db1:22", remote, newKey)
+		var kerr *HostKeyChangedError
+		require.ErrorAs(t, err, &kerr)
+		require.Equal(t, e, kerr.Endpoint)
+		require.Equal(t, "db1:22", kerr.Hostname)
+		require.Equal(t, path, kerr.Path)
+		require.Equal(t, newKey, kerr.Key)
+		require.Len(t, kerr.Want, 1)
+		require.Equal(t, 3, kerr.Want[0].Line)
+		require.Contains(t, rootCause(err).Error(), "possible man-in-the-middle attack")
+	})
+
+	t.Run("changed no checking", func(t *testing.T) {
+		e := &Endpoint{Name: "db1", Address: "db1:22", StrictHostKeyChecking: "no"}
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("db1:22", remote, newKey))
+	})
+
+	t.Run("trusted once", func(t *testing.T) {
+		require.NoError(t, hostKeyCallback(e, kh, newKey, nil)("db1:22", remote, newKey))
+		require.Error(t, hostKeyCallback(e, kh, otherKey, nil)("db1:22", remote, newKey))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		err := hostKeyCallback(e, kh, nil, nil)("db1:22", remote, newKey)
+		var kerr *HostKeyChangedError
+		require.ErrorAs(t, err, &kerr)
+		require.NoError(t, replaceKnownHost(kerr.Path, kerr.Want, kerr.Hostname, kerr.Key))
+
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("db1:22", remote, newKey))
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("other:22", remote, otherKey))
+
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "# a comment\n"+
+			knownhosts.Line([]string{"other:22"}, otherKey)+"\n"+
+			knownhosts.Line([]string{"db1:22"}, newKey)+"\n", string(bts))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	})
+}
+
+func TestReplaceKnownHost(t *testing.T) {
+	oldKey := newTestHostKey(t)
+	newKey := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+	e := &Endpoint{Name: "db1", Address: "db1:22"}
+
+	// replace writes the known hosts, and replaces the key of db1.
+	replace := func(t *testing.T, lines ...string) (string, error) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+		err := hostKeyCallback(e, knownHosts{user: []string{path}}, nil, nil)("db1:22", remote, newKey)
+		var kerr *HostKeyChangedError
+		require.ErrorAs(t, err, &kerr)
+		if err := replaceKnownHost(kerr.Path, kerr.Want, kerr.Hostname, kerr.Key); err != nil {
+			return "", err
+		}
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		return string(bts), nil
+	}
+	oldLine := func(hosts ...string) string {
+		return knownhosts.Line(hosts, oldKey)
+	}
+	newLine := knownhosts.Line([]string{"db1"}, newKey) + "\n"
+
+	t.Run("multiple hosts", func(t *testing.T) {
+		out, err := replace(t, oldLine("app1", "db1", "10.0.0.5"))
+		require.NoError(t, err)
+		require.Equal(t, oldLine("app1", "10.0.0.5")+"\n"+newLine, out)
+	})
+
+	t.Run("hashed", func(t *testing.T) {
+		// hashed entries have a single host.
+		hashed := strings.Replace(oldLine("placeholder"), "placeholder", knownhosts.HashHostname("db1"), 1)
+		out, err := replace(t, oldLine("app1"), hashed)
+		require.NoError(t, err)
+		require.Equal(t, oldLine("app1")+"\n"+newLine, out)
+	})
+
+	t.Run("wildcard", func(t *testing.T) {
+		_, err := replace(t, oldLine("app1", "db*"))
+		require.ErrorContains(t, err, "the entry has wildcards, edit it by hand")
+	})
+
+	t.Run("marker", func(t *testing.T) {
+		_, err := replace(t, "@cert-authority "+oldLine("db1"))
+		require.ErrorContains(t, err, "the entry has a marker, edit it by hand")
+	})
+}
+
+func TestHostKeyCallbackUnknown(t *testing.T) {
+	key := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 22}
+	endpoint := func(mode string) *Endpoint {
+		return &Endpoint{Name: "db1", Address: "db1:22", StrictHostKeyChecking: mode}
+	}
+	newKnownHosts := func(tb testing.TB) knownHosts {
+		tb.Helper()
+		return knownHosts{user: []string{filepath.Join(tb.TempDir(), "ssh", "known_hosts")}}
+	}
+	requireKnown := func(tb testing.TB, kh knownHosts) {
+		tb.Helper()
+		require.NoError(tb, hostKeyCallback(endpoint("yes"), kh, nil, nil)("db1:22", remote, key))
+	}
+
+	for _, mode := range []string{"", "accept-new", "no"} {
+		t.Run("add "+mode, func(t *testing.T) {
+			kh := newKnownHosts(t)
+			require.NoError(t, hostKeyCallback(endpoint(mode), kh, nil, nil)("db1:22", remote, key))
+			requireKnown(t, kh)
+		})
+	}
+
+	t.Run("yes", func(t *testing.T) {
+		kh := newKnownHosts(t)
+		err := hostKeyCallback(endpoint("yes"), kh, nil, nil)("db1:22", remote, key)
+		require.EqualError(t, err, `no host key is known for "db1:22" and strict host key checking is enabled`)
+		require.NoFileExists(t, kh.user[0])
+	})
+
+	t.Run("ask without asker", func(t *testing.T) {
+		kh := newKnownHosts(t)
+		err := hostKeyCallback(endpoint("ask"), kh, nil, nil)("db1:22", remote, key)
+		var uerr *HostKeyUnknownError
+		require.ErrorAs(t, err, &uerr)
+		require.Equal(t, kh.user[0], uerr.Path)
+		require.Equal(t, "db1:22", uerr.Hostname)
+		require.NoFileExists(t, kh.user[0])
+	})
+
+	t.Run("ask accepted", func(t *testing.T) {
+		kh := newKnownHosts(t)
+		var out bytes.Buffer
+		ask := askHostKey(strings.NewReader("maybe\nyes\n"), &out)
+		require.NoError(t, hostKeyCallback(endpoint("ask"), kh, nil, ask)("db1:22", remote, key))
+		require.Contains(t, out.String(), gossh.FingerprintSHA256(key))
+		require.Contains(t, out.String(), "Please type 'yes', 'no' or the fingerprint")
+		requireKnown(t, kh)
+	})
+
+	t.Run("ask fingerprint", func(t *testing.T) {
+		kh := newKnownHosts(t)
+		ask := askHostKey(strings.NewReader(gossh.FingerprintSHA256(key)+"\n"), io.Discard)
+		require.NoError(t, hostKeyCallback(endpoint("ask"), kh, nil, ask)("db1:22", remote, key))
+		requireKnown(t, kh)
+	})
+
+	t.Run("ask refused", func(t *testing.T) {
+		kh := newKnownHosts(t)
+		ask := askHostKey(strings.NewReader("no\n"), io.Discard)
+		err := hostKeyCallback(endpoint("ask"), kh, nil, ask)("db1:22", remote, key)
+		require.EqualError(t, err, `host key verification failed for "db1:22"`)
+		require.NoFileExists(t, kh.user[0])
+	})
+
+	t.Run("no user file", func(t *testing.T) {
+		require.NoError(t, hostKeyCallback(endpoint(""), knownHosts{}, nil, nil)("db1:22", remote, key))
+	})
+}
+
+func TestHostKeyCallbackKnownHosts(t *testing.T) {
+	key := newTestHostKey(t)
+	remote := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 2222}
+	e := &Endpoint{Name: "db1", Address: "db1:2222", StrictHostKeyChecking: "yes"}
+	dir := t.TempDir()
+	write := func(tb testing.TB, name string, lines ...string) string {
+		tb.Helper()
+		path := filepath.Join(dir, name)
+		require.NoError(tb, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600))
+		return path
+	}
+
+	t.Run("hashed", func(t *testing.T) {
+		path := write(t, "hashed", knownhosts.Line([]string{knownhosts.HashHostname(knownhosts.Normalize("db1:2222"))}, key))
+		kh := knownHosts{user: []string{path}}
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("db1:2222", remote, key))
+		require.Error(t, hostKeyCallback(e, kh, nil, nil)("db2:2222", remote, key))
+	})
+
+	t.Run("global", func(t *testing.T) {
+		global := write(t, "global", knownhosts.Line([]string{"db1:2222"}, key))
+		kh := knownHosts{
+			user:   []string{filepath.Join(dir, "missing")},
+			global: []string{global},
+		}
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("db1:2222", remote, key))
+	})
+
+	t.Run("revoked", func(t *testing.T) {
+		path := write(t, "revoked",
+			knownhosts.Line([]string{"db1:2222"}, key),
+			"@revoked * "+strings.TrimSpace(string(gossh.MarshalAuthorizedKey(key))),
+		)
+		e := &Endpoint{Name: "db1", Address: "db1:2222", StrictHostKeyChecking: "no"}
+		err := hostKeyCallback(e, knownHosts{user: []string{path}}, nil, nil)("db1:2222", remote, key)
+		var rerr *knownhosts.RevokedError
+		require.ErrorAs(t, err, &rerr)
+	})
+
+	t.Run("cert authority", func(t *testing.T) {
+		_, caKey, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		ca, err := gossh.NewSignerFromKey(caKey)
+		require.NoError(t, err)
+		cert := &gossh.Certificate{
+			Key:             key,
+			CertType:        gossh.HostCert,
+			ValidPrincipals: []string{"db1"},
+			ValidBefore:     gossh.CertTimeInfinity,
+		}
+		require.NoError(t, cert.SignCert(rand.Reader, ca))
+
+		path := write(t, "ca", "@cert-authority [db*]:2222 "+strings.TrimSpace(string(gossh.MarshalAuthorizedKey(ca.PublicKey()))))
+		kh := knownHosts{user: []string{path}}
+		require.NoError(t, hostKeyCallback(e, kh, nil, nil)("db1:2222", remote, cert))
+		require.Error(t, hostKeyCallback(e, kh, nil, nil)("web1:2222", remote, cert))
+	})
+}
+
+func TestKnownHosts(t *testing.T) {
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+
+	t.Run("local defaults", func(t *testing.T) {
+		kh := localKnownHosts(&Endpoint{}, "/home/foo")
+		require.Equal(t, []string{"/home/foo/.ssh/known_hosts"}, kh.user)
+		require.Equal(t, []string{"/etc/ssh/ssh_known_hosts"}, kh.global)
+		require.Equal(t, "/home/foo/.ssh/known_hosts", kh.writable())
+	})
+
+	t.Run("local", func(t *testing.T) {
+		kh := localKnownHosts(&Endpoint{
+			UserKnownHostsFile:   []string{"~/.ssh/a", "/tmp/b"},
+			GlobalKnownHostsFile: []string{"none"},
+		}, "/home/foo")
+		require.Equal(t, []string{filepath.Join(home, ".ssh/a"), "/tmp/b"}, kh.user)
+		require.Empty(t, kh.global)
+	})
+
+	t.Run("remote", func(t *testing.T) {
+		kh := remoteKnownHosts(&Endpoint{GlobalKnownHostsFile: []string{"/etc/ssh/ssh_known_hosts"}})
+		require.Equal(t, []string{".wishlist/known_hosts"}, kh.user)
+		require.Empty(t, kh.global)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		kh := remoteKnownHosts(&Endpoint{UserKnownHostsFile: []string{"none"}})
+		require.Empty(t, kh.user)
+		require.Empty(t, kh.writable())
+	})
+}
+
+type fakeTrustingClient struct {
+	fakeClient
+	trusted gossh.PublicKey
+}
+
+func (c *fakeTrustingClient) forTrustedKey(_ *Endpoint, key gossh.PublicKey) tea.ExecCommand {
+	c.trusted = key
+	return nil
+}
+
+func TestHostKeyChangedView(t *testing.T) {
+	e := &Endpoint{Name: "db1", Address: "db1:22"}
+	oldKey := newTestHostKey(t)
+	newKey := newTestHostKey(t)
+	path := filepath.Join(t.TempDir(), "known_hosts")
+	require.NoError(t, os.WriteFile(path, []byte(knownhosts.Line([]string{"db1:22"}, oldKey)+"\n"), 0o600))
+	kerr := &HostKeyChangedError{
+		Endpoint: e,
+		Hostname: "db1:22",
+		Path:     path,
+		Key:      newKey,
+		Want: []knownhosts.KnownKey{
+			{Key: oldKey, Filename: path, Line: 1},
+		},
+	}
+	key := func(s string) tea.KeyMsg {
+		if s == "esc" {
+			return tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		m := NewListing([]*Endpoint{e}, fakeClient{}, testRenderer)
+		_, _ = m.Update(errMsg{err: kerr})
+		require.Equal(t, viewHostKey, m.view)
+		require.Nil(t, m.err)
+		view := m.View()
+		require.Contains(t, view, gossh.FingerprintSHA256(oldKey))
+		require.Contains(t, view, gossh.FingerprintSHA256(newKey))
+		require.NotContains(t, view, "accept once")
+
+		_, _ = m.Update(key("esc"))
+		require.Equal(t, viewList, m.view)
+		require.Nil(t, m.hostKey)
+	})
+
+	t.Run("accept once", func(t *testing.T) {
+		client := &fakeTrustingClient{}
+		m := NewListing([]*Endpoint{e}, client, testRenderer)
+		_, _ = m.Update(errMsg{err: kerr})
+		require.Contains(t, m.View(), "accept once")
+		_, cmd := m.Update(key("a"))
+		require.NotNil(t, cmd)
+		require.Equal(t, newKey, client.trusted)
+		require.Equal(t, viewList, m.view)
+	})
+
+	t.Run("replace disabled", func(t *testing.T) {
+		m := NewListing([]*Endpoint{e}, fakeClient{}, testRenderer, WithHostKeyReplace(false))
+		_, _ = m.Update(errMsg{err: kerr})
+		require.NotContains(t, m.View(), "replace")
+		_, cmd := m.Update(key("R"))
+		require.Nil(t, cmd)
+		require.Equal(t, viewHostKey, m.view)
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		m := NewListing([]*Endpoint{e}, fakeClient{}, testRenderer)
+		_, _ = m.Update(errMsg{err: kerr})
+		_, cmd := m.Update(key("R"))
+		require.NotNil(t, cmd)
+		require.Equal(t, viewList, m.view)
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, knownhosts.Line([]string{"db1:22"}, newKey)+"\n", string(bts))
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		m := NewListing([]*Endpoint{e}, fakeClient{}, testRenderer)
+		_, _ = m.Update(errMsg{err: &HostKeyUnknownError{
+			Endpoint: e,
+			Hostname: "db1:22",
+			Path:     path,
+			Key:      newKey,
+		}})
+		require.Equal(t, viewHostKey, m.view)
+		view := m.View()
+		require.Contains(t, view, "can't be established")
+		require.Contains(t, view, "trust and save")
+		require.NotContains(t, view, "replace")
+
+		_, cmd := m.Update(key("R"))
+		require.Nil(t, cmd)
+		_, cmd = m.Update(key("t"))
+		require.NotNil(t, cmd)
+		require.Equal(t, viewList, m.view)
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, knownhosts.Line([]string{"db1:22"}, newKey)+"\n", string(bts))
+	})
+}
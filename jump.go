@@ -3,36 +3,158 @@ package wishlist
 import (
 	"fmt"
 	"net"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/log"
 	gossh "golang.org/x/crypto/ssh"
 )
 
-func proxyJump(addr, nextAddr string, conf, nextConf *gossh.ClientConfig) (*gossh.Client, closers, error) {
+// jumpHop is a host to jump through to reach an endpoint, along with the
+// config to authenticate to it.
+type jumpHop struct {
+	endpoint *Endpoint
+	config   *gossh.ClientConfig
+}
+
+// jumpConfigFunc returns the config to connect to the given hop, with its own
+// authentication methods, host key checking and timeout.
+// The returned closers should be closed once done with the connection, even
+// on errors.
+type jumpConfigFunc func(hop *Endpoint) (*gossh.ClientConfig, closers, error)
+
+// jumpHops resolves the ProxyJump of the endpoint into hops, configured by the
+// given function.
+// The returned closers should be closed even on errors.
+func jumpHops(e *Endpoint, endpoints []*Endpoint, config jumpConfigFunc) ([]jumpHop, closers, error) {
+	var cl closers
+	chain, err := resolveJumps(e, endpoints)
+	if err != nil {
+		return nil, cl, err
+	}
+	hops := make([]jumpHop, 0, len(chain))
+	for _, hop := range chain {
+		conf, hcl, err := config(hop)
+		cl = append(cl, hcl...)
+		if err != nil {
+			return nil, cl, fmt.Errorf("ProxyJump %q: %w", hop.Name, err)
+		}
+		hops = append(hops, jumpHop{endpoint: hop, config: conf})
+	}
+	return hops, cl, nil
+}
+
+// resolveJumps returns the hosts to jump through to reach the endpoint, in
+// order.
+//
+// The ProxyJump is a comma-separated list of hops, each either
+// `[user@]host[:port]` or the name of another endpoint, whose user, address,
+// identity files and so on are used.
+// Hops which are not endpoints use the settings of the endpoint whose
+// ProxyJump they're in, e.g. its user, identity files and known hosts.
+// As in OpenSSH, the ProxyJump of the first hop is used to reach it, while
+// the following ones are reached through the previous hop.
+func resolveJumps(e *Endpoint, endpoints []*Endpoint) ([]*Endpoint, error) {
+	return resolveJumpsVisiting(e, endpoints, []string{e.FullName()})
+}
+
+func resolveJumpsVisiting(e *Endpoint, endpoints []*Endpoint, visiting []string) ([]*Endpoint, error) {
+	specs := splitJumps(e.ProxyJump)
+	hops := make([]*Endpoint, 0, len(specs))
+	for i, spec := range specs {
+		hop := FindEndpoint(endpoints, spec)
+		if hop == nil {
+			user, addr := splitJump(spec)
+			hops = append(hops, &Endpoint{
+				Name:                     spec,
+				Address:                  addr,
+				User:                     FirstNonEmpty(user, e.User),
+				IdentityFiles:            e.IdentityFiles,
+				PreferredAuthentications: e.PreferredAuthentications,
+				Timeout:                  e.Timeout,
+				StrictHostKeyChecking:    e.StrictHostKeyChecking,
+				UserKnownHostsFile:       e.UserKnownHostsFile,
+				GlobalKnownHostsFile:     e.GlobalKnownHostsFile,
+			})
+			continue
+		}
+		if slices.Contains(visiting, hop.FullName()) {
+			return nil, fmt.Errorf("ProxyJump cycle: %s", strings.Join(slices.Concat(visiting, []string{hop.FullName()}), " → "))
+		}
+		if i == 0 {
+			chain, err := resolveJumpsVisiting(hop, endpoints, slices.Concat(visiting, []string{hop.FullName()}))
+			if err != nil {
+				return nil, err
+			}
+			hops = append(hops, chain...)
+		}
+		hops = append(hops, hop)
+	}
+	return hops, nil
+}
+
+// splitJumps splits a comma-separated ProxyJump into its hops.
+// `none` disables jumping, as in OpenSSH.
+func splitJumps(jump string) []string {
+	if strings.EqualFold(strings.TrimSpace(jump), "none") {
+		return nil
+	}
+	var specs []string
+	for _, spec := range strings.Split(jump, ",") {
+		if spec = strings.TrimPrefix(strings.TrimSpace(spec), "ssh://"); spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	return specs
+}
+
+// dialJumps connects to the address through the given hops.
+func dialJumps(hops []jumpHop, addr string, conf *gossh.ClientConfig) (*gossh.Client, closers, error) {
 	var cl closers
-	log.Info("connecting client to ProxyJump", "addr", addr)
-	jumpClient, err := gossh.Dial("tcp", addr, conf)
+	first := hops[0]
+	log.Info("connecting client to ProxyJump", "name", first.endpoint.Name, "addr", first.endpoint.Address)
+	client, err := dial(first.endpoint, first.config)
 	if err != nil {
-		return nil, cl, fmt.Errorf("connection to ProxyJump (%s) failed: %w", addr, err)
+		return nil, cl, fmt.Errorf("connection to ProxyJump %q (%s) failed: %w", first.endpoint.Name, first.endpoint.Address, err)
+	}
+	cl = append(cl, client.Close)
+
+	for i, hop := range hops[1:] {
+		prev := hops[i]
+		client, err = proxyJump(client, hop.endpoint.Address, hop.config)
+		if client != nil {
+			cl = append(cl, client.Close)
+		}
+		if err != nil {
+			return nil, cl, fmt.Errorf("connection from ProxyJump %q to ProxyJump %q failed: %w", prev.endpoint.Name, hop.endpoint.Name, err)
+		}
 	}
-	cl = append(cl, jumpClient.Close)
 
+	last := hops[len(hops)-1]
+	target, err := proxyJump(client, addr, conf)
+	if target != nil {
+		cl = append(cl, target.Close)
+	}
+	if err != nil {
+		return nil, cl, fmt.Errorf("connection from ProxyJump %q to Host (%s) failed: %w", last.endpoint.Name, addr, err)
+	}
+	return target, cl, nil
+}
+
+// proxyJump connects to the next address through the given client.
+func proxyJump(client *gossh.Client, nextAddr string, nextConf *gossh.ClientConfig) (*gossh.Client, error) {
 	log.Info("connecting to target using jump client", "addr", nextAddr)
-	jumpConn, err := jumpClient.Dial("tcp", nextAddr)
+	jumpConn, err := client.Dial("tcp", nextAddr)
 	if err != nil {
-		return nil, cl, fmt.Errorf("connection from ProxyJump (%s) to Host (%s) failed: %w", addr, nextAddr, err)
+		return nil, fmt.Errorf("dial %s: %w", nextAddr, err)
 	}
-	cl = append(cl, jumpConn.Close)
 
 	log.Info("getting client connection", "addr", nextAddr)
-	ncc, chans, reqs, err := gossh.NewClientConn(jumpConn, nextAddr, nextConf)
+	next, err := newClientConn(jumpConn, nextAddr, nextConf)
 	if err != nil {
-		return nil, cl, fmt.Errorf("client connection from ProxyJump (%s) to Host (%s) failed: %w", addr, nextAddr, err)
+		return nil, fmt.Errorf("client connection to %s: %w", nextAddr, err)
 	}
-	cl = append(cl, ncc.Close)
-
-	return gossh.NewClient(ncc, chans, reqs), cl, nil
+	return next, nil
 }
 
 func ensureJumpPort(addr string) string {
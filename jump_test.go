@@ -1,9 +1,18 @@
 package wishlist
 
 import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/charmbracelet/ssh"
 	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
 )
 
 func TestSplitJump(t *testing.T) {
@@ -28,3 +37,201 @@ func TestSplitJump(t *testing.T) {
 		})
 	}
 }
+
+func TestSplitJumps(t *testing.T) {
+	require.Empty(t, splitJumps(""))
+	require.Empty(t, splitJumps("none"))
+	require.Equal(t, []string{"foo"}, splitJumps("foo"))
+	require.Equal(
+		t,
+		[]string{"user@foo:2222", "bar", "baz"},
+		splitJumps("ssh://user@foo:2222, bar,,baz "),
+	)
+}
+
+func TestResolveJumps(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "edge", Address: "edge.example.com:22", User: "edger"},
+		{Name: "bastion", Address: "bastion.example.com:2222", User: "jumper", IdentityFiles: []string{"~/.ssh/bastion"}, ProxyJump: "edge"},
+		{Name: "internal", Group: "prod", Address: "internal.prod:22", ProxyJump: "unused"},
+		{Name: "a", Address: "a:22", ProxyJump: "b"},
+		{Name: "b", Address: "b:22", ProxyJump: "c"},
+		{Name: "c", Address: "c:22", ProxyJump: "a"},
+	}
+	names := func(hops []*Endpoint) []string {
+		var result []string
+		for _, hop := range hops {
+			result = append(result, hop.Name+"="+hop.User+"@"+hop.Address)
+		}
+		return result
+	}
+
+	t.Run("none", func(t *testing.T) {
+		hops, err := resolveJumps(&Endpoint{Name: "db1"}, endpoints)
+		require.NoError(t, err)
+		require.Empty(t, hops)
+	})
+
+	t.Run("hosts", func(t *testing.T) {
+		hops, err := resolveJumps(&Endpoint{Name: "db1", ProxyJump: "user@foo,bar:2222"}, endpoints)
+		require.NoError(t, err)
+		require.Equal(t, []string{"user@foo=user@foo:22", "bar:2222=@bar:2222"}, names(hops))
+	})
+
+	t.Run("hosts inherit settings", func(t *testing.T) {
+		hops, err := resolveJumps(&Endpoint{
+			Name:                  "db1",
+			User:                  "app",
+			ProxyJump:             "foo,root@bar",
+			IdentityFiles:         []string{"~/.ssh/db1"},
+			Timeout:               time.Second,
+			StrictHostKeyChecking: "yes",
+			UserKnownHostsFile:    []string{"~/.ssh/known_hosts_db1"},
+		}, endpoints)
+		require.NoError(t, err)
+		require.Equal(t, []string{"foo=app@foo:22", "root@bar=root@bar:22"}, names(hops))
+		for _, hop := range hops {
+			require.Equal(t, []string{"~/.ssh/db1"}, hop.IdentityFiles)
+			require.Equal(t, time.Second, hop.Timeout)
+			require.Equal(t, "yes", hop.StrictHostKeyChecking)
+			require.Equal(t, []string{"~/.ssh/known_hosts_db1"}, hop.UserKnownHostsFile)
+		}
+	})
+
+	t.Run("endpoints", func(t *testing.T) {
+		hops, err := resolveJumps(&Endpoint{Name: "db1", ProxyJump: "bastion,prod/internal"}, endpoints)
+		require.NoError(t, err)
+		require.Equal(t, []string{
+			"edge=edger@edge.example.com:22",
+			"bastion=jumper@bastion.example.com:2222",
+			"internal=@internal.prod:22",
+		}, names(hops))
+		require.Equal(t, []string{"~/.ssh/bastion"}, hops[1].IdentityFiles)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		_, err := resolveJumps(endpoints[3], endpoints)
+		require.EqualError(t, err, "ProxyJump cycle: a → b → c → a")
+	})
+
+	t.Run("self", func(t *testing.T) {
+		_, err := resolveJumps(&Endpoint{Name: "edge", ProxyJump: "edge"}, endpoints)
+		require.EqualError(t, err, "ProxyJump cycle: edge → edge")
+	})
+
+	t.Run("same name in different groups", func(t *testing.T) {
+		endpoints := []*Endpoint{
+			{Name: "bastion", Group: "a", Address: "bastion.a:22", ProxyJump: "b/bastion"},
+			{Name: "bastion", Group: "b", Address: "bastion.b:22"},
+		}
+		hops, err := resolveJumps(&Endpoint{Name: "db1", ProxyJump: "a/bastion"}, endpoints)
+		require.NoError(t, err)
+		require.Equal(t, []string{"bastion=@bastion.b:22", "bastion=@bastion.a:22"}, names(hops))
+
+		endpoints[1].ProxyJump = "a/bastion"
+		_, err = resolveJumps(endpoints[0], endpoints)
+		require.EqualError(t, err, "ProxyJump cycle: a/bastion → b/bastion → a/bastion")
+	})
+}
+
+func TestDialJumps(t *testing.T) {
+	hop1 := forwardingServer(t, func(ssh.Session) {})
+	hop2 := forwardingServer(t, func(ssh.Session) {})
+	target := forwardingServer(t, func(s ssh.Session) {
+		_, _ = fmt.Fprint(s, "hello from "+s.User())
+	})
+	endpoints := []*Endpoint{
+		{Name: "hop1", Address: hop1},
+		{Name: "hop2", Address: hop2, User: "jumper", ProxyJump: "hop1"},
+		{Name: "strict", Address: hop1, StrictHostKeyChecking: "yes"},
+		{Name: "asking", Address: hop1, StrictHostKeyChecking: "ask"},
+	}
+
+	// dial configures the target and hops the same way the clients do.
+	dial := func(tb testing.TB, e *Endpoint, path string) (*gossh.Session, error) {
+		tb.Helper()
+		kh := knownHosts{user: []string{path}}
+		hops, hcl, err := jumpHops(e, endpoints, func(hop *Endpoint) (*gossh.ClientConfig, closers, error) {
+			return &gossh.ClientConfig{
+				User:            FirstNonEmpty(hop.User, "carlos"),
+				HostKeyCallback: hopHostKeyCallback(e, hop, kh, nil, nil),
+				Timeout:         hop.Timeout,
+			}, nil, nil
+		})
+		tb.Cleanup(hcl.close)
+		require.NoError(tb, err)
+		session, _, cl, err := createSession(&gossh.ClientConfig{
+			User:            "carlos",
+			HostKeyCallback: hostKeyCallback(e, kh, nil, nil),
+			Timeout:         e.Timeout,
+		}, e, hops, nil)
+		tb.Cleanup(cl.close)
+		return session, err
+	}
+
+	t.Run("chain", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		session, err := dial(t, &Endpoint{Name: "target", Address: target, ProxyJump: "hop2"}, path)
+		require.NoError(t, err)
+		out, err := session.Output("")
+		require.NoError(t, err)
+		require.Equal(t, "hello from carlos", string(out))
+
+		// each hop has its own known hosts entry.
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(bts)), "\n")
+		require.Len(t, lines, 3)
+		for i, addr := range []string{hop1, hop2, target} {
+			require.True(t, strings.HasPrefix(lines[i], knownhosts.Normalize(addr)+" "), lines[i])
+		}
+	})
+
+	t.Run("hop host key checking", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: target, ProxyJump: "strict"}
+		_, err := dial(t, e, filepath.Join(t.TempDir(), "known_hosts"))
+		require.ErrorContains(t, err, fmt.Sprintf("no host key is known for %q", hop1))
+	})
+
+	t.Run("hop host key unknown", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: target, ProxyJump: "asking"}
+		_, err := dial(t, e, filepath.Join(t.TempDir(), "known_hosts"))
+		var uerr *HostKeyUnknownError
+		require.ErrorAs(t, err, &uerr)
+		require.Equal(t, e, uerr.Endpoint)
+		require.Equal(t, hop1, uerr.Hostname)
+	})
+
+	t.Run("hop timeout", func(t *testing.T) {
+		// accepts connections, but never does the SSH handshake.
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+		go func() {
+			for {
+				conn, err := l.Accept()
+				if err != nil {
+					return
+				}
+				t.Cleanup(func() { _ = conn.Close() })
+			}
+		}()
+
+		e := &Endpoint{Name: "target", Address: l.Addr().String(), ProxyJump: "hop1", Timeout: 100 * time.Millisecond}
+		_, err = dial(t, e, filepath.Join(t.TempDir(), "known_hosts"))
+		require.ErrorContains(t, err, "timed out after 100ms")
+	})
+
+	t.Run("failed hop", func(t *testing.T) {
+		down := closedAddr(t)
+		e := &Endpoint{Name: "target", Address: target, ProxyJump: "hop1," + down}
+		_, err := dial(t, e, filepath.Join(t.TempDir(), "known_hosts"))
+		require.ErrorContains(t, err, `connection from ProxyJump "hop1" to ProxyJump "`+down+`" failed`)
+	})
+
+	t.Run("failed target", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: closedAddr(t), ProxyJump: "hop1"}
+		_, err := dial(t, e, filepath.Join(t.TempDir(), "known_hosts"))
+		require.ErrorContains(t, err, `connection from ProxyJump "hop1" to Host (`+e.Address+`) failed`)
+	})
+}
@@ -0,0 +1,299 @@
+package wishlist
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+)
+
+// Actions that can be bound to keys.
+const (
+	KeyConnect      = "connect"
+	KeyCopy         = "copy"
+	KeyBack         = "back"
+	KeyPin          = "pin"
+	KeySort         = "sort"
+	KeyMark         = "mark"
+	KeyRun          = "run"
+	KeyDetails      = "details"
+	KeyConfirm      = "confirm"
+	KeyCancel       = "cancel"
+	KeyCopyOutput   = "copy_output"
+	KeyAcceptOnce   = "accept_once"
+	KeyReplaceKey   = "replace_key"
+	KeyTrustKey     = "trust_key"
+	KeyAdd          = "add"
+	KeyEdit         = "edit"
+	KeyNextField    = "next_field"
+	KeyPrevField    = "prev_field"
+	KeyUp           = "up"
+	KeyDown         = "down"
+	KeyPrevPage     = "prev_page"
+	KeyNextPage     = "next_page"
+	KeyGoToStart    = "go_to_start"
+	KeyGoToEnd      = "go_to_end"
+	KeyFilter       = "filter"
+	KeyClearFilter  = "clear_filter"
+	KeyCancelFilter = "cancel_filter"
+	KeyAcceptFilter = "accept_filter"
+	KeyHelp         = "help"
+	KeyQuit         = "quit"
+	KeyForceQuit    = "force_quit"
+)
+
+// Keys maps actions to the keys that trigger them, e.g.:
+//
+//	connect: [enter, l]
+//	copy: [c]
+//
+// Actions not set keep their default keys.
+type Keys map[string][]string
+
+// keyScopes are the groups of actions which are active at the same time,
+// and thus can't share keys.
+var keyScopes = map[string][]string{
+	"list": {
+		KeyConnect, KeyCopy, KeyBack, KeyPin, KeySort, KeyMark, KeyRun,
+		KeyDetails, KeyAdd, KeyEdit, KeyUp, KeyDown, KeyPrevPage, KeyNextPage,
+		KeyGoToStart, KeyGoToEnd, KeyFilter, KeyClearFilter, KeyHelp, KeyQuit,
+		KeyForceQuit,
+	},
+	"filter":   {KeyCancelFilter, KeyAcceptFilter, KeyForceQuit},
+	"prompt":   {KeyConfirm, KeyCancel, KeyForceQuit},
+	"results":  {KeyCopyOutput, KeyCancel, KeyUp, KeyDown, KeyForceQuit},
+	"host_key": {KeyCancel, KeyAcceptOnce, KeyReplaceKey, KeyTrustKey, KeyForceQuit},
+	"form":     {KeyConfirm, KeyCancel, KeyNextField, KeyPrevField, KeyForceQuit},
+	"menu":     {KeyConfirm, KeyCancel, KeyUp, KeyDown, KeyForceQuit},
+}
+
+// sharedKeys are the actions of the same scope which can share keys, as one
+// takes precedence: while a filter is applied, esc clears it instead of
+// quitting.
+var sharedKeys = map[[2]string]bool{
+	{KeyClearFilter, KeyQuit}: true,
+	{KeyQuit, KeyClearFilter}: true,
+}
+
+// Validate returns an error if any action is unknown, has no keys, or shares
+// a key with another action active at the same time.
+func (k Keys) Validate() error {
+	km := defaultKeyMap()
+	bindings := km.bindings()
+	for _, action := range sortedKeys(k) {
+		if _, ok := bindings[action]; !ok {
+			return fmt.Errorf("invalid key binding action %q", action)
+		}
+		if len(k[action]) == 0 {
+			return fmt.Errorf("key binding action %q has no keys", action)
+		}
+	}
+	km.apply(k)
+	bindings = km.bindings()
+
+	for _, scope := range sortedKeys(keyScopes) {
+		bound := map[string]string{}
+		for _, action := range keyScopes[scope] {
+			for _, kk := range bindings[action][0].Keys() {
+				if other, ok := bound[kk]; ok && !sharedKeys[[2]string{other, action}] {
+					return fmt.Errorf("key %q is bound to both %q and %q", kk, other, action)
+				}
+				bound[kk] = action
+			}
+		}
+	}
+	return nil
+}
+
+// keyMap are the key bindings of a ListModel.
+type keyMap struct {
+	Connect    key.Binding
+	Copy       key.Binding
+	Back       key.Binding
+	Pin        key.Binding
+	Sort       key.Binding
+	Mark       key.Binding
+	Run        key.Binding
+	Details    key.Binding
+	Confirm    key.Binding
+	Cancel     key.Binding
+	CopyOutput key.Binding
+	AcceptOnce key.Binding
+	ReplaceKey key.Binding
+	TrustKey   key.Binding
+	Add        key.Binding
+	Edit       key.Binding
+	NextField  key.Binding
+	PrevField  key.Binding
+
+	// List are the bubbles list key bindings.
+	List list.KeyMap
+}
+
+func defaultKeyMap() keyMap {
+	return keyMap{
+		Copy: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy"),
+		),
+		Connect: key.NewBinding(
+			key.WithKeys("enter", "o"),
+			key.WithHelp("enter/o", "connect"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("backspace"),
+			key.WithHelp("backspace", "parent group"),
+		),
+		Pin: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "toggle pin"),
+		),
+		Sort: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sort"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "mark"),
+		),
+		Run: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "run command"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "run"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back"),
+		),
+		Details: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "toggle details"),
+		),
+		CopyOutput: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy output"),
+		),
+		AcceptOnce: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "accept once"),
+		),
+		ReplaceKey: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "replace stored key"),
+		),
+		TrustKey: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "trust and save"),
+		),
+		Add: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "add endpoint"),
+		),
+		Edit: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "edit endpoint"),
+		),
+		NextField: key.NewBinding(
+			key.WithKeys("tab", "down"),
+			key.WithHelp("tab/↓", "next field"),
+		),
+		PrevField: key.NewBinding(
+			key.WithKeys("shift+tab", "up"),
+			key.WithHelp("shift+tab/↑", "previous field"),
+		),
+		List: list.DefaultKeyMap(),
+	}
+}
+
+// newKeyMap returns the default key map, with the given keys applied.
+func newKeyMap(keys Keys) keyMap {
+	km := defaultKeyMap()
+	km.apply(keys)
+	return km
+}
+
+// bindings returns the bindings of each action.
+// Some actions have more than one binding, e.g. help shows and closes the
+// full help.
+func (km *keyMap) bindings() map[string][]*key.Binding {
+	return map[string][]*key.Binding{
+		KeyConnect:      {&km.Connect},
+		KeyCopy:         {&km.Copy},
+		KeyBack:         {&km.Back},
+		KeyPin:          {&km.Pin},
+		KeySort:         {&km.Sort},
+		KeyMark:         {&km.Mark},
+		KeyRun:          {&km.Run},
+		KeyDetails:      {&km.Details},
+		KeyConfirm:      {&km.Confirm},
+		KeyCancel:       {&km.Cancel},
+		KeyCopyOutput:   {&km.CopyOutput},
+		KeyAcceptOnce:   {&km.AcceptOnce},
+		KeyReplaceKey:   {&km.ReplaceKey},
+		KeyTrustKey:     {&km.TrustKey},
+		KeyAdd:          {&km.Add},
+		KeyEdit:         {&km.Edit},
+		KeyNextField:    {&km.NextField},
+		KeyPrevField:    {&km.PrevField},
+		KeyUp:           {&km.List.CursorUp},
+		KeyDown:         {&km.List.CursorDown},
+		KeyPrevPage:     {&km.List.PrevPage},
+		KeyNextPage:     {&km.List.NextPage},
+		KeyGoToStart:    {&km.List.GoToStart},
+		KeyGoToEnd:      {&km.List.GoToEnd},
+		KeyFilter:       {&km.List.Filter},
+		KeyClearFilter:  {&km.List.ClearFilter},
+		KeyCancelFilter: {&km.List.CancelWhileFiltering},
+		KeyAcceptFilter: {&km.List.AcceptWhileFiltering},
+		KeyHelp:         {&km.List.ShowFullHelp, &km.List.CloseFullHelp},
+		KeyQuit:         {&km.List.Quit},
+		KeyForceQuit:    {&km.List.ForceQuit},
+	}
+}
+
+// apply sets the given keys, updating the help accordingly.
+// Unknown actions are ignored.
+func (km *keyMap) apply(keys Keys) {
+	bindings := km.bindings()
+	for action, kk := range keys {
+		if len(kk) == 0 {
+			continue
+		}
+		for _, b := range bindings[action] {
+			b.SetKeys(kk...)
+			b.SetHelp(keyHelp(kk), b.Help().Desc)
+		}
+	}
+}
+
+// keyHelp returns the help text of the given keys, e.g. `enter/o`.
+func keyHelp(keys []string) string {
+	names := make([]string, 0, len(keys))
+	for _, k := range keys {
+		switch k {
+		case " ":
+			k = "space"
+		case "up":
+			k = "↑"
+		case "down":
+			k = "↓"
+		}
+		names = append(names, k)
+	}
+	return strings.Join(names, "/")
+}
+
+// sortedKeys returns the keys of the given map, sorted.
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+package wishlist
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestKeysValidate(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		require.NoError(t, Keys{}.Validate())
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		var keys Keys
+		require.NoError(t, yaml.Unmarshal([]byte(`
+connect: [enter, l]
+next_page: [right, pgdown, f, d]
+copy: [c]
+copy_output: [c]
+`), &keys))
+		require.NoError(t, keys.Validate())
+	})
+
+	for name, tt := range map[string]struct {
+		keys Keys
+		err  string
+	}{
+		"unknown action": {
+			keys: Keys{"nope": {"x"}},
+			err:  `invalid key binding action "nope"`,
+		},
+		"no keys": {
+			keys: Keys{KeyCopy: {}},
+			err:  `key binding action "copy" has no keys`,
+		},
+		"conflict with default": {
+			keys: Keys{KeyCopy: {"p"}},
+			err:  `key "p" is bound to both "copy" and "pin"`,
+		},
+		"conflict with clear filter": {
+			keys: Keys{KeyClearFilter: {"p"}},
+			err:  `key "p" is bound to both "pin" and "clear_filter"`,
+		},
+		"conflict in the same scope": {
+			keys: Keys{KeyCopyOutput: {"x"}, KeyCancel: {"x"}},
+			err:  `key "x" is bound to both "copy_output" and "cancel"`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.EqualError(t, tt.keys.Validate(), tt.err)
+		})
+	}
+}
+
+func TestWithKeys(t *testing.T) {
+	m := NewListing([]*Endpoint{
+		{Name: "a", Address: "a:22"},
+	}, fakeClient{}, testRenderer, WithKeys(Keys{
+		KeyPin:  {"ctrl+p", "P"},
+		KeyQuit: {"x"},
+	}))
+
+	require.Equal(t, "ctrl+p/P", m.keys.Pin.Help().Key)
+	require.Equal(t, "toggle pin", m.keys.Pin.Help().Desc)
+	require.Equal(t, []string{"x"}, m.list.KeyMap.Quit.Keys())
+	require.Contains(t, m.list.Help.FullHelpView(m.list.FullHelp()), "ctrl+p/P")
+
+	// the old key does nothing
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	require.False(t, m.quitting)
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x")})
+	require.True(t, m.quitting)
+}
+
+func TestKeyHelp(t *testing.T) {
+	require.Equal(t, "space", keyHelp([]string{" "}))
+	require.Equal(t, "↑/k", keyHelp([]string{"up", "k"}))
+}
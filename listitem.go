@@ -1,39 +1,100 @@
 package wishlist
 
 import (
+	"fmt"
+	"io"
+	"slices"
 	"strings"
 
 	"github.com/charmbracelet/bubbles/list"
 )
 
-var _ list.Item = ItemWrapper{}
+var (
+	_ list.Item = ItemWrapper{}
+	_ list.Item = groupItem{}
+)
 
 // ItemWrapper wrappes an Endpoint and a set of descriptors and acts as a list.Item.
 type ItemWrapper struct {
 	endpoint    *Endpoint
 	descriptors []descriptor
 	styles      styles
+
+	// fields matched by the current filter, which should be highlighted.
+	matched []string
+
+	// whether the endpoint is pinned to the top of the list.
+	pinned bool
+
+	// whether the endpoint is marked to run a command on.
+	marked bool
+
+	// latest probe result of the endpoint, if it's being probed.
+	probe *ProbeResult
 }
 
 // FilterValue to abide the list.Item interface.
 func (i ItemWrapper) FilterValue() string { return i.endpoint.Name }
 
 // Title to abide the list.Item interface.
-func (i ItemWrapper) Title() string { return i.endpoint.Name }
+func (i ItemWrapper) Title() string {
+	title := i.endpoint.Name
+	if i.pinned {
+		title += " " + pinMark
+	}
+	if i.marked {
+		title += " " + markMark
+	}
+	if status := probeStatus(i.probe, i.styles); status != "" {
+		title += " " + status
+	}
+	return title
+}
+
+const (
+	pinMark  = "★"
+	markMark = "✓"
+)
 
 // Description to abide the list.Item interface.
 func (i ItemWrapper) Description() string {
 	lines := make([]string, 0, len(i.descriptors))
 	for _, desc := range i.descriptors {
-		lines = append(lines, desc(i.endpoint, i.styles))
+		line := desc.render(i.endpoint, i.styles)
+		if desc.highlight(i.matched) {
+			line = i.styles.Match.Render(line)
+		}
+		lines = append(lines, line)
 	}
 	return strings.Join(lines, "\n")
 }
 
-type descriptor func(e *Endpoint, styles styles) string
+// descriptor renders a line of the item description.
+type descriptor struct {
+	// filter fields shown by this descriptor.
+	fields []string
+	render func(e *Endpoint, styles styles) string
+}
+
+// highlight returns true if any of the descriptor fields was matched.
+func (d descriptor) highlight(matched []string) bool {
+	for _, field := range d.fields {
+		if slices.Contains(matched, field) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	descSSHURL      = descriptor{fields: []string{FilterFieldAddress, FilterFieldUser}, render: withSSHURL}
+	descLink        = descriptor{fields: []string{FilterFieldLink}, render: withLink}
+	descTags        = descriptor{render: withTags}
+	descDescription = descriptor{fields: []string{FilterFieldDescription}, render: withDescription}
+)
 
 func withSSHURL(i *Endpoint, _ styles) string {
-	return Link{URL: "ssh://" + i.Address}.String()
+	return Link{URL: sshURL(i)}.String()
 }
 
 func withLink(i *Endpoint, styles styles) string {
@@ -43,9 +104,62 @@ func withLink(i *Endpoint, styles styles) string {
 	return styles.NoContent.Render("no link")
 }
 
+func withTags(i *Endpoint, styles styles) string {
+	if len(i.Tags) == 0 {
+		return styles.NoContent.Render("no tags")
+	}
+	badges := make([]string, 0, len(i.Tags))
+	for _, tag := range i.Tags {
+		badges = append(badges, styles.Badge.Render(tag))
+	}
+	return strings.Join(badges, "")
+}
+
 func withDescription(i *Endpoint, styles styles) string {
 	if desc := strings.Split(i.Desc, "\n")[0]; desc != "" {
 		return desc
 	}
 	return styles.NoContent.Render("no description")
 }
+
+// itemDelegate is a list.DefaultDelegate that highlights the description
+// lines of the fields matched by the current filter.
+type itemDelegate struct {
+	list.DefaultDelegate
+	fields []string
+}
+
+// Render to abide the list.ItemDelegate interface.
+func (d itemDelegate) Render(w io.Writer, m list.Model, index int, item list.Item) {
+	if wrapper, ok := item.(ItemWrapper); ok && m.FilterState() != list.Unfiltered {
+		if match, ok := parseQuery(m.FilterValue()).matchText(wrapper, d.fields); ok {
+			wrapper.matched = match.fields
+			item = wrapper
+		}
+	}
+	d.DefaultDelegate.Render(w, m, index, item)
+}
+
+// groupItem is a group of endpoints, which can be entered into.
+type groupItem struct {
+	name      string
+	path      string
+	endpoints int
+	descLines int
+}
+
+// FilterValue to abide the list.Item interface.
+func (i groupItem) FilterValue() string { return i.name }
+
+// Title to abide the list.Item interface.
+func (i groupItem) Title() string { return i.name + "/" }
+
+// Description to abide the list.Item interface.
+func (i groupItem) Description() string {
+	desc := "1 endpoint"
+	if i.endpoints != 1 {
+		desc = fmt.Sprintf("%d endpoints", i.endpoints)
+	}
+	// pad so groups have the same height as endpoints.
+	return desc + strings.Repeat("\n", max(i.descLines-1, 0))
+}
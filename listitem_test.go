@@ -17,10 +17,11 @@ func TestItemWrapper(t *testing.T) {
 			Address: "foo.bar:22",
 		},
 		descriptors: []descriptor{
-			withDescription,
-			withLink,
-			withSSHURL,
+			descDescription,
+			descLink,
+			descSSHURL,
 		},
+		styles: makeStyles(testRenderer, Theme{}),
 	}
 
 	require.Equal(t, "name", s.Title())
@@ -30,6 +31,24 @@ func TestItemWrapper(t *testing.T) {
 		"desc\nhttps://example.com\nssh://foo.bar:22",
 		s.Description(),
 	)
+
+	t.Run("highlight", func(t *testing.T) {
+		s.styles.Match = s.styles.Match.SetString(">")
+		s.matched = []string{FilterFieldUser, FilterFieldDescription}
+		require.Equal(
+			t,
+			"> desc\nhttps://example.com\n> ssh://foo.bar:22",
+			s.Description(),
+		)
+	})
+}
+
+func TestDescriptorHighlight(t *testing.T) {
+	require.True(t, descSSHURL.highlight([]string{FilterFieldAddress}))
+	require.True(t, descSSHURL.highlight([]string{FilterFieldName, FilterFieldUser}))
+	require.False(t, descSSHURL.highlight([]string{FilterFieldName}))
+	require.False(t, descTags.highlight([]string{FilterFieldName}))
+	require.False(t, descLink.highlight(nil))
 }
 
 func TestWithSSHURL(t *testing.T) {
@@ -47,7 +66,7 @@ func TestWithDescription(t *testing.T) {
 		require.Equal(
 			t,
 			"no description",
-			withDescription(&Endpoint{}, makeStyles(testRenderer)),
+			withDescription(&Endpoint{}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 	t.Run("multiline", func(t *testing.T) {
@@ -56,7 +75,7 @@ func TestWithDescription(t *testing.T) {
 			"foo",
 			withDescription(&Endpoint{
 				Desc: "foo\n\nbar\n\nsfsdfsd\n",
-			}, makeStyles(testRenderer)),
+			}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 	t.Run("simple", func(t *testing.T) {
@@ -65,7 +84,7 @@ func TestWithDescription(t *testing.T) {
 			"foobar desc",
 			withDescription(&Endpoint{
 				Desc: "foobar desc",
-			}, makeStyles(testRenderer)),
+			}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 }
@@ -75,7 +94,7 @@ func TestWithLink(t *testing.T) {
 		require.Equal(
 			t,
 			"no link",
-			withLink(&Endpoint{}, makeStyles(testRenderer)),
+			withLink(&Endpoint{}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 	t.Run("url only", func(t *testing.T) {
@@ -86,7 +105,7 @@ func TestWithLink(t *testing.T) {
 				Link: Link{
 					URL: "https://example.com",
 				},
-			}, makeStyles(testRenderer)),
+			}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 	t.Run("url and name", func(t *testing.T) {
@@ -98,7 +117,24 @@ func TestWithLink(t *testing.T) {
 					Name: "example",
 					URL:  "https://example.com",
 				},
-			}, makeStyles(testRenderer)),
+			}, makeStyles(testRenderer, Theme{})),
 		)
 	})
 }
+
+func TestWithTags(t *testing.T) {
+	t.Run("no tags", func(t *testing.T) {
+		require.Equal(
+			t,
+			"no tags",
+			withTags(&Endpoint{}, makeStyles(testRenderer, Theme{})),
+		)
+	})
+	t.Run("tags", func(t *testing.T) {
+		result := withTags(&Endpoint{
+			Tags: []string{"prod", "db"},
+		}, makeStyles(testRenderer, Theme{}))
+		require.Contains(t, result, "prod")
+		require.Contains(t, result, "db")
+	})
+}
@@ -0,0 +1,62 @@
+package wishlist
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// menuItem is an option of a menu.
+type menuItem struct {
+	title string
+	desc  string
+}
+
+// menu is a list of options to pick one from.
+type menu struct {
+	title  string
+	items  []menuItem
+	cursor int
+}
+
+// move moves the cursor if the key is bound to up or down, returning whether
+// it was.
+func (mn *menu) move(msg tea.KeyMsg, km keyMap) bool {
+	switch {
+	case key.Matches(msg, km.List.CursorUp):
+		mn.cursor = max(mn.cursor-1, 0)
+	case key.Matches(msg, km.List.CursorDown):
+		mn.cursor = min(mn.cursor+1, len(mn.items)-1)
+	default:
+		return false
+	}
+	return true
+}
+
+// menuView renders the given menu, with confirm as the binding to pick the
+// selected option.
+func (m *ListModel) menuView(mn *menu, confirm key.Binding) string {
+	rows := make([]string, 0, len(mn.items))
+	for i, item := range mn.items {
+		title, desc := m.styles.Item.NormalTitle, m.styles.Item.NormalDesc
+		if i == mn.cursor {
+			title, desc = m.styles.Item.SelectedTitle, m.styles.Item.SelectedDesc
+		}
+		rows = append(rows, title.Render(item.title)+"\n"+desc.Render(item.desc))
+	}
+
+	return m.styles.Doc.Render(lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.styles.Logo.String()+"\n",
+		m.styles.Header.Render(mn.title)+"\n",
+		strings.Join(rows, "\n\n")+"\n",
+		m.styles.Footer.Render(m.list.Help.ShortHelpView([]key.Binding{
+			m.keys.List.CursorUp,
+			m.keys.List.CursorDown,
+			confirm,
+			m.keys.Cancel,
+		})),
+	))
+}
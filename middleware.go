@@ -1,6 +1,8 @@
 package wishlist
 
 import (
+	"errors"
+	"flag"
 	"fmt"
 	"strings"
 
@@ -16,40 +18,99 @@ import (
 	"github.com/teivah/broadcast"
 )
 
-// handles ssh host -t appname.
-func cmdsMiddleware(endpoints []*Endpoint) wish.Middleware {
-	valid := []string{`"list"`}
-	for _, e := range endpoints {
-		valid = append(valid, fmt.Sprintf("%q", e.Name))
-	}
+// handles ssh host -t appname [command], and ssh host list [flags].
+func cmdsMiddleware(config *Config, endpoints func() []*Endpoint, states *stateStore) wish.Middleware {
 	return func(h ssh.Handler) ssh.Handler {
 		return func(s ssh.Session) {
 			cmd := s.Command()
+			access := userAccess(config, s.User())
 
 			if len(cmd) == 0 {
 				h(s)
 				return
 			}
 
-			if len(cmd) == 1 && cmd[0] != "list" {
-				for _, e := range endpoints {
-					if e.Name == cmd[0] {
-						mustConnect(s, e)
+			if cmd[0] == "list" {
+				// the listing needs a PTY, otherwise, or if there are flags,
+				// endpoints are written in a machine-readable format.
+				if _, _, active := s.Pty(); active && len(cmd) == 1 {
+					h(s)
+					return
+				}
+				mustList(s, access.filter(endpoints()), cmd[1:])
+				return // unreachable
+			}
+
+			allowed := access.filter(endpoints())
+			e := FindEndpoint(allowed, cmd[0])
+			if e == nil {
+				if denied := FindEndpoint(endpoints(), cmd[0]); denied != nil {
+					log.Warn("access denied", "user", s.User(), "endpoint", denied.FullName())
+					config.audit.accessDenied(s, denied)
+					if !config.HideDeniedEndpoints {
+						wish.Fatal(s, fmt.Errorf("wishlist: access to %q denied", cmd[0]))
 						return // unreachable
 					}
 				}
+				valid := []string{`"list"`}
+				for _, e := range allowed {
+					valid = append(valid, fmt.Sprintf("%q", e.FullName()))
+				}
 				wish.Fatal(s, fmt.Errorf("wishlist: command %q not found, valid commands are %s", cmd[0], strings.Join(valid, ", ")))
 				return // unreachable
 			}
-			h(s)
+			if len(cmd) > 1 {
+				// ssh host -t appname command, the command name might have
+				// spaces.
+				name := strings.Join(cmd[1:], " ")
+				c, err := e.FindCommand(name)
+				if err != nil {
+					wish.Fatal(s, fmt.Errorf("wishlist: %w", err))
+					return // unreachable
+				}
+				e = e.WithCommand(*c)
+			}
+			mustConnect(s, e, config, endpoints, userState(states, s.User()))
 		}
 	}
 }
 
+// mustList writes the endpoints as requested by the list command arguments,
+// e.g. `--json`.
+func mustList(s ssh.Session, endpoints []*Endpoint, args []string) {
+	format, fields, err := parseListArgs(args, s.Stderr())
+	if errors.Is(err, flag.ErrHelp) {
+		_ = s.Exit(0)
+		return
+	}
+	if err != nil {
+		wish.Fatal(s, fmt.Errorf("wishlist: %w", err))
+		return // unreachable
+	}
+	if err := WriteEndpoints(s, endpoints, format, fields...); err != nil {
+		wish.Fatal(s, fmt.Errorf("wishlist: %w", err))
+		return // unreachable
+	}
+	_ = s.Exit(0)
+}
+
 // handles the listing and handoff of apps.
-func listingMiddleware(config *Config, endpointRelay *broadcast.Relay[[]*Endpoint]) wish.Middleware {
+func listingMiddleware(
+	config *Config,
+	endpoints func() []*Endpoint,
+	endpointRelay *broadcast.Relay[[]*Endpoint],
+	prober *Prober,
+	probeRelay *broadcast.Relay[ProbeResults],
+	states *stateStore,
+) wish.Middleware {
 	return func(ssh.Handler) ssh.Handler {
 		return func(s ssh.Session) {
+			if _, _, active := s.Pty(); !active {
+				wish.Fatalln(s, "wishlist: the listing requires an active PTY, try ssh -t, or list --json")
+				return // unreachable
+			}
+			state := userState(states, s.User())
+			access := userAccess(config, s.User())
 			lipgloss.SetColorProfile(termenv.ANSI256)
 
 			multiplexDoneCh := make(chan bool, 1)
@@ -58,20 +119,39 @@ func listingMiddleware(config *Config, endpointRelay *broadcast.Relay[[]*Endpoin
 
 			endpointL := endpointRelay.Listener(0)
 			defer endpointL.Close()
+			probeL := probeRelay.Listener(1)
+			defer probeL.Close()
+
+			opts := []ListingOption{
+				WithFilterFields(config.Filter.Fields...),
+				WithState(state),
+				WithTheme(config.Theme),
+				WithKeys(config.Keys),
+				WithHostKeyReplace(!config.DisableHostKeyReplace),
+			}
+			if config.EndpointWriter != nil && isAdmin(config, s.User()) {
+				opts = append(opts, WithEndpointWriter(config.EndpointWriter))
+			}
 
 			errch := make(chan error, 1)
 			appch := make(chan bool, 1)
 			model := NewListing(
-				config.Endpoints,
+				access.filter(endpoints()),
 				&remoteClient{
-					session: s,
-					stdin:   handoffStdin,
+					session:           s,
+					stdin:             handoffStdin,
+					state:             state,
+					endpoints:         endpoints,
+					allowProxyCommand: config.AllowProxyCommand,
+					recording:         config.Recording,
+					audit:             config.audit,
 					cleanup: func() {
 						listStdin.Reset()
 						handoffStdin.Reset()
 					},
 				},
 				bm.MakeRenderer(s),
+				opts...,
 			)
 			p := tea.NewProgram(
 				model,
@@ -79,7 +159,10 @@ func listingMiddleware(config *Config, endpointRelay *broadcast.Relay[[]*Endpoin
 				tea.WithOutput(s),
 				tea.WithAltScreen(),
 			)
-			go listenAppEvents(s, p, appch, endpointL.Ch(), errch)
+			if results := prober.Results(); results != nil {
+				go p.Send(ProbeResultsMsg{Results: results})
+			}
+			go listenAppEvents(s, p, access, appch, endpointL.Ch(), probeL.Ch(), errch)
 			_, err := p.Run()
 			errch <- err
 			appch <- true
@@ -93,12 +176,15 @@ func listingMiddleware(config *Config, endpointRelay *broadcast.Relay[[]*Endpoin
 // - session's context done: when the session is terminated by either party
 // - winch: when the terminal is resized
 // - endpointsch: new endpoint list provided
+// - probesch: new probe results provided
 // and handles them accordingly.
 func listenAppEvents(
 	s ssh.Session,
 	p *tea.Program,
+	access accessRules,
 	donech <-chan bool,
 	endpointsch <-chan []*Endpoint,
+	probesch <-chan ProbeResults,
 	errch <-chan error,
 ) {
 	_, winch, _ := s.Pty()
@@ -117,7 +203,11 @@ func listenAppEvents(
 			}
 		case m := <-endpointsch:
 			if p != nil {
-				p.Send(SetEndpointsMsg{Endpoints: m})
+				p.Send(SetEndpointsMsg{Endpoints: access.filter(m)})
+			}
+		case r := <-probesch:
+			if p != nil {
+				p.Send(ProbeResultsMsg{Results: r})
 			}
 		case err := <-errch:
 			if err != nil {
@@ -127,10 +217,26 @@ func listenAppEvents(
 	}
 }
 
-func mustConnect(session ssh.Session, e *Endpoint) {
+// userState gets the state of the given user, logging errors.
+func userState(states *stateStore, user string) *State {
+	state, err := states.get(user)
+	if err != nil {
+		log.Warn("could not open user state", "user", user, "err", err)
+		return nil
+	}
+	return state
+}
+
+func mustConnect(session ssh.Session, e *Endpoint, config *Config, endpoints func() []*Endpoint, state *State) {
 	client := &remoteClient{
-		session: session,
-		stdin:   session,
+		session:           session,
+		stdin:             session,
+		state:             state,
+		askHostKeys:       true,
+		endpoints:         endpoints,
+		allowProxyCommand: config.AllowProxyCommand,
+		recording:         config.Recording,
+		audit:             config.audit,
 	}
 	cmd := client.For(e)
 	cmd.SetStderr(session.Stderr())
@@ -0,0 +1,116 @@
+package wishlist
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish/testsession"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCmdsMiddleware(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "db1", Group: "prod", Address: "db1.local:22"},
+	}
+	newServer := func(t *testing.T) *ssh.Server {
+		t.Helper()
+		srv := &ssh.Server{
+			Handler: cmdsMiddleware(&Config{}, func() []*Endpoint { return endpoints }, newStateStore(t.TempDir()))(func(s ssh.Session) {
+				_, _ = s.Write([]byte("listing"))
+			}),
+		}
+		return srv
+	}
+
+	t.Run("list json without pty", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).Output("list --json --fields full_name,address")
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"full_name": "prod/db1", "address": "db1.local:22"}]`, string(out))
+	})
+
+	t.Run("list without pty", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).Output("list")
+		require.NoError(t, err)
+		require.Equal(t, "FULL_NAME  ADDRESS       USER  TAGS\nprod/db1   db1.local:22  -     -\n", string(out))
+	})
+
+	t.Run("list invalid format", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("list -o xml")
+		require.Error(t, err)
+		require.Contains(t, string(out), `wishlist: invalid output format "xml"`)
+	})
+
+	t.Run("no command", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).Output("")
+		require.NoError(t, err)
+		require.Equal(t, "listing", string(out))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("db2")
+		require.Error(t, err)
+		require.Contains(t, string(out), `wishlist: command "db2" not found, valid commands are "list", "prod/db1"`)
+	})
+
+	t.Run("command not found", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("db1 psql")
+		require.Error(t, err)
+		require.Contains(t, string(out), `wishlist: "db1" has no commands`)
+	})
+}
+
+func TestCmdsMiddlewareAccess(t *testing.T) {
+	endpoints := []*Endpoint{
+		{Name: "db1", Group: "prod", Address: "db1.local:22"},
+		{Name: "secret-db", Group: "prod", Address: "secret.local:22", Tags: []string{"secret"}},
+	}
+	config := &Config{
+		Users: []User{{Name: "testuser", Deny: []string{"tag:secret"}}},
+	}
+	newServer := func(t *testing.T) *ssh.Server {
+		t.Helper()
+		return &ssh.Server{
+			Handler: cmdsMiddleware(config, func() []*Endpoint { return endpoints }, newStateStore(t.TempDir()))(func(ssh.Session) {}),
+		}
+	}
+
+	t.Run("list", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).Output("list --json --fields full_name")
+		require.NoError(t, err)
+		require.JSONEq(t, `[{"full_name": "prod/db1"}]`, string(out))
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "audit.log")
+		audit, err := openAuditLog(Audit{Path: path})
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = audit.Close() })
+		config.audit = audit
+		t.Cleanup(func() { config.audit = nil })
+
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("secret-db")
+		require.Error(t, err)
+		require.Contains(t, string(out), `wishlist: access to "secret-db" denied`)
+
+		events := readAuditLog(t, path)
+		require.Len(t, events, 1)
+		require.Equal(t, auditAccessDenied, events[0]["event"])
+		require.Equal(t, "testuser", events[0]["user"])
+		require.Equal(t, "prod/secret-db", events[0]["endpoint"])
+	})
+
+	t.Run("denied hidden", func(t *testing.T) {
+		config.HideDeniedEndpoints = true
+		t.Cleanup(func() { config.HideDeniedEndpoints = false })
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("secret-db")
+		require.Error(t, err)
+		require.Contains(t, string(out), `wishlist: command "secret-db" not found, valid commands are "list", "prod/db1"`)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		out, err := testsession.New(t, newServer(t), nil).CombinedOutput("db2")
+		require.Error(t, err)
+		require.Contains(t, string(out), `valid commands are "list", "prod/db1"`)
+	})
+}
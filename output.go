@@ -0,0 +1,267 @@
+package wishlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Formats in which endpoints can be written by WriteEndpoints.
+const (
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+	OutputTable = "table"
+	OutputNames = "names"
+)
+
+// OutputFormats are the valid formats of WriteEndpoints.
+var OutputFormats = []string{OutputJSON, OutputYAML, OutputTable, OutputNames}
+
+// Fields of the endpoints that can be written by WriteEndpoints.
+const (
+	OutputFieldName          = "name"
+	OutputFieldFullName      = "full_name"
+	OutputFieldGroup         = "group"
+	OutputFieldAddress       = "address"
+	OutputFieldHost          = "host"
+	OutputFieldPort          = "port"
+	OutputFieldUser          = "user"
+	OutputFieldDescription   = "description"
+	OutputFieldLink          = "link"
+	OutputFieldTags          = "tags"
+	OutputFieldProxyJump     = "proxy_jump"
+	OutputFieldRemoteCommand = "remote_command"
+	OutputFieldSSHURL        = "ssh_url"
+)
+
+// OutputFields are the valid fields of WriteEndpoints, in the order they are
+// written in by default in the JSON and YAML formats.
+var OutputFields = []string{
+	OutputFieldName,
+	OutputFieldFullName,
+	OutputFieldGroup,
+	OutputFieldAddress,
+	OutputFieldHost,
+	OutputFieldPort,
+	OutputFieldUser,
+	OutputFieldDescription,
+	OutputFieldLink,
+	OutputFieldTags,
+	OutputFieldProxyJump,
+	OutputFieldRemoteCommand,
+	OutputFieldSSHURL,
+}
+
+// defaultTableFields are the fields written by default in the table format.
+var defaultTableFields = []string{
+	OutputFieldFullName,
+	OutputFieldAddress,
+	OutputFieldUser,
+	OutputFieldTags,
+}
+
+// WriteEndpoints writes the valid endpoints in the given format, with only
+// the given fields, if any.
+// The names format always writes the full names, one per line.
+func WriteEndpoints(w io.Writer, endpoints []*Endpoint, format string, fields ...string) error {
+	if !slices.Contains(OutputFormats, format) {
+		return fmt.Errorf("invalid output format %q, valid formats are %s", format, strings.Join(OutputFormats, ", "))
+	}
+	for _, field := range fields {
+		if !slices.Contains(OutputFields, field) {
+			return fmt.Errorf("invalid field %q, valid fields are %s", field, strings.Join(OutputFields, ", "))
+		}
+	}
+	if len(fields) == 0 {
+		fields = OutputFields
+		if format == OutputTable {
+			fields = defaultTableFields
+		}
+	}
+
+	var valid []*Endpoint
+	for _, e := range endpoints {
+		if e.Valid() {
+			valid = append(valid, e)
+		}
+	}
+
+	switch format {
+	case OutputNames:
+		for _, e := range valid {
+			if _, err := fmt.Fprintln(w, e.FullName()); err != nil {
+				return err //nolint:wrapcheck
+			}
+		}
+		return nil
+	case OutputTable:
+		return writeTable(w, valid, fields)
+	case OutputYAML:
+		return writeYAML(w, valid, fields)
+	default:
+		return writeJSON(w, valid, fields)
+	}
+}
+
+// writeYAML writes the endpoints as a YAML sequence of mappings, keeping the
+// fields order.
+func writeYAML(w io.Writer, endpoints []*Endpoint, fields []string) error {
+	nodes := &yaml.Node{Kind: yaml.SequenceNode}
+	for _, e := range endpoints {
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		for _, field := range fields {
+			var value yaml.Node
+			if err := value.Encode(outputValue(e, field)); err != nil {
+				return fmt.Errorf("failed to encode %q: %w", field, err)
+			}
+			node.Content = append(
+				node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Value: field},
+				&value,
+			)
+		}
+		nodes.Content = append(nodes.Content, node)
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2) //nolint:mnd
+	if err := enc.Encode(nodes); err != nil {
+		return fmt.Errorf("failed to encode endpoints: %w", err)
+	}
+	return enc.Close() //nolint:wrapcheck
+}
+
+// writeJSON writes the endpoints as a JSON array of objects, keeping the
+// fields order.
+func writeJSON(w io.Writer, endpoints []*Endpoint, fields []string) error {
+	objects := make([]json.RawMessage, 0, len(endpoints))
+	for _, e := range endpoints {
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, field := range fields {
+			value, err := json.Marshal(outputValue(e, field))
+			if err != nil {
+				return fmt.Errorf("failed to encode %q: %w", field, err)
+			}
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			fmt.Fprintf(&buf, "%q:%s", field, value)
+		}
+		buf.WriteByte('}')
+		objects = append(objects, buf.Bytes())
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(objects) //nolint:wrapcheck
+}
+
+// writeTable writes the endpoints as a table with a header.
+func writeTable(w io.Writer, endpoints []*Endpoint, fields []string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0) //nolint:mnd
+	header := make([]string, 0, len(fields))
+	for _, field := range fields {
+		header = append(header, strings.ToUpper(field))
+	}
+	if _, err := fmt.Fprintln(tw, strings.Join(header, "\t")); err != nil {
+		return err //nolint:wrapcheck
+	}
+	for _, e := range endpoints {
+		row := make([]string, 0, len(fields))
+		for _, field := range fields {
+			var s string
+			switch v := outputValue(e, field).(type) {
+			case []string:
+				s = strings.Join(v, ",")
+			case map[string]string:
+				s = v["url"]
+			default:
+				s = fmt.Sprint(v)
+			}
+			// keep a row per endpoint, e.g. with multi-line descriptions.
+			s, _, _ = strings.Cut(s, "\n")
+			row = append(row, FirstNonEmpty(s, "-"))
+		}
+		if _, err := fmt.Fprintln(tw, strings.Join(row, "\t")); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+	return tw.Flush() //nolint:wrapcheck
+}
+
+// outputValue returns the value of the given field of the endpoint.
+func outputValue(e *Endpoint, field string) any {
+	switch field {
+	case OutputFieldName:
+		return e.Name
+	case OutputFieldFullName:
+		return e.FullName()
+	case OutputFieldGroup:
+		return CleanGroup(e.Group)
+	case OutputFieldAddress:
+		return e.Address
+	case OutputFieldHost:
+		host, _ := splitAddress(e.Address)
+		return host
+	case OutputFieldPort:
+		_, port := splitAddress(e.Address)
+		return port
+	case OutputFieldUser:
+		return e.User
+	case OutputFieldDescription:
+		return e.Desc
+	case OutputFieldLink:
+		return map[string]string{"name": e.Link.Name, "url": e.Link.URL}
+	case OutputFieldTags:
+		if e.Tags == nil {
+			return []string{}
+		}
+		return e.Tags
+	case OutputFieldProxyJump:
+		return e.ProxyJump
+	case OutputFieldRemoteCommand:
+		return e.RemoteCommand
+	case OutputFieldSSHURL:
+		return sshURL(e)
+	}
+	return nil
+}
+
+// parseListArgs parses the arguments of the list command over SSH, e.g.
+// `list --json --fields name,address`.
+// It returns the output format and fields.
+func parseListArgs(args []string, stderr io.Writer) (string, []string, error) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	output := fs.String("output", OutputTable, "Output format: "+strings.Join(OutputFormats, ", "))
+	fs.StringVar(output, "o", OutputTable, "Shorthand for --output")
+	asJSON := fs.Bool("json", false, "Shorthand for --output json")
+	fields := fs.String("fields", "", "Comma-separated fields to output: "+strings.Join(OutputFields, ", "))
+	if err := fs.Parse(args); err != nil {
+		return "", nil, err //nolint:wrapcheck
+	}
+	if fs.NArg() > 0 {
+		return "", nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if *asJSON {
+		*output = OutputJSON
+	}
+	return *output, splitFields(*fields), nil
+}
+
+// splitFields splits a comma-separated list of fields.
+func splitFields(s string) []string {
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
@@ -0,0 +1,133 @@
+package wishlist
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteEndpoints(t *testing.T) {
+	endpoints := []*Endpoint{
+		{
+			Name:    "db1",
+			Group:   "prod",
+			Address: "db1.local:2222",
+			User:    "app",
+			Desc:    "The database.\nDon't drop it.",
+			Link:    Link{Name: "Dashboard", URL: "https://db1"},
+			Tags:    []string{"prod", "db"},
+		},
+		{Name: "web1", Address: "web1.local:22"},
+		{Name: "invalid"},
+	}
+	write := func(t *testing.T, format string, fields ...string) string {
+		t.Helper()
+		var buf bytes.Buffer
+		require.NoError(t, WriteEndpoints(&buf, endpoints, format, fields...))
+		return buf.String()
+	}
+
+	t.Run("names", func(t *testing.T) {
+		require.Equal(t, "prod/db1\nweb1\n", write(t, OutputNames))
+	})
+
+	t.Run("table", func(t *testing.T) {
+		require.Equal(t, ""+
+			"FULL_NAME  ADDRESS         USER  TAGS\n"+
+			"prod/db1   db1.local:2222  app   prod,db\n"+
+			"web1       web1.local:22   -     -\n",
+			write(t, OutputTable))
+		require.Equal(t, ""+
+			"NAME  DESCRIPTION    LINK\n"+
+			"db1   The database.  https://db1\n"+
+			"web1  -              -\n",
+			write(t, OutputTable, "name", "description", "link"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		require.JSONEq(t, `[
+			{"name": "db1", "host": "db1.local", "port": "2222", "tags": ["prod", "db"], "link": {"name": "Dashboard", "url": "https://db1"}},
+			{"name": "web1", "host": "web1.local", "port": "22", "tags": [], "link": {"name": "", "url": ""}}
+		]`, write(t, OutputJSON, "name", "host", "port", "tags", "link"))
+
+		// fields keep their order.
+		require.Equal(t, "[\n  {\n    \"ssh_url\": \"ssh://web1.local:22\",\n    \"name\": \"web1\"\n  }\n]\n", func() string {
+			var buf bytes.Buffer
+			require.NoError(t, WriteEndpoints(&buf, endpoints[1:2], OutputJSON, "ssh_url", "name"))
+			return buf.String()
+		}())
+	})
+
+	t.Run("yaml", func(t *testing.T) {
+		require.Equal(t, ""+
+			"- full_name: prod/db1\n"+
+			"  description: |-\n"+
+			"    The database.\n"+
+			"    Don't drop it.\n"+
+			"  tags:\n"+
+			"    - prod\n"+
+			"    - db\n"+
+			"- full_name: web1\n"+
+			"  description: \"\"\n"+
+			"  tags: []\n",
+			write(t, OutputYAML, "full_name", "description", "tags"))
+	})
+
+	t.Run("all fields", func(t *testing.T) {
+		out := write(t, OutputJSON)
+		for _, field := range OutputFields {
+			require.Contains(t, out, `"`+field+`"`)
+		}
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		require.EqualError(
+			t,
+			WriteEndpoints(io.Discard, endpoints, "xml"),
+			`invalid output format "xml", valid formats are json, yaml, table, names`,
+		)
+	})
+
+	t.Run("invalid field", func(t *testing.T) {
+		require.ErrorContains(
+			t,
+			WriteEndpoints(io.Discard, endpoints, OutputJSON, "name", "password"),
+			`invalid field "password", valid fields are name, full_name,`,
+		)
+	})
+}
+
+func TestParseListArgs(t *testing.T) {
+	for name, tc := range map[string]struct {
+		args   []string
+		format string
+		fields []string
+	}{
+		"defaults":    {nil, OutputTable, nil},
+		"json":        {[]string{"--json"}, OutputJSON, nil},
+		"output":      {[]string{"--output", "yaml"}, OutputYAML, nil},
+		"shorthand":   {[]string{"-o=names"}, OutputNames, nil},
+		"fields":      {[]string{"--json", "--fields", "name, address,,tags"}, OutputJSON, []string{"name", "address", "tags"}},
+		"json wins":   {[]string{"-o", "table", "--json"}, OutputJSON, nil},
+		"single dash": {[]string{"-json"}, OutputJSON, nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			format, fields, err := parseListArgs(tc.args, io.Discard)
+			require.NoError(t, err)
+			require.Equal(t, tc.format, format)
+			require.Equal(t, tc.fields, fields)
+		})
+	}
+
+	t.Run("unknown flag", func(t *testing.T) {
+		_, _, err := parseListArgs([]string{"--xml"}, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("extra args", func(t *testing.T) {
+		_, _, err := parseListArgs([]string{"--json", "db1"}, io.Discard)
+		require.EqualError(t, err, "unexpected arguments: db1")
+	})
+}
@@ -0,0 +1,260 @@
+package wishlist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"maps"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+)
+
+const (
+	defaultProbeInterval = 30 * time.Second
+	defaultProbeTimeout  = 5 * time.Second
+
+	// maximum number of endpoints probed at the same time.
+	probeConcurrency = 16
+
+	// maximum number of lines a server may send before its SSH version.
+	maxPreambleLines = 16
+)
+
+// ProbeStatus is the reachability status of an endpoint.
+type ProbeStatus int
+
+// Probe statuses.
+const (
+	ProbeUnknown ProbeStatus = iota
+	ProbeUp
+	ProbeDown
+)
+
+func (s ProbeStatus) String() string {
+	switch s {
+	case ProbeUp:
+		return "up"
+	case ProbeDown:
+		return "down"
+	default:
+		return "unknown"
+	}
+}
+
+// ProbeResult is the result of probing an endpoint.
+type ProbeResult struct {
+	Status ProbeStatus
+	// RTT is the time it took to establish the connection.
+	RTT time.Duration
+	// Via is the address of the first ProxyJump hop, which is probed
+	// instead of the endpoint itself. The endpoint is then down if the hop
+	// is, and unknown otherwise, as it can't be reached without
+	// authenticating to the hop.
+	Via string
+	Err error
+}
+
+// ProbeResults are probe results by endpoint full name.
+type ProbeResults map[string]ProbeResult
+
+// ProbeResultsMsg can be used to update the probe results shown in the
+// listing.
+type ProbeResultsMsg struct {
+	Results ProbeResults
+}
+
+// Prober periodically probes endpoints to check whether they are reachable.
+// A single prober can be shared by many listings.
+type Prober struct {
+	interval time.Duration
+	timeout  time.Duration
+	ssh      bool
+
+	mu        sync.RWMutex
+	endpoints []*Endpoint
+	results   ProbeResults
+}
+
+// NewProber creates a new prober with the given configuration.
+func NewProber(config Probe) *Prober {
+	interval := config.Interval
+	if interval <= 0 {
+		interval = defaultProbeInterval
+	}
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	return &Prober{
+		interval: interval,
+		timeout:  timeout,
+		ssh:      config.SSH,
+		results:  ProbeResults{},
+	}
+}
+
+// SetEndpoints sets the endpoints to probe from the next round on.
+func (p *Prober) SetEndpoints(endpoints []*Endpoint) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.endpoints = endpoints
+}
+
+// Results returns a copy of the latest results.
+func (p *Prober) Results() ProbeResults {
+	if p == nil {
+		return nil
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return maps.Clone(p.results)
+}
+
+// Run probes all endpoints, and then again every interval, calling notify
+// with all the results after each round. It blocks until the context is done.
+func (p *Prober) Run(ctx context.Context, notify func(ProbeResults)) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.probeAll(ctx)
+		notify(p.Results())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// probeAll probes all valid endpoints concurrently, and replaces the results.
+func (p *Prober) probeAll(ctx context.Context) {
+	p.mu.RLock()
+	endpoints := p.endpoints
+	p.mu.RUnlock()
+
+	results := ProbeResults{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, probeConcurrency)
+	for _, e := range endpoints {
+		if !e.Valid() {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			result := probe(ctx, e, endpoints, p.timeout, p.ssh)
+			log.Debug("probed", "endpoint", e.FullName(), "status", result.Status, "rtt", result.RTT, "err", result.Err)
+			mu.Lock()
+			defer mu.Unlock()
+			results[e.FullName()] = result
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.results = results
+}
+
+// probe dials the endpoint address, or its first ProxyJump hop, optionally
+// exchanging SSH versions with it.
+// The endpoints are used to resolve ProxyJump hops by name.
+// The status is unknown if it would be dialed through a ProxyCommand, or if
+// it's behind a ProxyJump hop which is up.
+func probe(ctx context.Context, e *Endpoint, endpoints []*Endpoint, timeout time.Duration, sshCheck bool) ProbeResult {
+	var result ProbeResult
+	addr := e.Address
+	hops, err := resolveJumps(e, endpoints)
+	if err != nil {
+		result.Status = ProbeDown
+		result.Err = err
+		return result
+	}
+	first := e
+	if len(hops) > 0 {
+		first = hops[0]
+		addr = first.Address
+		result.Via = addr
+	}
+	if proxyCommand(first) != "" {
+		// running the ProxyCommand just to probe might have side effects.
+		return result
+	}
+
+	start := time.Now()
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		result.Status = ProbeDown
+		result.Err = fmt.Errorf("could not connect to %s: %w", addr, err)
+		return result
+	}
+	defer conn.Close() //nolint:errcheck
+	result.RTT = time.Since(start)
+
+	if sshCheck {
+		_ = conn.SetDeadline(time.Now().Add(timeout))
+		if err := exchangeVersions(conn); err != nil {
+			result.Status = ProbeDown
+			result.Err = fmt.Errorf("could not exchange ssh versions with %s: %w", addr, err)
+			return result
+		}
+	}
+
+	result.Status = ProbeUp
+	if result.Via != "" {
+		result.Status = ProbeUnknown
+	}
+	return result
+}
+
+// exchangeVersions sends the client version and reads the server's, as
+// defined in RFC 4253, section 4.2.
+func exchangeVersions(conn net.Conn) error {
+	if _, err := fmt.Fprint(conn, "SSH-2.0-wishlist\r\n"); err != nil {
+		return fmt.Errorf("could not send version: %w", err)
+	}
+	r := bufio.NewReader(conn)
+	for range maxPreambleLines {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return fmt.Errorf("could not read version: %w", err)
+		}
+		if strings.HasPrefix(line, "SSH-") {
+			return nil
+		}
+	}
+	return fmt.Errorf("server did not send its version")
+}
+
+// probeStatus returns the status and RTT to show next to an item title.
+func probeStatus(r *ProbeResult, styles styles) string {
+	switch {
+	case r == nil:
+		return ""
+	case r.Status == ProbeUp:
+		rtt := "<1ms"
+		if r.RTT >= time.Millisecond {
+			rtt = r.RTT.Round(time.Millisecond).String()
+		}
+		return styles.Success.Render(probeMark) + " " + rtt
+	case r.Status == ProbeDown:
+		return styles.Err.Render(probeMark)
+	case r.Via != "":
+		return styles.NoContent.Render(probeMark + " via " + r.Via)
+	default:
+		return styles.NoContent.Render(probeMark)
+	}
+}
+
+const probeMark = "●"
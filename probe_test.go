@@ -0,0 +1,154 @@
+package wishlist
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// listen starts a TCP server that writes the given banner to every
+// connection.
+func listen(tb testing.TB, banner string) string {
+	tb.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = fmt.Fprint(conn, banner)
+			_ = conn.Close()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// closedAddr returns an address nothing is listening on.
+func closedAddr(tb testing.TB) string {
+	tb.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	addr := ln.Addr().String()
+	require.NoError(tb, ln.Close())
+	return addr
+}
+
+func TestProbe(t *testing.T) {
+	sshAddr := listen(t, "a preamble\r\nSSH-2.0-OpenSSH_9.6\r\n")
+	httpAddr := listen(t, "HTTP/1.1 400 Bad Request\r\n")
+	down := closedAddr(t)
+	ctx := context.Background()
+
+	t.Run("up", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{Address: sshAddr}, nil, time.Second, false)
+		require.Equal(t, ProbeUp, result.Status)
+		require.NoError(t, result.Err)
+		require.Positive(t, result.RTT)
+	})
+
+	t.Run("down", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{Address: down}, nil, time.Second, false)
+		require.Equal(t, ProbeDown, result.Status)
+		require.Error(t, result.Err)
+	})
+
+	t.Run("ssh", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{Address: sshAddr}, nil, time.Second, true)
+		require.Equal(t, ProbeUp, result.Status)
+		require.NoError(t, result.Err)
+	})
+
+	t.Run("not ssh", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{Address: httpAddr}, nil, time.Second, true)
+		require.Equal(t, ProbeDown, result.Status)
+		require.Error(t, result.Err)
+	})
+
+	t.Run("proxy jump", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{
+			Address:   down,
+			ProxyJump: "user@" + sshAddr + ",other:22",
+		}, nil, time.Second, false)
+		require.Equal(t, ProbeUnknown, result.Status)
+		require.Equal(t, sshAddr, result.Via)
+		require.Positive(t, result.RTT)
+	})
+
+	t.Run("proxy jump endpoint", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{
+			Address:   down,
+			ProxyJump: "bastion",
+		}, []*Endpoint{{Name: "bastion", Address: sshAddr}}, time.Second, false)
+		require.Equal(t, ProbeUnknown, result.Status)
+		require.Equal(t, sshAddr, result.Via)
+	})
+
+	t.Run("proxy jump down", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{
+			Address:   sshAddr,
+			ProxyJump: "bastion",
+		}, []*Endpoint{{Name: "bastion", Address: down}}, time.Second, false)
+		require.Equal(t, ProbeDown, result.Status)
+		require.Equal(t, down, result.Via)
+		require.Error(t, result.Err)
+	})
+
+	t.Run("proxy command", func(t *testing.T) {
+		result := probe(ctx, &Endpoint{
+			Address:      sshAddr,
+			ProxyCommand: "nc %h %p",
+		}, nil, time.Second, false)
+		require.Equal(t, ProbeUnknown, result.Status)
+		require.NoError(t, result.Err)
+	})
+}
+
+func TestProber(t *testing.T) {
+	up := &Endpoint{Name: "up", Group: "g", Address: listen(t, "")}
+	down := &Endpoint{Name: "down", Address: closedAddr(t)}
+
+	prober := NewProber(Probe{Interval: time.Hour, Timeout: time.Second})
+	prober.SetEndpoints([]*Endpoint{up, down, {}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ch := make(chan ProbeResults, 1)
+	go prober.Run(ctx, func(results ProbeResults) { ch <- results })
+
+	results := <-ch
+	require.Len(t, results, 2)
+	require.Equal(t, ProbeUp, results["g/up"].Status)
+	require.Equal(t, ProbeDown, results["down"].Status)
+	require.Equal(t, results, prober.Results())
+
+	t.Run("nil", func(t *testing.T) {
+		var prober *Prober
+		prober.SetEndpoints([]*Endpoint{up})
+		require.Nil(t, prober.Results())
+	})
+}
+
+func TestProbeResultsMsg(t *testing.T) {
+	a := &Endpoint{Name: "a", Address: "a:22"}
+	b := &Endpoint{Name: "b", Address: "b:22"}
+	c := &Endpoint{Name: "c", Address: "c:22", ProxyJump: "bastion"}
+	m := NewListing([]*Endpoint{a, b, c}, fakeClient{}, testRenderer)
+	m.list.Select(1)
+
+	_, _ = m.Update(ProbeResultsMsg{Results: ProbeResults{
+		"a": {Status: ProbeDown},
+		"b": {Status: ProbeUp, RTT: 12 * time.Millisecond},
+		"c": {Status: ProbeUnknown, Via: "bastion:22"},
+	}})
+	require.Equal(t, b, m.selected().endpoint)
+	require.Equal(t, "a "+probeMark, m.list.Items()[0].(ItemWrapper).Title())
+	require.Equal(t, "b "+probeMark+" 12ms", m.list.Items()[1].(ItemWrapper).Title())
+	require.Equal(t, "c "+probeMark+" via bastion:22", m.list.Items()[2].(ItemWrapper).Title())
+}
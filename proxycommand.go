@@ -0,0 +1,198 @@
+package wishlist
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// errProxyCommandDisabled is returned when connecting to an endpoint with a
+// ProxyCommand in server mode, unless allowed in the config.
+var errProxyCommandDisabled = errors.New("ProxyCommand is disabled in server mode, set allow_proxy_command to enable it")
+
+// proxyCommand returns the ProxyCommand of the endpoint, if any.
+// `none` disables it, as in OpenSSH.
+func proxyCommand(e *Endpoint) string {
+	cmd := strings.TrimSpace(e.ProxyCommand)
+	if strings.EqualFold(cmd, "none") {
+		return ""
+	}
+	return cmd
+}
+
+// checkProxyCommand returns errProxyCommandDisabled if the endpoint would be
+// connected to through a ProxyCommand, and that isn't allowed.
+// Only the endpoint, or its first hop, if it has any, are connected to
+// directly.
+func checkProxyCommand(e *Endpoint, hops []jumpHop, allowed bool) error {
+	if allowed {
+		return nil
+	}
+	if len(hops) > 0 {
+		e = hops[0].endpoint
+	}
+	if proxyCommand(e) != "" {
+		return errProxyCommandDisabled
+	}
+	return nil
+}
+
+// ValidateProxyCommand returns an error if the ProxyCommand has unknown
+// tokens.
+func ValidateProxyCommand(cmd string) error {
+	_, err := expandProxyCommand(cmd, "", "", "", "")
+	return err
+}
+
+// expandProxyCommand expands the tokens in the ProxyCommand, as OpenSSH does:
+//   - %h: the host to connect to;
+//   - %p: the port to connect to;
+//   - %r: the remote user;
+//   - %n: the endpoint name;
+//   - %%: a literal `%`.
+//
+// Expanded values are quoted if needed, as the user might come from the SSH
+// login in server mode, and the command is run in a shell.
+func expandProxyCommand(cmd, name, host, port, user string) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < len(cmd); i++ {
+		if cmd[i] != '%' {
+			sb.WriteByte(cmd[i])
+			continue
+		}
+		i++
+		if i == len(cmd) {
+			return "", fmt.Errorf("invalid ProxyCommand %q: trailing %%", cmd)
+		}
+		switch cmd[i] {
+		case 'h':
+			sb.WriteString(shellQuote(host))
+		case 'p':
+			sb.WriteString(shellQuote(port))
+		case 'r':
+			sb.WriteString(shellQuote(user))
+		case 'n':
+			sb.WriteString(shellQuote(name))
+		case '%':
+			sb.WriteByte('%')
+		default:
+			return "", fmt.Errorf("invalid ProxyCommand %q: unknown token %%%c", cmd, cmd[i])
+		}
+	}
+	return sb.String(), nil
+}
+
+// dialProxyCommand connects to the endpoint through its ProxyCommand, which
+// is run in a shell, with the SSH connection going through its stdin and
+// stdout.
+func dialProxyCommand(e *Endpoint, conf *gossh.ClientConfig) (*gossh.Client, error) {
+	host, port, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", e.Address, err)
+	}
+	command, err := expandProxyCommand(proxyCommand(e), e.Name, host, port, conf.User)
+	if err != nil {
+		return nil, err
+	}
+
+	shell := FirstNonEmpty(os.Getenv("SHELL"), "/bin/sh")
+	log.Info("connecting using ProxyCommand", "command", command)
+	cmd := exec.Command(shell, "-c", command) //nolint:gosec
+	cmd.Stderr = log.StandardLog(log.StandardLogOptions{ForceLevel: log.WarnLevel}).Writer()
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ProxyCommand %q: %w", command, err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run ProxyCommand %q: %w", command, err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to run ProxyCommand %q: %w", command, err)
+	}
+
+	conn := &proxyCommandConn{
+		Reader: stdout,
+		stdin:  stdin,
+		cmd:    cmd,
+		addr:   e.Address,
+	}
+	client, err := newClientConn(conn, e.Address, conf)
+	if err != nil {
+		return nil, fmt.Errorf("connection through ProxyCommand %q failed: %w", command, err)
+	}
+	return client, nil
+}
+
+// newClientConn does the SSH handshake over the given connection.
+// The config timeout applies to the handshake, as it does to gossh.Dial.
+func newClientConn(conn net.Conn, addr string, conf *gossh.ClientConfig) (*gossh.Client, error) {
+	// not all connections support deadlines, so the connection is closed
+	// instead.
+	var timer *time.Timer
+	if conf.Timeout > 0 {
+		timer = time.AfterFunc(conf.Timeout, func() { _ = conn.Close() })
+	}
+
+	ncc, chans, reqs, err := gossh.NewClientConn(conn, addr, conf)
+	if timer != nil && !timer.Stop() {
+		err = fmt.Errorf("timed out after %s", conf.Timeout)
+	}
+	if err != nil {
+		_ = conn.Close()
+		return nil, err //nolint:wrapcheck
+	}
+	return gossh.NewClient(ncc, chans, reqs), nil
+}
+
+// proxyCommandConn is a net.Conn over the stdin and stdout of a ProxyCommand.
+type proxyCommandConn struct {
+	io.Reader
+	stdin io.WriteCloser
+	cmd   *exec.Cmd
+	addr  string
+	once  sync.Once
+}
+
+var _ net.Conn = &proxyCommandConn{}
+
+func (c *proxyCommandConn) Write(p []byte) (int, error) {
+	return c.stdin.Write(p) //nolint:wrapcheck
+}
+
+// Close stops the command.
+func (c *proxyCommandConn) Close() error {
+	c.once.Do(func() {
+		_ = c.stdin.Close()
+		_ = c.cmd.Process.Kill()
+		_ = c.cmd.Wait()
+	})
+	return nil
+}
+
+// LocalAddr and RemoteAddr are the endpoint address, as host keys are checked
+// against it.
+func (c *proxyCommandConn) LocalAddr() net.Addr  { return proxyCommandAddr(c.addr) }
+func (c *proxyCommandConn) RemoteAddr() net.Addr { return proxyCommandAddr(c.addr) }
+
+func (c *proxyCommandConn) SetDeadline(time.Time) error      { return errDeadlineUnsupported }
+func (c *proxyCommandConn) SetReadDeadline(time.Time) error  { return errDeadlineUnsupported }
+func (c *proxyCommandConn) SetWriteDeadline(time.Time) error { return errDeadlineUnsupported }
+
+var errDeadlineUnsupported = errors.New("deadlines are not supported by ProxyCommand connections")
+
+// proxyCommandAddr is the address of the endpoint connected to through a
+// ProxyCommand.
+type proxyCommandAddr string
+
+func (a proxyCommandAddr) Network() string { return "proxycommand" }
+func (a proxyCommandAddr) String() string  { return string(a) }
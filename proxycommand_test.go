@@ -0,0 +1,170 @@
+package wishlist
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// proxyCommandHelperEnv makes the test binary act as a ProxyCommand,
+// connecting its stdin and stdout to the address in its arguments.
+const proxyCommandHelperEnv = "WISHLIST_PROXY_COMMAND_HELPER"
+
+func TestProxyCommandHelper(t *testing.T) {
+	if os.Getenv(proxyCommandHelperEnv) == "" {
+		t.Skip("not running as a ProxyCommand")
+	}
+	args := os.Args[len(os.Args)-2:]
+	conn, err := net.Dial("tcp", net.JoinHostPort(args[0], args[1]))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err) //nolint:errcheck
+		os.Exit(1)
+	}
+	go func() {
+		_, _ = io.Copy(conn, os.Stdin)
+		_ = conn.Close()
+	}()
+	_, _ = io.Copy(os.Stdout, conn)
+	os.Exit(0)
+}
+
+// helperProxyCommand returns a ProxyCommand running TestProxyCommandHelper.
+func helperProxyCommand(tb testing.TB) string {
+	tb.Helper()
+	tb.Setenv(proxyCommandHelperEnv, "1")
+	return shellQuote(os.Args[0]) + " -test.run=TestProxyCommandHelper -- %h %p"
+}
+
+func TestExpandProxyCommand(t *testing.T) {
+	for cmd, expected := range map[string]string{
+		"":                         "",
+		"nc %h %p":                 "nc db1.local 2222",
+		"ssh -W %h:%p %r@bastion":  "ssh -W db1.local:2222 app@bastion",
+		"connect --name %n":        "connect --name db1",
+		"echo 100%% %h":            "echo 100% db1.local",
+		"cloudflared access ssh":   "cloudflared access ssh",
+		"%h%p%r%n":                 "db1.local2222appdb1",
+		"printf '%%s' %h | nc - 1": "printf '%s' db1.local | nc - 1",
+	} {
+		t.Run(cmd, func(t *testing.T) {
+			s, err := expandProxyCommand(cmd, "db1", "db1.local", "2222", "app")
+			require.NoError(t, err)
+			require.Equal(t, expected, s)
+		})
+	}
+
+	t.Run("hostile values are quoted", func(t *testing.T) {
+		s, err := expandProxyCommand("echo %r %n %h %p", "db 1", "$(id)", "22`id`", "x;id'")
+		require.NoError(t, err)
+		require.Equal(t, `echo 'x;id'\''' 'db 1' '$(id)' '22`+"`id`'", s)
+		out, err := exec.Command("/bin/sh", "-c", s).Output()
+		require.NoError(t, err)
+		require.Equal(t, "x;id' db 1 $(id) 22`id`\n", string(out))
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		_, err := expandProxyCommand("nc %h %x", "db1", "db1.local", "22", "")
+		require.EqualError(t, err, `invalid ProxyCommand "nc %h %x": unknown token %x`)
+	})
+
+	t.Run("trailing", func(t *testing.T) {
+		require.EqualError(t, ValidateProxyCommand("nc %h %"), `invalid ProxyCommand "nc %h %": trailing %`)
+	})
+}
+
+func TestCheckProxyCommand(t *testing.T) {
+	e := &Endpoint{Name: "db1", ProxyCommand: "nc %h %p"}
+	hop := jumpHop{endpoint: &Endpoint{Name: "bastion", ProxyCommand: "nc %h %p"}}
+
+	require.NoError(t, checkProxyCommand(&Endpoint{Name: "db1"}, nil, false))
+	require.NoError(t, checkProxyCommand(&Endpoint{Name: "db1", ProxyCommand: "none"}, nil, false))
+	require.NoError(t, checkProxyCommand(e, nil, true))
+	require.NoError(t, checkProxyCommand(e, []jumpHop{{endpoint: &Endpoint{Name: "bastion"}}}, false))
+	require.NoError(t, checkProxyCommand(e, []jumpHop{hop}, true))
+	require.ErrorIs(t, checkProxyCommand(e, nil, false), errProxyCommandDisabled)
+	require.ErrorIs(t, checkProxyCommand(&Endpoint{Name: "db1"}, []jumpHop{hop}, false), errProxyCommandDisabled)
+}
+
+func TestDialProxyCommand(t *testing.T) {
+	target := forwardingServer(t, func(s ssh.Session) {
+		_, _ = fmt.Fprint(s, "hello from "+s.User())
+	})
+	conf := func() *gossh.ClientConfig {
+		return &gossh.ClientConfig{
+			User:            "carlos",
+			HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+			Timeout:         time.Second,
+		}
+	}
+
+	t.Run("target", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: target, ProxyCommand: helperProxyCommand(t)}
+		session, _, cl, err := createSession(conf(), e, nil, nil)
+		t.Cleanup(cl.close)
+		require.NoError(t, err)
+		out, err := session.Output("")
+		require.NoError(t, err)
+		require.Equal(t, "hello from carlos", string(out))
+	})
+
+	t.Run("first hop", func(t *testing.T) {
+		hop := forwardingServer(t, func(ssh.Session) {})
+		hops := []jumpHop{{
+			endpoint: &Endpoint{Name: "bastion", Address: hop, ProxyCommand: helperProxyCommand(t)},
+			config:   conf(),
+		}}
+		session, _, cl, err := createSession(conf(), &Endpoint{Name: "target", Address: target}, hops, nil)
+		t.Cleanup(cl.close)
+		require.NoError(t, err)
+		out, err := session.Output("")
+		require.NoError(t, err)
+		require.Equal(t, "hello from carlos", string(out))
+	})
+
+	t.Run("known hosts", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "known_hosts")
+		e := &Endpoint{Name: "target", Address: target, ProxyCommand: helperProxyCommand(t), StrictHostKeyChecking: "accept-new"}
+		c := conf()
+		c.HostKeyCallback = hostKeyCallback(e, knownHosts{user: []string{path}}, nil, nil)
+		client, err := dialProxyCommand(e, c)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = client.Close() })
+
+		// the endpoint address is used, not the command.
+		bts, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.True(t, strings.HasPrefix(string(bts), knownhosts.Normalize(target)+" "), string(bts))
+	})
+
+	t.Run("failed", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: target, ProxyCommand: "exit 1"}
+		_, err := dialProxyCommand(e, conf())
+		require.ErrorContains(t, err, `connection through ProxyCommand "exit 1" failed`)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		e := &Endpoint{Name: "target", Address: target, ProxyCommand: "sleep 10"}
+		c := conf()
+		c.Timeout = 100 * time.Millisecond
+		start := time.Now()
+		_, err := dialProxyCommand(e, c)
+		require.ErrorContains(t, err, "timed out after 100ms")
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := dialProxyCommand(&Endpoint{Name: "target", Address: "target", ProxyCommand: "nc %h %p"}, conf())
+		require.ErrorContains(t, err, `invalid address "target"`)
+	})
+}
@@ -0,0 +1,278 @@
+package wishlist
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/log"
+)
+
+// Recording configuration, for sessions in server mode.
+type Recording struct {
+	// Enabled enables recording the sessions to endpoints.
+	Enabled bool `yaml:"enabled"`
+	// Dir is the directory recordings are written to, as
+	// `<user>/<endpoint>/<timestamp>.cast`. Defaults to .wishlist/recordings.
+	Dir string `yaml:"dir"`
+	// Input also records what users type, which might include passwords.
+	Input bool `yaml:"input"`
+}
+
+// castHeader is the header of an asciicast v2 file.
+// https://docs.asciinema.org/manual/asciicast/v2/
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp,omitempty"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// asciicast event types.
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// defaults of recordings without a PTY.
+const (
+	castWidth  = 80
+	castHeight = 24
+)
+
+// recorder records a session in the asciicast v2 format.
+// A nil recorder records nothing, and recording errors are logged once
+// instead of interrupting the session.
+type recorder struct {
+	mu     sync.Mutex
+	w      io.WriteCloser
+	start  time.Time
+	failed bool
+}
+
+// startRecording starts recording the session of the user to the endpoint,
+// if enabled.
+func startRecording(config Recording, user string, e *Endpoint, term string, width, height int) (*recorder, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+	dir := filepath.Join(
+		FirstNonEmpty(config.Dir, filepath.Join(".wishlist", "recordings")),
+		recordingPathPart(user),
+		recordingPathPart(e.FullName()),
+	)
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("could not create recordings dir: %w", err)
+	}
+	now := time.Now()
+	path := filepath.Join(dir, now.UTC().Format("2006-01-02T15-04-05.000000Z")+".cast")
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600) //nolint:mnd
+	if err != nil {
+		return nil, fmt.Errorf("could not create recording: %w", err)
+	}
+	log.Info("recording session", "user", user, "endpoint", e.Name, "path", path)
+
+	header := castHeader{
+		Version:   2, //nolint:mnd
+		Width:     width,
+		Height:    height,
+		Timestamp: now.Unix(),
+		Title:     user + " → " + e.FullName(),
+	}
+	if term != "" {
+		header.Env = map[string]string{"TERM": term}
+	}
+	rec, err := newRecorder(f, header, now)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return rec, nil
+}
+
+// recordingPathPart returns the string as a single path element, so user and
+// endpoint names can't escape the recordings dir.
+func recordingPathPart(s string) string {
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+	if strings.Trim(s, ".") == "" {
+		return strings.Repeat("_", len(s)+1)
+	}
+	return s
+}
+
+// newRecorder writes the header into w, and returns a recorder writing the
+// events after it, timed from start.
+func newRecorder(w io.WriteCloser, header castHeader, start time.Time) (*recorder, error) {
+	bts, err := json.Marshal(header)
+	if err != nil {
+		return nil, fmt.Errorf("could not write recording: %w", err)
+	}
+	if _, err := w.Write(append(bts, '\n')); err != nil {
+		return nil, fmt.Errorf("could not write recording: %w", err)
+	}
+	return &recorder{w: w, start: start}, nil
+}
+
+// event records an event of the given type, with the given data.
+func (r *recorder) event(kind, data string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failed {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	bts, err := json.Marshal([]any{elapsed, kind, data})
+	if err == nil {
+		_, err = r.w.Write(append(bts, '\n'))
+	}
+	if err != nil {
+		r.failed = true
+		log.Warn("could not record session", "err", err)
+	}
+}
+
+// output returns a writer recording output events.
+func (r *recorder) output() io.Writer {
+	return &castWriter{rec: r, kind: castOutput}
+}
+
+// input returns a writer recording input events.
+func (r *recorder) input() io.Writer {
+	return &castWriter{rec: r, kind: castInput}
+}
+
+// resize records a resize event.
+func (r *recorder) resize(width, height int) {
+	r.event(castResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+// Close stops recording.
+func (r *recorder) Close() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failed = true
+	return r.w.Close() //nolint:wrapcheck
+}
+
+// castWriter records the data written into it as events of its type.
+// Incomplete UTF-8 sequences are kept until the next write, as events are
+// strings.
+type castWriter struct {
+	rec     *recorder
+	kind    string
+	pending []byte
+}
+
+func (w *castWriter) Write(p []byte) (int, error) {
+	data := append(w.pending, p...)
+	n := len(data)
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+	w.pending = append([]byte(nil), data[n:]...)
+	if n > 0 {
+		w.rec.event(w.kind, string(data[:n]))
+	}
+	return len(p), nil
+}
+
+// Replay writes the output of the asciicast recording into w, with the
+// original timing, sped up by the given factor.
+// Pauses are capped to maxWait, if positive.
+func Replay(w io.Writer, r io.Reader, speed float64, maxWait time.Duration) error {
+	return replay(w, r, speed, maxWait, time.Sleep)
+}
+
+func replay(w io.Writer, r io.Reader, speed float64, maxWait time.Duration, sleep func(time.Duration)) error {
+	if speed <= 0 {
+		return fmt.Errorf("invalid speed: %v", speed)
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024) //nolint:mnd
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return fmt.Errorf("could not read recording: %w", err)
+		}
+		return errors.New("empty recording")
+	}
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return fmt.Errorf("invalid recording header: %w", err)
+	}
+	if header.Version != 2 { //nolint:mnd
+		return fmt.Errorf("unsupported recording version: %d", header.Version)
+	}
+
+	var last float64
+	for line := 2; scanner.Scan(); line++ {
+		var event []any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return fmt.Errorf("invalid recording event on line %d: %w", line, err)
+		}
+		at, ok1 := eventField[float64](event, 0)
+		kind, ok2 := eventField[string](event, 1)
+		data, ok3 := eventField[string](event, 2) //nolint:mnd
+		if !ok1 || !ok2 || !ok3 {
+			return fmt.Errorf("invalid recording event on line %d", line)
+		}
+		if kind != castOutput {
+			continue
+		}
+
+		wait := time.Duration((at - last) / speed * float64(time.Second))
+		if maxWait > 0 && wait > maxWait {
+			wait = maxWait
+		}
+		if wait > 0 {
+			sleep(wait)
+		}
+		last = at
+		if _, err := io.WriteString(w, data); err != nil {
+			return fmt.Errorf("could not replay recording: %w", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("could not read recording: %w", err)
+	}
+	return nil
+}
+
+// eventField returns the field of an asciicast event at the given index.
+func eventField[T any](event []any, i int) (T, bool) {
+	var zero T
+	if i >= len(event) {
+		return zero, false
+	}
+	v, ok := event[i].(T)
+	return v, ok
+}
@@ -0,0 +1,166 @@
+package wishlist
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestRecorder(t *testing.T) {
+	var buf bytes.Buffer
+	rec, err := newRecorder(nopWriteCloser{&buf}, castHeader{Version: 2, Width: 80, Height: 24}, time.Now())
+	require.NoError(t, err)
+
+	out := rec.output()
+	_, err = out.Write([]byte("hello \xe2\x98"))
+	require.NoError(t, err)
+	_, err = out.Write([]byte("\x83!"))
+	require.NoError(t, err)
+	_, err = rec.input().Write([]byte("ls\r"))
+	require.NoError(t, err)
+	rec.resize(120, 40)
+	require.NoError(t, rec.Close())
+	rec.resize(1, 1) // ignored after closing
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	require.JSONEq(t, `{"version":2,"width":80,"height":24}`, lines[0])
+	for i, expected := range [][2]string{
+		{castOutput, "hello "},
+		{castOutput, "☃!"},
+		{castInput, "ls\r"},
+		{castResize, "120x40"},
+	} {
+		var event []any
+		require.NoError(t, json.Unmarshal([]byte(lines[i+1]), &event))
+		require.Len(t, event, 3)
+		require.IsType(t, float64(0), event[0])
+		require.Equal(t, expected[0], event[1])
+		require.Equal(t, expected[1], event[2])
+	}
+}
+
+func TestNilRecorder(t *testing.T) {
+	var rec *recorder
+	_, err := rec.output().Write([]byte("hello"))
+	require.NoError(t, err)
+	rec.resize(80, 24)
+	require.NoError(t, rec.Close())
+}
+
+func TestStartRecording(t *testing.T) {
+	e := &Endpoint{Name: "db1", Group: "prod"}
+
+	t.Run("disabled", func(t *testing.T) {
+		rec, err := startRecording(Recording{Dir: t.TempDir()}, "carlos", e, "xterm", 80, 24)
+		require.NoError(t, err)
+		require.Nil(t, rec)
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		dir := t.TempDir()
+		rec, err := startRecording(Recording{Enabled: true, Dir: dir}, "../carlos", e, "xterm", 100, 30)
+		require.NoError(t, err)
+		_, _ = rec.output().Write([]byte("hello"))
+		require.NoError(t, rec.Close())
+
+		files, err := filepath.Glob(filepath.Join(dir, ".._carlos", "prod_db1", "*.cast"))
+		require.NoError(t, err)
+		require.Len(t, files, 1)
+		info, err := os.Stat(files[0])
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+		f, err := os.Open(files[0])
+		require.NoError(t, err)
+		defer f.Close() //nolint:errcheck
+		var header castHeader
+		require.NoError(t, json.NewDecoder(f).Decode(&header))
+		require.Equal(t, 2, header.Version)
+		require.Equal(t, 100, header.Width)
+		require.Equal(t, 30, header.Height)
+		require.Equal(t, "../carlos → prod/db1", header.Title)
+		require.Equal(t, map[string]string{"TERM": "xterm"}, header.Env)
+	})
+}
+
+func TestRecordingPathPart(t *testing.T) {
+	for s, expected := range map[string]string{
+		"carlos":     "carlos",
+		"prod/db1":   "prod_db1",
+		"..":         "___",
+		".":          "__",
+		"":           "_",
+		"a b\\c":     "a_b_c",
+		"db1.local":  "db1.local",
+		"user@host!": "user_host_",
+	} {
+		t.Run(s, func(t *testing.T) {
+			require.Equal(t, expected, recordingPathPart(s))
+		})
+	}
+}
+
+func TestReplay(t *testing.T) {
+	recording := `{"version":2,"width":80,"height":24}
+[0.5,"o","hello"]
+[1.0,"i","ls\r"]
+[1.5,"r","100x30"]
+[4.5,"o"," world"]
+`
+	replayed := func(t *testing.T, speed float64, maxWait time.Duration) (string, []time.Duration) {
+		t.Helper()
+		var out bytes.Buffer
+		var waits []time.Duration
+		require.NoError(t, replay(&out, strings.NewReader(recording), speed, maxWait, func(d time.Duration) {
+			waits = append(waits, d)
+		}))
+		return out.String(), waits
+	}
+
+	t.Run("original speed", func(t *testing.T) {
+		out, waits := replayed(t, 1, 0)
+		require.Equal(t, "hello world", out)
+		require.Equal(t, []time.Duration{500 * time.Millisecond, 4 * time.Second}, waits)
+	})
+
+	t.Run("faster", func(t *testing.T) {
+		_, waits := replayed(t, 2, 0)
+		require.Equal(t, []time.Duration{250 * time.Millisecond, 2 * time.Second}, waits)
+	})
+
+	t.Run("max wait", func(t *testing.T) {
+		_, waits := replayed(t, 1, time.Second)
+		require.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, waits)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for name, tc := range map[string]struct {
+			recording string
+			speed     float64
+			err       string
+		}{
+			"invalid speed":   {recording, 0, "invalid speed: 0"},
+			"empty":           {"", 1, "empty recording"},
+			"invalid header":  {"nope\n", 1, "invalid recording header"},
+			"invalid version": {`{"version":1}`, 1, "unsupported recording version: 1"},
+			"invalid event":   {"{\"version\":2}\n[0.1,\"o\"]\n", 1, "invalid recording event on line 2"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				err := replay(io.Discard, strings.NewReader(tc.recording), tc.speed, 0, func(time.Duration) {})
+				require.ErrorContains(t, err, tc.err)
+			})
+		}
+	})
+}
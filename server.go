@@ -7,6 +7,9 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"slices"
+	"sync"
 	"sync/atomic"
 	"syscall"
 	"time"
@@ -17,6 +20,7 @@ import (
 	"github.com/charmbracelet/wish"
 	"github.com/hashicorp/go-multierror"
 	"github.com/teivah/broadcast"
+	gossh "golang.org/x/crypto/ssh"
 )
 
 // Serve serves wishlist with the given config.
@@ -41,24 +45,87 @@ func Serve(config *Config) error {
 		return fmt.Errorf("could not create .wishlist dir: %w", err)
 	}
 
+	states := newStateStore(filepath.Join(".wishlist", "state"))
+
+	audit, err := openAuditLog(config.Audit)
+	if err != nil {
+		return err
+	}
+	defer audit.Close() //nolint:errcheck
+	config.audit = audit
+
+	// keys loaded from authorized_keys files and URLs are refreshed in the
+	// background, keeping the last ones if they fail to load.
+	interval := config.AuthorizedKeysRefresh
+	if interval <= 0 {
+		interval = defaultAuthorizedKeysRefresh
+	}
+	config.keys = newKeyStore(config.Users, config.AuthorizedKeys, interval)
+	if config.keys != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		config.keys.refresh(ctx)
+		go config.keys.run(ctx, interval)
+	}
+
+	// a single prober is shared by all sessions.
+	var prober *Prober
+	probeRelay := broadcast.NewRelay[ProbeResults]()
+	if config.Probe.Enabled {
+		prober = NewProber(config.Probe)
+		prober.SetEndpoints(config.Endpoints)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go prober.Run(ctx, probeRelay.Broadcast)
+	}
+
 	relay := broadcast.NewRelay[[]*Endpoint]()
+	var endpointsMu sync.Mutex
 	if config.EndpointChan != nil {
 		go func() {
 			for endpoints := range config.EndpointChan {
+				endpointsMu.Lock()
 				config.Endpoints = endpoints
+				endpointsMu.Unlock()
+				prober.SetEndpoints(endpoints)
 				relay.Broadcast(endpoints)
 			}
 		}()
 	}
 
+	// endpoints added or edited by admins are written one at a time, and
+	// sent to all sessions.
+	if write := config.EndpointWriter; write != nil {
+		if len(config.Admins) > 0 && !authenticates(config) {
+			log.Warn("admins are ignored as no users, authorized keys nor trusted user CAs are set, so they can't be authenticated")
+		}
+		config.EndpointWriter = func(old, e *Endpoint) error {
+			endpointsMu.Lock()
+			defer endpointsMu.Unlock()
+			if err := write(old, e); err != nil {
+				return err
+			}
+			config.Endpoints = replaceEndpoint(config.Endpoints, old, e)
+			prober.SetEndpoints(config.Endpoints)
+			relay.Broadcast(config.Endpoints)
+			return nil
+		}
+	}
+
+	endpoints := func() []*Endpoint {
+		endpointsMu.Lock()
+		defer endpointsMu.Unlock()
+		return config.Endpoints
+	}
+
 	config.lastPort = config.Port
 	for _, endpoint := range append([]*Endpoint{
 		{
 			Name:    "list",
 			Address: toAddress(config.Listen, config.Port),
 			Middlewares: []wish.Middleware{
-				listingMiddleware(config, relay),
-				cmdsMiddleware(config.Endpoints),
+				listingMiddleware(config, endpoints, relay, prober, probeRelay, states),
+				cmdsMiddleware(config, endpoints, states),
 			},
 		},
 	}, config.Endpoints...) {
@@ -77,8 +144,16 @@ func Serve(config *Config) error {
 			endpoint.Address = toAddress(config.Listen, atomic.AddInt64(&config.lastPort, 1))
 		}
 
+		// the listing also proxies file transfers to the endpoints.
+		var subsystems map[string]ssh.SubsystemHandler
+		if endpoint.Name == "list" {
+			subsystems = map[string]ssh.SubsystemHandler{
+				"sftp": sftpSubsystem(config, endpoints),
+			}
+		}
+
 		// i don't see where closer was declared before, linter bug maybe?
-		closer, err := listenAndServe(config, *endpoint) //nolint:predeclared
+		closer, err := listenAndServe(config, *endpoint, subsystems) //nolint:predeclared
 		if closer != nil {
 			closes = append(closes, closer)
 		}
@@ -94,13 +169,20 @@ func Serve(config *Config) error {
 	return closeAll(closes)
 }
 
-// listenAndServe starts a server for the given endpoint.
-func listenAndServe(config *Config, endpoint Endpoint) (func() error, error) {
+// listenAndServe starts a server for the given endpoint, with the given
+// subsystem handlers, if any.
+func listenAndServe(config *Config, endpoint Endpoint, subsystems map[string]ssh.SubsystemHandler) (func() error, error) {
 	s, err := config.Factory(endpoint)
 	if err != nil {
 		return nil, err
 	}
-	s.PublicKeyHandler = publicKeyAccessOption(config.Users)
+	s.PublicKeyHandler = publicKeyAccessOption(config.Users, config.TrustedUserCAKeys, config.keys, config.audit)
+	for name, handler := range subsystems {
+		if s.SubsystemHandlers == nil {
+			s.SubsystemHandlers = map[string]ssh.SubsystemHandler{}
+		}
+		s.SubsystemHandlers[name] = handler
+	}
 
 	log.Info("Starting SSH server", "endpoint", endpoint.Name, "address", "ssh://"+endpoint.Address)
 	ln, err := net.Listen("tcp", endpoint.Address)
@@ -154,29 +236,83 @@ func getFirstOpenPort(addr string, ports ...int64) (int64, error) {
 	return 0, fmt.Errorf("all ports unavailable")
 }
 
-func publicKeyAccessOption(users []User) ssh.PublicKeyHandler {
-	if len(users) == 0 {
+// isAdmin returns whether the given user can add and edit endpoints.
+// Users are only authenticated if the config has users or trusted user CAs,
+// so admins are ignored otherwise.
+func isAdmin(config *Config, user string) bool {
+	return authenticates(config) && slices.Contains(config.Admins, user)
+}
+
+// authenticates returns whether users are authenticated, either by their keys
+// or by their certificates.
+func authenticates(config *Config) bool {
+	return len(config.Users) > 0 || len(config.TrustedUserCAKeys) > 0 || len(config.AuthorizedKeys) > 0
+}
+
+// publicKeyAccessOption authorizes the keys of the given users, inline or
+// loaded into the key store, and the certificates signed by the given CAs.
+func publicKeyAccessOption(users []User, trustedUserCAKeys []string, keys *keyStore, audit *auditLog) ssh.PublicKeyHandler {
+	if len(users) == 0 && len(trustedUserCAKeys) == 0 && keys == nil {
 		// if no users, assume everyone can login
 		return nil
 	}
 
+	cas, err := parseUserCAKeys(trustedUserCAKeys)
+	if err != nil {
+		log.Warn("ignoring trusted user CA keys", "err", err)
+	}
+	checker := userCertChecker(cas)
+
 	return func(ctx ssh.Context, key ssh.PublicKey) bool {
+		if cert, ok := key.(*gossh.Certificate); ok && checker != nil {
+			if err := checkUserCert(checker, ctx, cert, users); err != nil {
+				log.Warn("denied", "user", ctx.User(), "cert.key_id", cert.KeyId, "err", err)
+				audit.denied(ctx, key, err.Error())
+				return false
+			}
+			log.Info("authorized", "user", ctx.User(), "cert.key_id", cert.KeyId, "cert.serial", cert.Serial)
+			return true
+		}
+
 		for _, user := range users {
 			if user.Name == ctx.User() {
 				for _, pubkey := range user.PublicKeys {
-					upk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(pubkey))
+					upk, _, options, _, err := ssh.ParseAuthorizedKey([]byte(pubkey))
 					if err != nil {
 						log.Warn("invalid key", "user", user.Name, "err", err)
+						audit.denied(ctx, key, "invalid key")
 						return false
 					}
-					if ssh.KeysEqual(upk, key) {
-						log.Info("authorized", "user", ctx.User(), "key", pubkey[:30])
-						return true
+					if !ssh.KeysEqual(upk, key) {
+						continue
+					}
+					ak, err := newAuthorizedKey(upk, options)
+					if err != nil {
+						// as in the sources, keys with unsupported options are ignored.
+						log.Warn("ignoring key", "user", user.Name, "err", err)
+						continue
 					}
+					if err := ak.check(ctx.RemoteAddr(), time.Now()); err != nil {
+						log.Warn("denied", "user", ctx.User(), "key.type", key.Type(), "err", err)
+						audit.denied(ctx, key, err.Error())
+						return false
+					}
+					log.Info("authorized", "user", ctx.User(), "key", pubkey[:30])
+					return true
 				}
 			}
 		}
+		if ok, err := keys.check(ctx, ctx.User(), key, ctx.RemoteAddr()); ok {
+			if err != nil {
+				log.Warn("denied", "user", ctx.User(), "key.type", key.Type(), "err", err)
+				audit.denied(ctx, key, err.Error())
+				return false
+			}
+			log.Info("authorized", "user", ctx.User(), "key.fingerprint", gossh.FingerprintSHA256(key))
+			return true
+		}
 		log.Warn("denied", "user", ctx.User(), "key.type", key.Type())
+		audit.denied(ctx, key, "unauthorized")
 		return false
 	}
 }
@@ -71,7 +71,7 @@ func TestGetFirstOpenPort(t *testing.T) {
 
 func TestPublicKeyHandler(t *testing.T) {
 	t.Run("no users", func(t *testing.T) {
-		require.Nil(t, publicKeyAccessOption([]User{}))
+		require.Nil(t, publicKeyAccessOption([]User{}, nil, nil, nil))
 	})
 
 	t.Run("with users", func(t *testing.T) {
@@ -86,7 +86,7 @@ func TestPublicKeyHandler(t *testing.T) {
 					Name:       "test",
 					PublicKeys: []string{pubkey},
 				},
-			})(fakeCtx{}, k))
+			}, nil, nil, nil)(fakeCtx{}, k))
 		})
 
 		t.Run("unauthorized wrong username", func(t *testing.T) {
@@ -95,7 +95,7 @@ func TestPublicKeyHandler(t *testing.T) {
 					Name:       "not-test",
 					PublicKeys: []string{pubkey},
 				},
-			})(fakeCtx{}, k))
+			}, nil, nil, nil)(fakeCtx{}, k))
 		})
 
 		t.Run("unauthorized wrong key", func(t *testing.T) {
@@ -104,7 +104,7 @@ func TestPublicKeyHandler(t *testing.T) {
 					Name:       "test",
 					PublicKeys: []string{pubkey2},
 				},
-			})(fakeCtx{}, k))
+			}, nil, nil, nil)(fakeCtx{}, k))
 		})
 
 		t.Run("invalid key", func(t *testing.T) {
@@ -113,7 +113,7 @@ func TestPublicKeyHandler(t *testing.T) {
 					Name:       "test",
 					PublicKeys: []string{"giberrish"},
 				},
-			})(fakeCtx{}, k))
+			}, nil, nil, nil)(fakeCtx{}, k))
 		})
 	})
 }
@@ -162,3 +162,15 @@ func portFromAddr(tb testing.TB, addr string) int64 {
 	require.NoError(tb, err)
 	return result
 }
+
+func TestIsAdmin(t *testing.T) {
+	config := &Config{
+		Users:  []User{{Name: "carlos"}, {Name: "andrey"}},
+		Admins: []string{"carlos"},
+	}
+	require.True(t, isAdmin(config, "carlos"))
+	require.False(t, isAdmin(config, "andrey"))
+	require.False(t, isAdmin(&Config{Admins: []string{"carlos"}}, "carlos"))
+	require.True(t, isAdmin(&Config{Admins: []string{"carlos"}, TrustedUserCAKeys: []string{"ssh-ed25519 AAAA"}}, "carlos"))
+	require.True(t, isAdmin(&Config{Admins: []string{"carlos"}, AuthorizedKeys: []string{"/etc/wishlist/keys/%u.pub"}}, "carlos"))
+}
@@ -0,0 +1,486 @@
+package wishlist
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	"github.com/pkg/sftp"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sftpSubsystem handles the sftp subsystem, proxying to the endpoints.
+// Each endpoint is a directory named after it, with its group as parent
+// directories, e.g. `prod/db1/backups` is `backups` in the home directory of
+// `db1`, in the `prod` group.
+// Only the endpoints the user can access are listed.
+func sftpSubsystem(config *Config, endpoints func() []*Endpoint) ssh.SubsystemHandler {
+	return func(s ssh.Session) {
+		methods, cls, err := remoteNonInteractiveAuthMethods(s)
+		defer cls.close()
+		if err != nil {
+			log.Warn("sftp failed", "user", s.User(), "err", err)
+			fmt.Fprintf(s.Stderr(), "wishlist: failed to find an auth method: %v\n\r", err) //nolint:errcheck
+			_ = s.Exit(1)
+			return
+		}
+
+		proxy := &sftpProxy{
+			client: &remoteClient{
+				session:           s,
+				stdin:             s,
+				endpoints:         endpoints,
+				allowProxyCommand: config.AllowProxyCommand,
+				audit:             config.audit,
+			},
+			access:  userAccess(config, s.User()),
+			methods: methods,
+			clients: map[string]*sftp.Client{},
+			audits:  map[string]*auditSession{},
+		}
+		defer proxy.close()
+
+		log.Info("sftp", "user", s.User(), "remote.addr", s.RemoteAddr().String())
+		server := sftp.NewRequestServer(s, sftp.Handlers{
+			FileGet:  proxy,
+			FilePut:  proxy,
+			FileCmd:  proxy,
+			FileList: proxy,
+		})
+		if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+			log.Warn("sftp failed", "user", s.User(), "err", err)
+		}
+		_ = server.Close()
+	}
+}
+
+// sftpProxy implements the sftp handlers, proxying requests to SFTP clients
+// connected to the endpoints as they are needed.
+type sftpProxy struct {
+	client  *remoteClient
+	access  accessRules
+	methods []gossh.AuthMethod
+
+	mu      sync.Mutex
+	clients map[string]*sftp.Client  // by endpoint full name
+	audits  map[string]*auditSession // by endpoint full name
+	cl      closers
+}
+
+var (
+	_ sftp.OpenFileWriter       = &sftpProxy{}
+	_ sftp.FileReader           = &sftpProxy{}
+	_ sftp.PosixRenameFileCmder = &sftpProxy{}
+	_ sftp.LstatFileLister      = &sftpProxy{}
+)
+
+// errSFTPAcrossEndpoints is returned by operations on paths in different
+// endpoints.
+var errSFTPAcrossEndpoints = errors.New("paths are in different endpoints")
+
+func (p *sftpProxy) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, c := range p.clients {
+		_ = c.Close()
+		p.audits[name].end(nil)
+	}
+	p.cl.close()
+}
+
+// endpoints returns the endpoints the user can access.
+func (p *sftpProxy) endpoints() []*Endpoint {
+	return p.access.filter(p.client.allEndpoints())
+}
+
+// resolve returns the endpoint the path is in, along with the path relative
+// to its home directory, or nil if the path is not in any endpoint.
+func (p *sftpProxy) resolve(name string) (*Endpoint, string) {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil, ""
+	}
+	parts := strings.Split(name, "/")
+	endpoints := p.endpoints()
+	for i := 1; i <= len(parts); i++ {
+		if e := FindEndpoint(endpoints, strings.Join(parts[:i], "/")); e != nil {
+			return e, FirstNonEmpty(path.Join(parts[i:]...), ".")
+		}
+	}
+	return nil, ""
+}
+
+// sftp returns the SFTP client of the endpoint the path is in, connecting to
+// it if needed, along with the path in it.
+func (p *sftpProxy) sftp(name string) (*sftp.Client, string, error) {
+	e, rest := p.resolve(name)
+	if e == nil {
+		// the directories of endpoints and groups can't be changed.
+		if _, ok := p.dir(path.Dir(path.Clean("/" + name))); ok {
+			return nil, "", sftp.ErrSSHFxPermissionDenied
+		}
+		return nil, "", os.ErrNotExist
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if c, ok := p.clients[e.FullName()]; ok {
+		return c, rest, nil
+	}
+
+	audit := p.client.audit.session(p.client.session, e, "")
+	c, err := p.connect(e, audit)
+	if err != nil {
+		audit.end(err)
+		return nil, "", err
+	}
+	audit.connected()
+	log.Info("sftp connected", "user", p.client.session.User(), "endpoint", e.Name)
+	p.clients[e.FullName()] = c
+	p.audits[e.FullName()] = audit
+	return c, rest, nil
+}
+
+// connect starts an SFTP client on the endpoint, counting the bytes sent and
+// received in the audit session.
+func (p *sftpProxy) connect(e *Endpoint, audit *auditSession) (*sftp.Client, error) {
+	conf, hops, err := p.client.nonInteractiveConfigs(e, p.methods)
+	if err != nil {
+		return nil, err
+	}
+	client, cl, err := dialEndpoint(conf, e, hops, nil)
+	p.cl = append(p.cl, cl...)
+	if err != nil {
+		log.Warn("sftp connection failed", "user", p.client.session.User(), "endpoint", e.Name, "err", err)
+		return nil, err
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp on %q: %w", e.Name, err)
+	}
+	stdin, err := session.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp on %q: %w", e.Name, err)
+	}
+	stdout, err := session.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start sftp on %q: %w", e.Name, err)
+	}
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to start sftp on %q: %w", e.Name, err)
+	}
+
+	// the client talks to the endpoint through pipes, to count the bytes.
+	inr, inw := io.Pipe()
+	outr, outw := io.Pipe()
+	go func() {
+		_, _ = io.Copy(stdin, audit.input(inr))
+		_ = stdin.Close()
+	}()
+	go func() {
+		_, err := io.Copy(audit.output(outw), stdout)
+		_ = outw.CloseWithError(err)
+		_ = inr.Close()
+	}()
+	c, err := sftp.NewClientPipe(outr, inw)
+	if err != nil {
+		_ = session.Close()
+		return nil, fmt.Errorf("failed to start sftp on %q: %w", e.Name, err)
+	}
+	return c, nil
+}
+
+// sftpPair returns the SFTP client of the endpoint both paths are in, along
+// with the paths in it.
+func (p *sftpProxy) sftpPair(a, b string) (*sftp.Client, string, string, error) {
+	ea, _ := p.resolve(a)
+	eb, _ := p.resolve(b)
+	if ea != eb {
+		return nil, "", "", errSFTPAcrossEndpoints
+	}
+	c, ra, err := p.sftp(a)
+	if err != nil {
+		return nil, "", "", err
+	}
+	_, rb, err := p.sftp(b)
+	return c, ra, rb, err
+}
+
+// dir returns the directories of endpoints and groups in the given
+// directory, if it's not in an endpoint itself.
+func (p *sftpProxy) dir(name string) ([]os.FileInfo, bool) {
+	prefix := strings.Trim(path.Clean("/"+name), "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	found := prefix == ""
+	var entries []os.FileInfo
+	for _, e := range p.endpoints() {
+		child, ok := strings.CutPrefix(e.FullName(), prefix)
+		if !ok || child == "" {
+			continue
+		}
+		found = true
+		child, _, _ = strings.Cut(child, "/")
+		if !slices.ContainsFunc(entries, func(fi os.FileInfo) bool { return fi.Name() == child }) {
+			entries = append(entries, sftpDir(child))
+		}
+	}
+	return entries, found
+}
+
+// Fileread implements sftp.FileReader.
+func (p *sftpProxy) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	c, name, err := p.sftp(r.Filepath)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	f, err := c.Open(name)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	return f, nil
+}
+
+// Filewrite implements sftp.FileWriter.
+func (p *sftpProxy) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	return p.OpenFile(r)
+}
+
+// OpenFile implements sftp.OpenFileWriter.
+func (p *sftpProxy) OpenFile(r *sftp.Request) (sftp.WriterAtReaderAt, error) {
+	c, name, err := p.sftp(r.Filepath)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	f, err := c.OpenFile(name, openFlags(r.Pflags()))
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	return f, nil
+}
+
+// Filecmd implements sftp.FileCmder.
+func (p *sftpProxy) Filecmd(r *sftp.Request) error {
+	switch r.Method {
+	case "Setstat":
+		c, name, err := p.sftp(r.Filepath)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(setstat(c, name, r.AttrFlags(), r.Attributes()))
+	case "Rename":
+		c, from, to, err := p.sftpPair(r.Filepath, r.Target)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.Rename(from, to))
+	case "Link":
+		c, from, to, err := p.sftpPair(r.Filepath, r.Target)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.Link(from, to))
+	case "Symlink":
+		// the target is given as is, as it's in the endpoint.
+		c, link, err := p.sftp(r.Target)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.Symlink(r.Filepath, link))
+	case "Rmdir":
+		c, name, err := p.sftp(r.Filepath)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.RemoveDirectory(name))
+	case "Mkdir":
+		c, name, err := p.sftp(r.Filepath)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.Mkdir(name))
+	case "Remove":
+		c, name, err := p.sftp(r.Filepath)
+		if err != nil {
+			return sftpStatus(err)
+		}
+		return sftpStatus(c.Remove(name))
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// PosixRename implements sftp.PosixRenameFileCmder.
+func (p *sftpProxy) PosixRename(r *sftp.Request) error {
+	c, from, to, err := p.sftpPair(r.Filepath, r.Target)
+	if err != nil {
+		return sftpStatus(err)
+	}
+	return sftpStatus(c.PosixRename(from, to))
+}
+
+// Filelist implements sftp.FileLister.
+func (p *sftpProxy) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	if e, _ := p.resolve(r.Filepath); e == nil {
+		entries, ok := p.dir(r.Filepath)
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		switch r.Method {
+		case "List":
+			return sftpLister(entries), nil
+		case "Stat":
+			return sftpLister{sftpDir(path.Base(r.Filepath))}, nil
+		default:
+			return nil, sftp.ErrSSHFxOpUnsupported
+		}
+	}
+
+	c, name, err := p.sftp(r.Filepath)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	switch r.Method {
+	case "List":
+		entries, err := c.ReadDir(name)
+		if err != nil {
+			return nil, sftpStatus(err)
+		}
+		return sftpLister(entries), nil
+	case "Stat":
+		fi, err := c.Stat(name)
+		if err != nil {
+			return nil, sftpStatus(err)
+		}
+		return sftpLister{fi}, nil
+	case "Readlink":
+		target, err := c.ReadLink(name)
+		if err != nil {
+			return nil, sftpStatus(err)
+		}
+		// only the name is used.
+		return sftpLister{sftpDir(target)}, nil
+	default:
+		return nil, sftp.ErrSSHFxOpUnsupported
+	}
+}
+
+// Lstat implements sftp.LstatFileLister.
+func (p *sftpProxy) Lstat(r *sftp.Request) (sftp.ListerAt, error) {
+	if e, _ := p.resolve(r.Filepath); e == nil {
+		r.Method = "Stat"
+		return p.Filelist(r)
+	}
+	c, name, err := p.sftp(r.Filepath)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	fi, err := c.Lstat(name)
+	if err != nil {
+		return nil, sftpStatus(err)
+	}
+	return sftpLister{fi}, nil
+}
+
+// setstat sets the given attributes of the file.
+func setstat(c *sftp.Client, name string, flags sftp.FileAttrFlags, attrs *sftp.FileStat) error {
+	if flags.Size {
+		if err := c.Truncate(name, int64(attrs.Size)); err != nil { //nolint:gosec
+			return err //nolint:wrapcheck
+		}
+	}
+	if flags.Permissions {
+		if err := c.Chmod(name, attrs.FileMode().Perm()); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+	if flags.UidGid {
+		if err := c.Chown(name, int(attrs.UID), int(attrs.GID)); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+	if flags.Acmodtime {
+		if err := c.Chtimes(name, attrs.AccessTime(), attrs.ModTime()); err != nil {
+			return err //nolint:wrapcheck
+		}
+	}
+	return nil
+}
+
+// openFlags returns the os.OpenFile flags of the given SFTP flags.
+func openFlags(pflags sftp.FileOpenFlags) int {
+	var flags int
+	switch {
+	case pflags.Read && pflags.Write:
+		flags = os.O_RDWR
+	case pflags.Write:
+		flags = os.O_WRONLY
+	default:
+		flags = os.O_RDONLY
+	}
+	if pflags.Append {
+		flags |= os.O_APPEND
+	}
+	if pflags.Creat {
+		flags |= os.O_CREATE
+	}
+	if pflags.Trunc {
+		flags |= os.O_TRUNC
+	}
+	if pflags.Excl {
+		flags |= os.O_EXCL
+	}
+	return flags
+}
+
+// sftpStatus returns the error to reply with, keeping the status code of
+// errors from the endpoints.
+func sftpStatus(err error) error {
+	var serr *sftp.StatusError
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrPermission):
+		return sftp.ErrSSHFxPermissionDenied
+	case errors.As(err, &serr) && serr.FxCode() != sftp.ErrSSHFxFailure:
+		return serr.FxCode()
+	}
+	return err
+}
+
+// sftpLister lists the given files.
+type sftpLister []os.FileInfo
+
+// ListAt implements sftp.ListerAt.
+func (l sftpLister) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(l)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, l[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// sftpDir is a directory of endpoints or groups.
+type sftpDir string
+
+var _ os.FileInfo = sftpDir("")
+
+func (d sftpDir) Name() string       { return string(d) }
+func (d sftpDir) Size() int64        { return 0 }
+func (d sftpDir) Mode() os.FileMode  { return os.ModeDir | 0o555 } //nolint:mnd
+func (d sftpDir) ModTime() time.Time { return time.Time{} }
+func (d sftpDir) IsDir() bool        { return true }
+func (d sftpDir) Sys() any           { return nil }
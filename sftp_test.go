@@ -0,0 +1,153 @@
+package wishlist
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSFTPSubsystem(t *testing.T) {
+	t.Chdir(t.TempDir()) // the client key is written in the working directory
+
+	app1, db1 := t.TempDir(), t.TempDir()
+	endpoints := []*Endpoint{
+		{Name: "app1", Address: sftpServer(t, app1)},
+		{Name: "db1", Group: "prod", Address: sftpServer(t, db1)},
+		{Name: "proxied", Address: sftpServer(t, app1), ProxyCommand: "nc %h %p"},
+	}
+	srv := &ssh.Server{
+		Handler: func(ssh.Session) {},
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{
+			"sftp": sftpSubsystem(&Config{}, func() []*Endpoint { return endpoints }),
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	sc := sftpClient(t, l.Addr().String())
+
+	names := func(t *testing.T, dir string) []string {
+		t.Helper()
+		entries, err := sc.ReadDir(dir)
+		require.NoError(t, err)
+		var names []string
+		for _, fi := range entries {
+			names = append(names, fi.Name())
+		}
+		return names
+	}
+
+	t.Run("list endpoints", func(t *testing.T) {
+		require.ElementsMatch(t, []string{"app1", "prod", "proxied"}, names(t, "/"))
+		require.Equal(t, []string{"db1"}, names(t, "/prod"))
+		fi, err := sc.Stat("/prod")
+		require.NoError(t, err)
+		require.True(t, fi.IsDir())
+		_, err = sc.Stat("/nope")
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("write", func(t *testing.T) {
+		f, err := sc.Create("/app1/hello.txt")
+		require.NoError(t, err)
+		_, err = f.Write([]byte("hello"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+		bts, err := os.ReadFile(filepath.Join(app1, "hello.txt"))
+		require.NoError(t, err)
+		require.Equal(t, "hello", string(bts))
+		require.Contains(t, names(t, "/app1"), "hello.txt")
+	})
+
+	t.Run("read", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(db1, "dump.sql"), []byte("select 1;"), 0o600))
+		f, err := sc.Open("prod/db1/dump.sql")
+		require.NoError(t, err)
+		defer f.Close() //nolint:errcheck
+		bts, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "select 1;", string(bts))
+	})
+
+	t.Run("commands", func(t *testing.T) {
+		require.NoError(t, sc.Mkdir("/app1/sub"))
+		require.DirExists(t, filepath.Join(app1, "sub"))
+		require.NoError(t, sc.Rename("/app1/hello.txt", "/app1/sub/hello.txt"))
+		require.FileExists(t, filepath.Join(app1, "sub", "hello.txt"))
+		require.NoError(t, sc.Chmod("/app1/sub/hello.txt", 0o600))
+		requireFile(t, filepath.Join(app1, "sub", "hello.txt"), "hello", 0o600)
+		require.NoError(t, sc.Remove("/app1/sub/hello.txt"))
+		require.NoError(t, sc.RemoveDirectory("/app1/sub"))
+		require.NoDirExists(t, filepath.Join(app1, "sub"))
+	})
+
+	t.Run("across endpoints", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(app1, "a.txt"), []byte("a"), 0o600))
+		require.Error(t, sc.Rename("/app1/a.txt", "/prod/db1/a.txt"))
+		require.FileExists(t, filepath.Join(app1, "a.txt"))
+	})
+
+	t.Run("outside endpoints", func(t *testing.T) {
+		_, err := sc.Create("/hello.txt")
+		require.ErrorIs(t, err, os.ErrPermission)
+		require.ErrorIs(t, sc.Mkdir("/prod/db2"), os.ErrPermission)
+	})
+
+	t.Run("proxy command disabled", func(t *testing.T) {
+		_, err := sc.Stat("/proxied/hello.txt")
+		require.ErrorContains(t, err, "ProxyCommand is disabled in server mode")
+	})
+}
+
+func TestSFTPSubsystemAudit(t *testing.T) {
+	t.Chdir(t.TempDir()) // the client key is written in the working directory
+
+	app1 := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(app1, "hello.txt"), []byte("hello"), 0o600))
+	endpoints := []*Endpoint{{Name: "app1", Address: sftpServer(t, app1)}}
+	path := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := openAuditLog(Audit{Path: path})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = audit.Close() })
+	srv := &ssh.Server{
+		Handler: func(ssh.Session) {},
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{
+			"sftp": sftpSubsystem(&Config{audit: audit}, func() []*Endpoint { return endpoints }),
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(l) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	sc := sftpClient(t, l.Addr().String())
+	f, err := sc.Open("/app1/hello.txt")
+	require.NoError(t, err)
+	bts, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(bts))
+	require.NoError(t, f.Close())
+	require.NoError(t, sc.Close())
+
+	require.Eventually(t, func() bool {
+		return len(readAuditLog(t, path)) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	events := readAuditLog(t, path)
+	for _, event := range events {
+		require.Equal(t, "carlos", event["user"])
+		require.Equal(t, "app1", event["endpoint"])
+		require.Equal(t, "sftp", event["subsystem"])
+		require.NotContains(t, event, "command")
+	}
+	require.Equal(t, auditSessionStart, events[0]["event"])
+	require.Equal(t, auditSessionEnd, events[1]["event"])
+	require.Greater(t, events[1]["bytes_in"], float64(0))
+	require.Greater(t, events[1]["bytes_out"], float64(len("hello")))
+}
@@ -0,0 +1,152 @@
+// Package socks implements the server side of the SOCKS5 handshake, as
+// needed by dynamic port forwarding.
+//
+// Only the CONNECT command without authentication is supported.
+package socks
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+)
+
+const version = 5
+
+// Authentication methods.
+const (
+	methodNoAuth       = 0x00
+	methodNoAcceptable = 0xff
+)
+
+// Commands.
+const cmdConnect = 0x01
+
+// Address types.
+const (
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+)
+
+// Replies.
+const (
+	replySucceeded           byte = 0x00
+	replyGeneralFailure      byte = 0x01
+	replyCommandNotSupported byte = 0x07
+	replyAddressNotSupported byte = 0x08
+)
+
+// ErrNoAcceptableMethods is returned if the client doesn't support
+// connecting without authentication.
+var ErrNoAcceptableMethods = errors.New("socks: no acceptable authentication methods")
+
+// Dialer connects to the given address.
+type Dialer func(network, addr string) (net.Conn, error)
+
+// Handshake negotiates a SOCKS5 connection with the client in conn, and
+// connects to the address it requests with dial.
+// It returns the connection to the requested address, which the caller
+// should pipe conn into, and close.
+func Handshake(conn io.ReadWriter, dial Dialer) (net.Conn, error) {
+	if err := negotiate(conn); err != nil {
+		return nil, err
+	}
+
+	addr, err := readRequest(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := dial("tcp", addr)
+	if err != nil {
+		_ = reply(conn, replyGeneralFailure)
+		return nil, fmt.Errorf("socks: failed to connect to %q: %w", addr, err)
+	}
+	if err := reply(conn, replySucceeded); err != nil {
+		_ = target.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// negotiate reads the client greeting, and picks the no authentication
+// method.
+func negotiate(conn io.ReadWriter) error {
+	header := make([]byte, 2) //nolint:mnd
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return fmt.Errorf("socks: failed to read greeting: %w", err)
+	}
+	if header[0] != version {
+		return fmt.Errorf("socks: unsupported version %d", header[0])
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return fmt.Errorf("socks: failed to read greeting: %w", err)
+	}
+	for _, m := range methods {
+		if m == methodNoAuth {
+			_, err := conn.Write([]byte{version, methodNoAuth})
+			return err //nolint:wrapcheck
+		}
+	}
+	_, _ = conn.Write([]byte{version, methodNoAcceptable})
+	return ErrNoAcceptableMethods
+}
+
+// readRequest reads a connect request, returning the address to connect to.
+func readRequest(conn io.ReadWriter) (string, error) {
+	header := make([]byte, 4) //nolint:mnd
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", fmt.Errorf("socks: failed to read request: %w", err)
+	}
+	if header[0] != version {
+		return "", fmt.Errorf("socks: unsupported version %d", header[0])
+	}
+	if header[1] != cmdConnect {
+		_ = reply(conn, replyCommandNotSupported)
+		return "", fmt.Errorf("socks: unsupported command %d", header[1])
+	}
+
+	var host string
+	switch header[3] {
+	case atypIPv4, atypIPv6:
+		ip := make(net.IP, net.IPv4len)
+		if header[3] == atypIPv6 {
+			ip = make(net.IP, net.IPv6len)
+		}
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", fmt.Errorf("socks: failed to read address: %w", err)
+		}
+		host = ip.String()
+	case atypDomain:
+		size := make([]byte, 1)
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", fmt.Errorf("socks: failed to read address: %w", err)
+		}
+		domain := make([]byte, size[0])
+		if _, err := io.ReadFull(conn, domain); err != nil {
+			return "", fmt.Errorf("socks: failed to read address: %w", err)
+		}
+		host = string(domain)
+	default:
+		_ = reply(conn, replyAddressNotSupported)
+		return "", fmt.Errorf("socks: unsupported address type %d", header[3])
+	}
+
+	port := make([]byte, 2) //nolint:mnd
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return "", fmt.Errorf("socks: failed to read port: %w", err)
+	}
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port)))), nil
+}
+
+// reply sends the given reply.
+// The bound address is always empty, as it's not known when connecting
+// through SSH.
+func reply(conn io.Writer, code byte) error {
+	_, err := conn.Write([]byte{version, code, 0, atypIPv4, 0, 0, 0, 0, 0, 0})
+	return err //nolint:wrapcheck
+}
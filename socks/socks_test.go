@@ -0,0 +1,107 @@
+package socks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// conn is a fake client connection, reading the client messages and
+// recording the replies.
+type conn struct {
+	io.Reader
+	bytes.Buffer
+}
+
+func (c *conn) Write(p []byte) (int, error) {
+	return c.Buffer.Write(p)
+}
+
+func (c *conn) Read(p []byte) (int, error) {
+	return c.Reader.Read(p)
+}
+
+func newConn(msgs ...[]byte) *conn {
+	return &conn{Reader: bytes.NewReader(bytes.Join(msgs, nil))}
+}
+
+var (
+	greeting  = []byte{version, 1, methodNoAuth}
+	accepted  = []byte{version, methodNoAuth}
+	succeeded = []byte{version, replySucceeded, 0, atypIPv4, 0, 0, 0, 0, 0, 0}
+)
+
+func TestHandshake(t *testing.T) {
+	for name, tc := range map[string]struct {
+		request []byte
+		addr    string
+	}{
+		"ipv4":   {[]byte{version, cmdConnect, 0, atypIPv4, 10, 0, 0, 1, 0x1f, 0x90}, "10.0.0.1:8080"},
+		"ipv6":   {append(append([]byte{version, cmdConnect, 0, atypIPv6}, net.ParseIP("::1")...), 0, 80), "[::1]:80"},
+		"domain": {append(append([]byte{version, cmdConnect, 0, atypDomain, 9}, "db1.local"...), 0x15, 0x38), "db1.local:5432"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := newConn(greeting, tc.request)
+			client, server := net.Pipe()
+			defer client.Close() //nolint:errcheck
+			var dialed string
+			target, err := Handshake(c, func(network, addr string) (net.Conn, error) {
+				require.Equal(t, "tcp", network)
+				dialed = addr
+				return server, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, server, target)
+			require.Equal(t, tc.addr, dialed)
+			require.Equal(t, append(accepted, succeeded...), c.Bytes())
+		})
+	}
+}
+
+func TestHandshakeErrors(t *testing.T) {
+	dial := func(string, string) (net.Conn, error) {
+		return nil, fmt.Errorf("connection refused")
+	}
+
+	t.Run("version", func(t *testing.T) {
+		_, err := Handshake(newConn([]byte{4, 1, methodNoAuth}), dial)
+		require.EqualError(t, err, "socks: unsupported version 4")
+	})
+
+	t.Run("no acceptable methods", func(t *testing.T) {
+		c := newConn([]byte{version, 1, 0x02})
+		_, err := Handshake(c, dial)
+		require.ErrorIs(t, err, ErrNoAcceptableMethods)
+		require.Equal(t, []byte{version, methodNoAcceptable}, c.Bytes())
+	})
+
+	t.Run("bind", func(t *testing.T) {
+		c := newConn(greeting, []byte{version, 0x02, 0, atypIPv4, 10, 0, 0, 1, 0, 80})
+		_, err := Handshake(c, dial)
+		require.EqualError(t, err, "socks: unsupported command 2")
+		require.Equal(t, replyCommandNotSupported, c.Bytes()[len(accepted)+1])
+	})
+
+	t.Run("address type", func(t *testing.T) {
+		c := newConn(greeting, []byte{version, cmdConnect, 0, 0x09})
+		_, err := Handshake(c, dial)
+		require.EqualError(t, err, "socks: unsupported address type 9")
+		require.Equal(t, replyAddressNotSupported, c.Bytes()[len(accepted)+1])
+	})
+
+	t.Run("dial", func(t *testing.T) {
+		c := newConn(greeting, []byte{version, cmdConnect, 0, atypIPv4, 10, 0, 0, 1, 0, 80})
+		_, err := Handshake(c, dial)
+		require.EqualError(t, err, `socks: failed to connect to "10.0.0.1:80": connection refused`)
+		require.Equal(t, replyGeneralFailure, c.Bytes()[len(accepted)+1])
+	})
+
+	t.Run("short", func(t *testing.T) {
+		_, err := Handshake(newConn(greeting, []byte{version, cmdConnect}), dial)
+		require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	})
+}
@@ -0,0 +1,88 @@
+package wishlist
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// sortMode defines how endpoints are sorted in the list.
+// Pinned endpoints are always listed first.
+type sortMode int
+
+const (
+	sortDefault sortMode = iota
+	sortAlphabetical
+	sortRecent
+	sortFrequent
+	sortStatus
+	sortModes // number of sort modes
+)
+
+func (s sortMode) String() string {
+	switch s {
+	case sortAlphabetical:
+		return "alphabetical"
+	case sortRecent:
+		return "most recent"
+	case sortFrequent:
+		return "most frequent"
+	case sortStatus:
+		return "status"
+	default:
+		return "default"
+	}
+}
+
+// next returns the next sort mode, wrapping around.
+// Sorting by status is skipped if endpoints are not being probed.
+func (s sortMode) next(probing bool) sortMode {
+	next := (s + 1) % sortModes
+	if next == sortStatus && !probing {
+		return next.next(probing)
+	}
+	return next
+}
+
+// sortEndpoints returns a sorted copy of the given endpoints.
+func sortEndpoints(endpoints []*Endpoint, state *State, probes ProbeResults, mode sortMode) []*Endpoint {
+	result := slices.Clone(endpoints)
+	sort.SliceStable(result, func(i, j int) bool {
+		si, sj := state.Get(result[i]), state.Get(result[j])
+		if si.Pinned != sj.Pinned {
+			return si.Pinned
+		}
+		switch mode {
+		case sortAlphabetical:
+			return strings.ToLower(result[i].Name) < strings.ToLower(result[j].Name)
+		case sortRecent:
+			return si.LastUsed.After(sj.LastUsed)
+		case sortFrequent:
+			return si.Count > sj.Count
+		case sortStatus:
+			return probeLess(probes[result[i].FullName()], probes[result[j].FullName()])
+		default:
+			return false
+		}
+	})
+	return result
+}
+
+// probeLess returns true if a should be listed before b: reachable endpoints
+// first, by RTT, then unknown ones, and then unreachable ones.
+func probeLess(a, b ProbeResult) bool {
+	rank := func(r ProbeResult) int {
+		switch r.Status {
+		case ProbeUp:
+			return 0
+		case ProbeUnknown:
+			return 1
+		default:
+			return 2 //nolint:mnd
+		}
+	}
+	if ra, rb := rank(a), rank(b); ra != rb {
+		return ra < rb
+	}
+	return a.Status == ProbeUp && a.RTT < b.RTT
+}
@@ -0,0 +1,59 @@
+package wishlist
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortEndpoints(t *testing.T) {
+	state, err := OpenState(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	a := &Endpoint{Name: "a"}
+	b := &Endpoint{Name: "B"}
+	c := &Endpoint{Name: "c"}
+	d := &Endpoint{Name: "d"}
+	endpoints := []*Endpoint{d, c, b, a}
+
+	require.NoError(t, state.Touch(a))
+	time.Sleep(time.Millisecond)
+	require.NoError(t, state.Touch(c))
+	require.NoError(t, state.Touch(c))
+	require.NoError(t, state.TogglePin(d))
+
+	probes := ProbeResults{
+		"a": {Status: ProbeDown},
+		"B": {Status: ProbeUp, RTT: 20 * time.Millisecond},
+		"c": {Status: ProbeUp, RTT: 10 * time.Millisecond},
+	}
+
+	for mode, expected := range map[sortMode][]*Endpoint{
+		sortDefault:      {d, c, b, a},
+		sortAlphabetical: {d, a, b, c},
+		sortRecent:       {d, c, a, b},
+		sortFrequent:     {d, c, a, b},
+		sortStatus:       {d, c, b, a},
+	} {
+		t.Run(mode.String(), func(t *testing.T) {
+			require.Equal(t, expected, sortEndpoints(endpoints, state, probes, mode))
+		})
+	}
+
+	t.Run("nil state", func(t *testing.T) {
+		require.Equal(t, []*Endpoint{a, b, c, d}, sortEndpoints(endpoints, nil, nil, sortAlphabetical))
+	})
+
+	t.Run("does not change input", func(t *testing.T) {
+		require.Equal(t, []*Endpoint{d, c, b, a}, endpoints)
+	})
+}
+
+func TestSortModeNext(t *testing.T) {
+	require.Equal(t, sortAlphabetical, sortDefault.next(false))
+	require.Equal(t, sortDefault, sortFrequent.next(false))
+	require.Equal(t, sortStatus, sortFrequent.next(true))
+	require.Equal(t, sortDefault, sortStatus.next(true))
+}
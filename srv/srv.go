@@ -11,8 +11,9 @@ import (
 )
 
 const (
-	service   = "_ssh._tcp"
-	txtPrefix = "wishlist.name "
+	service        = "_ssh._tcp"
+	txtPrefix      = "wishlist.name "
+	txtGroupPrefix = "wishlist.group "
 )
 
 // Endpoints returns the _ssh._tcp SRV records on the given domain as
@@ -36,25 +37,32 @@ func fromRecords(srvs []*net.SRV, txts []string) []*wishlist.Endpoint {
 	result := make([]*wishlist.Endpoint, 0, len(srvs))
 	for _, entry := range srvs {
 		hostname := strings.TrimSuffix(entry.Target, ".")
-		name := hostname
 		port := fmt.Sprintf("%d", entry.Port)
 		address := net.JoinHostPort(hostname, port)
-		for _, txt := range txts {
-			if !strings.HasPrefix(txt, txtPrefix) {
-				continue
-			}
-			txtAddr, txtName, ok := strings.Cut(strings.TrimPrefix(txt, txtPrefix), "=")
-			if !ok {
-				continue
-			}
-			if txtAddr == address {
-				name = txtName
-			}
-		}
 		result = append(result, &wishlist.Endpoint{
-			Name:    name,
+			Name:    wishlist.FirstNonEmpty(fromTXT(txts, txtPrefix, address), hostname),
+			Group:   fromTXT(txts, txtGroupPrefix, address),
 			Address: address,
 		})
 	}
 	return result
 }
+
+// fromTXT gets the value for the given address from TXT records in the
+// `prefix address=value` format.
+func fromTXT(txts []string, prefix, address string) string {
+	var result string
+	for _, txt := range txts {
+		if !strings.HasPrefix(txt, prefix) {
+			continue
+		}
+		txtAddr, value, ok := strings.Cut(strings.TrimPrefix(txt, prefix), "=")
+		if !ok {
+			continue
+		}
+		if txtAddr == address {
+			result = value
+		}
+	}
+	return result
+}
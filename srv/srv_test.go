@@ -63,4 +63,24 @@ func TestFromRecords(t *testing.T) {
 			"wishlist.name foo.local:2222=local-foo",
 		}))
 	})
+
+	t.Run("with group txt records", func(t *testing.T) {
+		require.ElementsMatch(t, []*wishlist.Endpoint{
+			{
+				Name:    "foo.bar",
+				Group:   "prod/eu",
+				Address: "foo.bar:22",
+			},
+		}, fromRecords([]*net.SRV{
+			{
+				Target:   "foo.bar",
+				Port:     22,
+				Priority: 10,
+				Weight:   10,
+			},
+		}, []string{
+			"wishlist.group foo.bar:22=prod/eu",
+			"wishlist.group foo.local:2222=dev",
+		}))
+	})
 }
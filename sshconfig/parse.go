@@ -13,6 +13,7 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"github.com/charmbracelet/log"
 	"github.com/charmbracelet/wishlist"
@@ -21,6 +22,9 @@ import (
 	"github.com/kevinburke/ssh_config"
 )
 
+// annotationPrefix is the prefix of wishlist-specific comments in a Host block.
+const annotationPrefix = "wishlist."
+
 // NamedReader is an io.Reader that also has a name, usually a os.File.
 type NamedReader interface {
 	io.Reader
@@ -68,7 +72,8 @@ func ParseReader(r NamedReader, seed []*wishlist.Endpoint) ([]*wishlist.Endpoint
 		}
 
 		endpoints = append(endpoints, &wishlist.Endpoint{
-			Name: name,
+			Name:  name,
+			Group: info.Group,
 			Address: net.JoinHostPort(
 				wishlist.FirstNonEmpty(info.Hostname, name),
 				wishlist.FirstNonEmpty(info.Port, "22"),
@@ -83,6 +88,16 @@ func ParseReader(r NamedReader, seed []*wishlist.Endpoint) ([]*wishlist.Endpoint
 			SendEnv:                  info.SendEnv,
 			PreferredAuthentications: info.PreferredAuthentications,
 			ProxyJump:                info.ProxyJump,
+			ProxyCommand:             info.ProxyCommand,
+			Tags:                     info.Tags,
+			Desc:                     info.Desc,
+			Link:                     info.Link,
+			StrictHostKeyChecking:    info.StrictHostKeyChecking,
+			UserKnownHostsFile:       info.UserKnownHostsFile,
+			GlobalKnownHostsFile:     info.GlobalKnownHostsFile,
+			LocalForward:             info.LocalForward,
+			RemoteForward:            info.RemoteForward,
+			DynamicForward:           info.DynamicForward,
 		})
 		return nil
 	}); err != nil {
@@ -98,6 +113,10 @@ func stringToBool(s string) bool {
 }
 
 type hostinfo struct {
+	Group                    string
+	Tags                     []string
+	Desc                     string
+	Link                     wishlist.Link
 	User                     string
 	Hostname                 string
 	Port                     string
@@ -106,10 +125,17 @@ type hostinfo struct {
 	RequestTTY               string
 	RemoteCommand            string
 	ProxyJump                string
+	ProxyCommand             string
 	SendEnv                  []string
 	SetEnv                   []string
 	PreferredAuthentications []string
 	Timeout                  time.Duration
+	StrictHostKeyChecking    string
+	UserKnownHostsFile       []string
+	GlobalKnownHostsFile     []string
+	LocalForward             []string
+	RemoteForward            []string
+	DynamicForward           []string
 }
 
 type hostinfoMap struct {
@@ -192,6 +218,20 @@ func parseInternal(r NamedReader) (*hostinfoMap, error) {
 				}
 
 				if strings.HasPrefix(node, "#") {
+					key, value, ok := parseAnnotation(node)
+					if !ok {
+						continue
+					}
+					switch key {
+					case "group":
+						info.Group = value
+					case "tags":
+						info.Tags = append(info.Tags, parseTags(value)...)
+					case "description":
+						info.Desc = strings.TrimPrefix(info.Desc+"\n"+value, "\n")
+					case "link":
+						info.Link = parseLink(value)
+					}
 					continue
 				}
 
@@ -220,16 +260,45 @@ func parseInternal(r NamedReader) (*hostinfoMap, error) {
 					info.RemoteCommand = value
 				case "proxyjump":
 					info.ProxyJump = value
+				case "proxycommand":
+					if err := wishlist.ValidateProxyCommand(value); err != nil {
+						return nil, err //nolint: wrapcheck
+					}
+					info.ProxyCommand = value
 				case "connecttimeout":
 					timeout, err := strconv.Atoi(value)
 					if err != nil {
 						return nil, fmt.Errorf("invalid ConnectTimeout: %s: %w", value, err)
 					}
 					info.Timeout = time.Second * time.Duration(timeout)
+				case "stricthostkeychecking":
+					mode, err := wishlist.ParseHostKeyChecking(value)
+					if err != nil {
+						return nil, err //nolint: wrapcheck
+					}
+					info.StrictHostKeyChecking = mode
+				case "userknownhostsfile":
+					info.UserKnownHostsFile = strings.Fields(value)
+				case "globalknownhostsfile":
+					info.GlobalKnownHostsFile = strings.Fields(value)
 				case "sendenv":
 					info.SendEnv = append(info.SendEnv, value)
 				case "setenv":
 					info.SetEnv = append(info.SetEnv, parseSetEnv(value))
+				case "localforward", "remoteforward":
+					if _, err := wishlist.ParseForward(value); err != nil {
+						return nil, err //nolint: wrapcheck
+					}
+					if strings.EqualFold(key, "localforward") {
+						info.LocalForward = append(info.LocalForward, value)
+					} else {
+						info.RemoteForward = append(info.RemoteForward, value)
+					}
+				case "dynamicforward":
+					if _, err := wishlist.ParseDynamicForward(value); err != nil {
+						return nil, err //nolint: wrapcheck
+					}
+					info.DynamicForward = append(info.DynamicForward, value)
 				case "preferredauthentications":
 					info.PreferredAuthentications = append(info.PreferredAuthentications, strings.Split(value, ",")...)
 				case "include":
@@ -276,6 +345,7 @@ func split(m *hostinfoMap, seed []*wishlist.Endpoint) (*hostinfoMap, *hostinfoMa
 	for _, e := range seed {
 		hostname, port, _ := net.SplitHostPort(e.Address)
 		hosts.set(e.Name, hostinfo{
+			Group:    e.Group,
 			Hostname: hostname,
 			Port:     port,
 		})
@@ -316,6 +386,15 @@ func merge(m1, m2 *hostinfoMap) *hostinfoMap {
 }
 
 func mergeHostinfo(h1, h2 hostinfo) hostinfo {
+	if h1.Group != "" {
+		h2.Group = h1.Group
+	}
+	if h1.Desc != "" {
+		h2.Desc = h1.Desc
+	}
+	if h1.Link.URL != "" {
+		h2.Link = h1.Link
+	}
 	if h1.Port != "" {
 		h2.Port = h1.Port
 	}
@@ -341,9 +420,25 @@ func mergeHostinfo(h1, h2 hostinfo) hostinfo {
 	if h1.ProxyJump != "" {
 		h2.ProxyJump = h1.ProxyJump
 	}
+	if h1.ProxyCommand != "" {
+		h2.ProxyCommand = h1.ProxyCommand
+	}
+	if h1.StrictHostKeyChecking != "" {
+		h2.StrictHostKeyChecking = h1.StrictHostKeyChecking
+	}
+	if len(h1.UserKnownHostsFile) > 0 {
+		h2.UserKnownHostsFile = h1.UserKnownHostsFile
+	}
+	if len(h1.GlobalKnownHostsFile) > 0 {
+		h2.GlobalKnownHostsFile = h1.GlobalKnownHostsFile
+	}
+	h2.Tags = append(h2.Tags, h1.Tags...)
 	h2.SendEnv = append(h2.SendEnv, h1.SendEnv...)
 	h2.SetEnv = append(h2.SetEnv, h1.SetEnv...)
 	h2.PreferredAuthentications = append(h2.PreferredAuthentications, h1.PreferredAuthentications...)
+	h2.LocalForward = append(h2.LocalForward, h1.LocalForward...)
+	h2.RemoteForward = append(h2.RemoteForward, h1.RemoteForward...)
+	h2.DynamicForward = append(h2.DynamicForward, h1.DynamicForward...)
 	return h2
 }
 
@@ -356,6 +451,37 @@ func parseFileInternal(path string) (*hostinfoMap, error) {
 	return parseInternal(f)
 }
 
+// parseAnnotation parses wishlist-specific settings set as comments within a
+// Host block, e.g. `# wishlist.group: prod/eu`.
+func parseAnnotation(node string) (string, string, bool) {
+	node = strings.TrimSpace(strings.TrimPrefix(node, "#"))
+	if !strings.HasPrefix(node, annotationPrefix) {
+		return "", "", false
+	}
+	key, value, ok := strings.Cut(strings.TrimPrefix(node, annotationPrefix), ":")
+	if !ok {
+		return "", "", false
+	}
+	return strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value), true
+}
+
+// parseTags parses a comma or space separated list of tags.
+func parseTags(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// parseLink parses a link annotation, made of an URL optionally followed by
+// its name, e.g. `https://example.com Example`.
+func parseLink(s string) wishlist.Link {
+	url, name, _ := strings.Cut(strings.TrimSpace(s), " ")
+	return wishlist.Link{
+		URL:  url,
+		Name: strings.TrimSpace(name),
+	}
+}
+
 func parseSetEnv(e string) string {
 	k, v, ok := strings.Cut(e, "=")
 	if !ok {
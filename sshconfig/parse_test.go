@@ -48,7 +48,9 @@ func TestParseFile(t *testing.T) {
 			},
 			"app1": {
 				Name:    "app1",
+				Group:   "apps/foo",
 				Address: "app.foo.local:2222",
+				Tags:    []string{"prod", "web"},
 			},
 			"app2": {
 				Name:          "app2",
@@ -59,6 +61,7 @@ func TestParseFile(t *testing.T) {
 			},
 			"multiple1": {
 				Name:    "multiple1",
+				Tags:    []string{"multi"},
 				Address: "multi1.foo.local:22",
 				User:    "multi",
 				Timeout: time.Second * 12,
@@ -71,6 +74,7 @@ func TestParseFile(t *testing.T) {
 			},
 			"multiple2": {
 				Name:    "multiple2",
+				Tags:    []string{"multi"},
 				Address: "multi2.foo.local:2223",
 				User:    "multi",
 				Timeout: time.Second * 12,
@@ -84,6 +88,7 @@ func TestParseFile(t *testing.T) {
 			},
 			"multiple3": {
 				Name:    "multiple3",
+				Tags:    []string{"multi", "three"},
 				Address: "multi3.foo.local:22",
 				User:    "overridden",
 				Timeout: time.Second * 12,
@@ -152,6 +157,7 @@ Host zap.local
 			},
 			{
 				Name:    "zap.non_local",
+				Group:   "discovered",
 				Address: "zap.non_local:22",
 			},
 		})
@@ -170,12 +176,158 @@ Host zap.local
 			},
 			{
 				Name:    "zap.non_local",
+				Group:   "discovered",
 				Address: "zap.non_local:22",
 			},
 		}, endpoints)
 	})
 }
 
+func TestParseHostKeyChecking(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host *.prod
+  StrictHostKeyChecking yes
+  UserKnownHostsFile ~/.ssh/known_hosts_prod /etc/ssh/known_hosts_prod
+
+Host db.prod
+  StrictHostKeyChecking Ask
+  GlobalKnownHostsFile none
+
+Host web.prod
+
+Host lab
+  StrictHostKeyChecking off
+		`, t.TempDir()), nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []*wishlist.Endpoint{
+			{
+				Name:                  "db.prod",
+				Address:               "db.prod:22",
+				StrictHostKeyChecking: "ask",
+				UserKnownHostsFile:    []string{"~/.ssh/known_hosts_prod", "/etc/ssh/known_hosts_prod"},
+				GlobalKnownHostsFile:  []string{"none"},
+			},
+			{
+				Name:                  "web.prod",
+				Address:               "web.prod:22",
+				StrictHostKeyChecking: "yes",
+				UserKnownHostsFile:    []string{"~/.ssh/known_hosts_prod", "/etc/ssh/known_hosts_prod"},
+			},
+			{
+				Name:                  "lab",
+				Address:               "lab:22",
+				StrictHostKeyChecking: "no",
+			},
+		}, endpoints)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host foo
+  StrictHostKeyChecking maybe
+		`, t.TempDir()), nil)
+		require.EqualError(t, err, `invalid StrictHostKeyChecking: "maybe"`)
+		require.Empty(t, endpoints)
+	})
+}
+
+func TestParseForwards(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host *.prod
+  DynamicForward 1080
+
+Host db.prod
+  LocalForward 5432 localhost:5432
+  LocalForward 127.0.0.1:6379 cache.prod:6379
+  RemoteForward 8080 localhost:80
+		`, t.TempDir()), nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []*wishlist.Endpoint{
+			{
+				Name:           "db.prod",
+				Address:        "db.prod:22",
+				LocalForward:   []string{"5432 localhost:5432", "127.0.0.1:6379 cache.prod:6379"},
+				RemoteForward:  []string{"8080 localhost:80"},
+				DynamicForward: []string{"1080"},
+			},
+		}, endpoints)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host foo
+  LocalForward 5432
+		`, t.TempDir()), nil)
+		require.EqualError(t, err, `invalid forward: "5432"`)
+		require.Empty(t, endpoints)
+	})
+}
+
+func TestParseProxyCommand(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host *.internal
+  ProxyCommand ssh -W %h:%p bastion
+
+Host db.internal
+  User admin
+		`, t.TempDir()), nil)
+		require.NoError(t, err)
+		require.ElementsMatch(t, []*wishlist.Endpoint{
+			{
+				Name:         "db.internal",
+				Address:      "db.internal:22",
+				User:         "admin",
+				ProxyCommand: "ssh -W %h:%p bastion",
+			},
+		}, endpoints)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		endpoints, err := ParseReader(newNamedReader(`
+Host foo
+  ProxyCommand nc %x %p
+		`, t.TempDir()), nil)
+		require.EqualError(t, err, `invalid ProxyCommand "nc %x %p": unknown token %x`)
+		require.Empty(t, endpoints)
+	})
+}
+
+func TestParseAnnotation(t *testing.T) {
+	for node, expected := range map[string]struct {
+		key, value string
+		ok         bool
+	}{
+		"# wishlist.group: prod/eu":   {key: "group", value: "prod/eu", ok: true},
+		"#wishlist.Group:prod":        {key: "group", value: "prod", ok: true},
+		"# wishlist.group prod":       {},
+		"# just a comment":            {},
+		"# wishlist.group: ":          {key: "group", ok: true},
+		"# other.group: prod/eu/db/1": {},
+		"# wishlist.tags: a, b c":     {key: "tags", value: "a, b c", ok: true},
+	} {
+		t.Run(node, func(t *testing.T) {
+			key, value, ok := parseAnnotation(node)
+			require.Equal(t, expected.key, key)
+			require.Equal(t, expected.value, value)
+			require.Equal(t, expected.ok, ok)
+		})
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	require.Equal(t, []string{"a", "b", "c"}, parseTags("a, b c,,"))
+	require.Empty(t, parseTags(""))
+}
+
+func TestParseLink(t *testing.T) {
+	require.Equal(t, wishlist.Link{URL: "https://example.com"}, parseLink("https://example.com"))
+	require.Equal(t, wishlist.Link{URL: "https://example.com", Name: "An example"}, parseLink(" https://example.com  An example "))
+	require.Equal(t, wishlist.Link{}, parseLink(""))
+}
+
 func TestParseReader(t *testing.T) {
 	t.Run("error", func(t *testing.T) {
 		endpoints, err := ParseReader(
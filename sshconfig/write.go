@@ -0,0 +1,291 @@
+package sshconfig
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/charmbracelet/wishlist/atomicfile"
+)
+
+// defaultIndent is used in configs which have no indented lines yet.
+const defaultIndent = "  "
+
+// WriteEndpoint adds or updates the given endpoint in the SSH config file in
+// the given path.
+// If old is nil, the endpoint is added into a new Host block at the end of
+// the file, otherwise, the Host block of old is updated.
+//
+// Only the options wishlist knows how to edit are changed, everything else,
+// including comments, is kept as is.
+func WriteEndpoint(path string, old, e *wishlist.Endpoint) error {
+	bts, err := os.ReadFile(path)
+	if err != nil && !(old == nil && errors.Is(err, os.ErrNotExist)) {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	lines := strings.Split(strings.TrimRight(string(bts), "\n"), "\n")
+	if len(bts) == 0 {
+		lines = nil
+	}
+
+	if old == nil {
+		if _, _, ok := findHostBlock(lines, e.Name); ok {
+			return fmt.Errorf("endpoint %q is already defined in %q", e.Name, path)
+		}
+		var at int
+		lines, at = insertHostBlock(lines, "Host "+e.Name)
+		lines = updateHostBlock(lines, at, at+1, e)
+	} else {
+		start, end, ok := findHostBlock(lines, old.Name)
+		if !ok {
+			return fmt.Errorf("endpoint %q is not defined in %q", old.Name, path)
+		}
+		if patterns := hostPatterns(lines[start]); len(patterns) != 1 {
+			return fmt.Errorf("endpoint %q shares its Host block with %v, edit it manually", old.Name, patterns)
+		}
+		if e.Name != old.Name {
+			if _, _, ok := findHostBlock(lines, e.Name); ok {
+				return fmt.Errorf("endpoint %q is already defined in %q", e.Name, path)
+			}
+			indent, keyword, _ := splitLine(lines[start])
+			lines[start] = indent + keyword + " " + e.Name
+		}
+		lines = updateHostBlock(lines, start, end, e)
+	}
+
+	if err := atomicfile.Write(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// insertHostBlock inserts the given Host line at the end of the config, or
+// before the `Host *` block, if any, as its options would take precedence
+// otherwise.
+// It returns the new lines, and the index of the Host line.
+func insertHostBlock(lines []string, host string) ([]string, int) {
+	at := len(lines)
+	if start, _, ok := findHostBlock(lines, "*"); ok && len(hostPatterns(lines[start])) == 1 {
+		at = start
+		for at > 0 && strings.HasPrefix(strings.TrimSpace(lines[at-1]), "#") {
+			at-- // keep the comments above it together with it
+		}
+	}
+
+	result := make([]string, 0, len(lines)+3) //nolint:mnd
+	result = append(result, lines[:at]...)
+	if at > 0 && strings.TrimSpace(lines[at-1]) != "" {
+		result = append(result, "")
+	}
+	result = append(result, host)
+	index := len(result) - 1
+	if at < len(lines) {
+		result = append(result, "")
+	}
+	return append(result, lines[at:]...), index
+}
+
+// updateHostBlock sets the endpoint options into the Host block between the
+// given lines.
+func updateHostBlock(lines []string, start, end int, e *wishlist.Endpoint) []string {
+	host, port, err := net.SplitHostPort(e.Address)
+	if err != nil {
+		host = e.Address
+	}
+	if host == e.Name {
+		host = ""
+	}
+	if port == "22" {
+		port = ""
+	}
+
+	var desc []string
+	if e.Desc != "" {
+		desc = strings.Split(e.Desc, "\n")
+	}
+
+	block := hostBlock{
+		lines:    lines[start:end],
+		fallback: fileIndent(lines),
+	}
+	block.set(isOption("hostname"), "HostName", values(host))
+	block.set(isOption("port"), "Port", values(port))
+	block.set(isOption("user"), "User", values(e.User))
+	block.set(isOption("proxyjump"), "ProxyJump", values(e.ProxyJump))
+	block.set(isAnnotation("group"), annotation("group"), values(wishlist.CleanGroup(e.Group)))
+	block.set(isAnnotation("tags"), annotation("tags"), values(strings.Join(e.Tags, ", ")))
+	block.set(isAnnotation("description"), annotation("description"), desc)
+	block.set(isAnnotation("link"), annotation("link"), values(strings.TrimSpace(e.Link.URL+" "+e.Link.Name)))
+
+	result := make([]string, 0, len(lines)-(end-start)+len(block.lines))
+	result = append(result, lines[:start]...)
+	result = append(result, block.lines...)
+	return append(result, lines[end:]...)
+}
+
+// hostBlock is a Host line followed by its options.
+type hostBlock struct {
+	lines []string
+
+	// indentation used if the block has no indented lines yet.
+	fallback string
+}
+
+// set replaces all the lines matching the given function with a line for
+// each value, in the same place the first matching line was.
+// If there are no matching lines, the new lines are added after the last
+// option of the block.
+func (b *hostBlock) set(match func(string) bool, prefix string, values []string) {
+	indent := b.indent()
+	at := -1
+	kept := make([]string, 0, len(b.lines))
+	for i, line := range b.lines {
+		if i > 0 && match(line) {
+			if at == -1 {
+				at = len(kept)
+			}
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if at == -1 {
+		at = lastOption(kept) + 1
+	}
+
+	added := make([]string, 0, len(values))
+	for _, v := range values {
+		added = append(added, indent+prefix+" "+v)
+	}
+	b.lines = append(kept[:at], append(added, kept[at:]...)...)
+}
+
+// indent returns the indentation used by the options in the block.
+func (b *hostBlock) indent() string {
+	for _, line := range b.lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent, _, _ := splitLine(line)
+		if indent != "" {
+			return indent
+		}
+	}
+	return b.fallback
+}
+
+// fileIndent returns the indentation used by the first indented option in
+// the config.
+func fileIndent(lines []string) string {
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indent, _, _ := splitLine(line); indent != "" {
+			return indent
+		}
+	}
+	return defaultIndent
+}
+
+// lastOption returns the index of the last option or annotation in the
+// block, so comments and empty lines separating it from the next block are
+// kept in place.
+func lastOption(lines []string) int {
+	last := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "#") {
+			if _, _, ok := parseAnnotation(trimmed); !ok {
+				continue
+			}
+		}
+		last = i
+	}
+	return last
+}
+
+// findHostBlock returns the lines of the Host block with the given pattern,
+// from its Host line up to the next Host or Match line.
+func findHostBlock(lines []string, name string) (int, int, bool) {
+	start := -1
+	for i, line := range lines {
+		_, keyword, _ := splitLine(line)
+		keyword = strings.ToLower(keyword)
+		if keyword != "host" && keyword != "match" {
+			continue
+		}
+		if start != -1 {
+			return start, i, true
+		}
+		if keyword == "host" && slices.Contains(hostPatterns(line), name) {
+			start = i
+		}
+	}
+	if start != -1 {
+		return start, len(lines), true
+	}
+	return 0, 0, false
+}
+
+// hostPatterns returns the patterns of a Host line.
+func hostPatterns(line string) []string {
+	_, _, value := splitLine(line)
+	return strings.Fields(value)
+}
+
+// splitLine splits an option line into its indentation, keyword, and value.
+// Keywords and values might be separated by spaces or an equal sign.
+func splitLine(line string) (string, string, string) {
+	trimmed := strings.TrimLeftFunc(line, unicode.IsSpace)
+	indent := line[:len(line)-len(trimmed)]
+	if strings.HasPrefix(trimmed, "#") {
+		return indent, "", ""
+	}
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return r == '=' || unicode.IsSpace(r)
+	})
+	if i == -1 {
+		return indent, trimmed, ""
+	}
+	value := strings.TrimLeftFunc(trimmed[i:], unicode.IsSpace)
+	value = strings.TrimLeftFunc(strings.TrimPrefix(value, "="), unicode.IsSpace)
+	return indent, trimmed[:i], strings.TrimSpace(value)
+}
+
+// isOption returns a function that matches lines setting the given option.
+func isOption(name string) func(string) bool {
+	return func(line string) bool {
+		_, keyword, _ := splitLine(line)
+		return strings.EqualFold(keyword, name)
+	}
+}
+
+// isAnnotation returns a function that matches the given annotation.
+func isAnnotation(name string) func(string) bool {
+	return func(line string) bool {
+		key, _, ok := parseAnnotation(strings.TrimSpace(line))
+		return ok && key == name
+	}
+}
+
+// annotation returns the prefix of the given annotation, e.g.
+// `# wishlist.group:`.
+func annotation(name string) string {
+	return "# " + annotationPrefix + name + ":"
+}
+
+// values returns the given value as a list, which is empty if the value is.
+func values(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
@@ -0,0 +1,202 @@
+package sshconfig
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/wishlist"
+	"github.com/stretchr/testify/require"
+)
+
+const writeConfig = `# my hosts
+Host db1
+	# the main database
+	HostName db1.prod.local
+	User postgres
+	Port 2222
+	# wishlist.tags: db
+	IdentityFile ~/.ssh/db
+
+	# ssh cares about this one
+Host web1 web2
+	User www
+
+Host = shell
+    ForwardAgent yes
+
+# defaults
+Host *
+	User me
+`
+
+func writeTestConfig(tb testing.TB) string {
+	tb.Helper()
+	path := filepath.Join(tb.TempDir(), "config")
+	require.NoError(tb, os.WriteFile(path, []byte(writeConfig), 0o600))
+	return path
+}
+
+func requireConfig(tb testing.TB, path, expected string) {
+	tb.Helper()
+	bts, err := os.ReadFile(path)
+	require.NoError(tb, err)
+	require.Equal(tb, expected, string(bts))
+}
+
+func TestWriteEndpoint(t *testing.T) {
+	t.Run("add", func(t *testing.T) {
+		path := writeTestConfig(t)
+		e := &wishlist.Endpoint{
+			Name:    "cache1",
+			Group:   "prod/cache",
+			Address: "cache1.prod.local:6379",
+			User:    "redis",
+			Desc:    "The cache.\nDon't flush it.",
+			Link:    wishlist.Link{URL: "https://example.com/cache", Name: "Dashboard"},
+			Tags:    []string{"cache", "prod"},
+		}
+		require.NoError(t, WriteEndpoint(path, nil, e))
+		requireConfig(t, path, `# my hosts
+Host db1
+	# the main database
+	HostName db1.prod.local
+	User postgres
+	Port 2222
+	# wishlist.tags: db
+	IdentityFile ~/.ssh/db
+
+	# ssh cares about this one
+Host web1 web2
+	User www
+
+Host = shell
+    ForwardAgent yes
+
+Host cache1
+	HostName cache1.prod.local
+	Port 6379
+	User redis
+	# wishlist.group: prod/cache
+	# wishlist.tags: cache, prod
+	# wishlist.description: The cache.
+	# wishlist.description: Don't flush it.
+	# wishlist.link: https://example.com/cache Dashboard
+
+# defaults
+Host *
+	User me
+`)
+
+		endpoints, err := ParseFile(path, nil)
+		require.NoError(t, err)
+		require.Equal(t, e, wishlist.FindEndpoint(endpoints, "cache1"))
+	})
+
+	t.Run("add to empty file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config")
+		require.NoError(t, WriteEndpoint(path, nil, &wishlist.Endpoint{
+			Name:    "foo",
+			Address: "foo:22",
+		}))
+		requireConfig(t, path, "Host foo\n")
+
+		require.NoError(t, WriteEndpoint(path, nil, &wishlist.Endpoint{
+			Name:    "bar",
+			Address: "bar.local:22",
+		}))
+		requireConfig(t, path, "Host foo\n\nHost bar\n  HostName bar.local\n")
+	})
+
+	t.Run("add existing", func(t *testing.T) {
+		path := writeTestConfig(t)
+		err := WriteEndpoint(path, nil, &wishlist.Endpoint{Name: "web2", Address: "web2:22"})
+		require.EqualError(t, err, `endpoint "web2" is already defined in "`+path+`"`)
+	})
+
+	t.Run("edit", func(t *testing.T) {
+		path := writeTestConfig(t)
+		endpoints, err := ParseFile(path, nil)
+		require.NoError(t, err)
+		old := wishlist.FindEndpoint(endpoints, "db1")
+		e := *old
+		e.Name = "db2"
+		e.Address = "db2.prod.local:22"
+		e.User = ""
+		e.ProxyJump = "bastion"
+		e.Tags = []string{"db", "primary"}
+		require.NoError(t, WriteEndpoint(path, old, &e))
+		requireConfig(t, path, `# my hosts
+Host db2
+	# the main database
+	HostName db2.prod.local
+	# wishlist.tags: db, primary
+	IdentityFile ~/.ssh/db
+	ProxyJump bastion
+
+	# ssh cares about this one
+Host web1 web2
+	User www
+
+Host = shell
+    ForwardAgent yes
+
+# defaults
+Host *
+	User me
+`)
+	})
+
+	t.Run("edit with equal sign", func(t *testing.T) {
+		path := writeTestConfig(t)
+		old := &wishlist.Endpoint{Name: "shell", Address: "shell:22"}
+		require.NoError(t, WriteEndpoint(path, old, &wishlist.Endpoint{
+			Name:    "shell",
+			Address: "shell:2222",
+			Desc:    "A shell",
+		}))
+		endpoints, err := ParseFile(path, nil)
+		require.NoError(t, err)
+		e := wishlist.FindEndpoint(endpoints, "shell")
+		require.Equal(t, "shell:2222", e.Address)
+		require.Equal(t, "A shell", e.Desc)
+		require.True(t, e.ForwardAgent)
+	})
+
+	t.Run("edit shared block", func(t *testing.T) {
+		path := writeTestConfig(t)
+		old := &wishlist.Endpoint{Name: "web1", Address: "web1:22"}
+		err := WriteEndpoint(path, old, old)
+		require.EqualError(t, err, `endpoint "web1" shares its Host block with [web1 web2], edit it manually`)
+		requireConfig(t, path, writeConfig)
+	})
+
+	t.Run("edit missing", func(t *testing.T) {
+		path := writeTestConfig(t)
+		old := &wishlist.Endpoint{Name: "discovered", Address: "discovered:22"}
+		err := WriteEndpoint(path, old, old)
+		require.EqualError(t, err, `endpoint "discovered" is not defined in "`+path+`"`)
+	})
+
+	t.Run("rename to existing", func(t *testing.T) {
+		path := writeTestConfig(t)
+		old := &wishlist.Endpoint{Name: "shell", Address: "shell:22"}
+		err := WriteEndpoint(path, old, &wishlist.Endpoint{Name: "db1", Address: "shell:22"})
+		require.EqualError(t, err, `endpoint "db1" is already defined in "`+path+`"`)
+	})
+}
+
+func TestSplitLine(t *testing.T) {
+	for line, expected := range map[string][3]string{
+		"Host foo":          {"", "Host", "foo"},
+		"  HostName = bar ": {"  ", "HostName", "bar"},
+		"\tUser=me":         {"\t", "User", "me"},
+		"  # a comment":     {"  ", "", ""},
+		"Include":           {"", "Include", ""},
+	} {
+		t.Run(line, func(t *testing.T) {
+			indent, keyword, value := splitLine(line)
+			require.Equal(t, expected, [3]string{indent, keyword, value})
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package wishlist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/wishlist/atomicfile"
+)
+
+// EndpointState is the persisted state of an endpoint.
+type EndpointState struct {
+	Pinned   bool      `json:"pinned,omitempty"`    // Whether the endpoint is pinned to the top of the list.
+	Count    int       `json:"count,omitempty"`     // How many times the endpoint was connected to.
+	LastUsed time.Time `json:"last_used,omitempty"` // Last time the endpoint was connected to.
+}
+
+// State persists per-endpoint usage information, such as pins, connection
+// counts and when they were last used, in a JSON file.
+//
+// A nil State is valid, and does nothing.
+type State struct {
+	path      string
+	lock      sync.Mutex
+	endpoints map[string]EndpointState
+}
+
+// OpenState opens the state in the given path.
+// If the file does not exist, an empty state is returned, and the file is
+// created when it first changes.
+func OpenState(path string) (*State, error) {
+	state := &State{
+		path:      path,
+		endpoints: map[string]EndpointState{},
+	}
+	bts, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return state, nil
+		}
+		return nil, fmt.Errorf("could not read state: %w", err)
+	}
+	if err := json.Unmarshal(bts, &state.endpoints); err != nil {
+		return nil, fmt.Errorf("could not parse state: %q: %w", path, err)
+	}
+	return state, nil
+}
+
+// Get returns the state of the given endpoint.
+func (s *State) Get(e *Endpoint) EndpointState {
+	if s == nil {
+		return EndpointState{}
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.endpoints[e.FullName()]
+}
+
+// Touch records a connection to the given endpoint.
+func (s *State) Touch(e *Endpoint) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(e, func(es *EndpointState) {
+		es.Count++
+		es.LastUsed = time.Now()
+	})
+}
+
+// TogglePin pins the given endpoint if it is not pinned, unpins it
+// otherwise.
+func (s *State) TogglePin(e *Endpoint) error {
+	if s == nil {
+		return nil
+	}
+	return s.update(e, func(es *EndpointState) {
+		es.Pinned = !es.Pinned
+	})
+}
+
+func (s *State) update(e *Endpoint, fn func(es *EndpointState)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	es := s.endpoints[e.FullName()]
+	fn(&es)
+	s.endpoints[e.FullName()] = es
+	return s.save()
+}
+
+// save atomically writes the state into its file.
+func (s *State) save() error {
+	bts, err := json.MarshalIndent(s.endpoints, "", "  ")
+	if err != nil {
+		return fmt.Errorf("could not encode state: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil { //nolint:mnd
+		return fmt.Errorf("could not create state dir: %w", err)
+	}
+	if err := atomicfile.Write(s.path, bts, 0o600); err != nil { //nolint:mnd
+		return fmt.Errorf("could not write state: %w", err)
+	}
+	return nil
+}
+
+// stateStore keeps one State per user, so concurrent sessions of the same
+// user share it.
+type stateStore struct {
+	dir    string
+	lock   sync.Mutex
+	states map[string]*State
+}
+
+func newStateStore(dir string) *stateStore {
+	return &stateStore{
+		dir:    dir,
+		states: map[string]*State{},
+	}
+}
+
+// get returns the state of the given user.
+func (s *stateStore) get(user string) (*State, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if state, ok := s.states[user]; ok {
+		return state, nil
+	}
+	state, err := OpenState(filepath.Join(s.dir, stateFileName(user)))
+	if err != nil {
+		return nil, err
+	}
+	s.states[user] = state
+	return state, nil
+}
+
+// stateFileName returns a safe file name for the given user.
+func stateFileName(user string) string {
+	if user == "" || user == "." || user == ".." || strings.ContainsAny(user, `/\`) {
+		sum := sha256.Sum256([]byte(user))
+		return hex.EncodeToString(sum[:]) + ".json"
+	}
+	return user + ".json"
+}
@@ -0,0 +1,74 @@
+package wishlist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sub", "state.json")
+	e1 := &Endpoint{Name: "foo", Group: "prod"}
+	e2 := &Endpoint{Name: "foo"}
+
+	state, err := OpenState(path)
+	require.NoError(t, err)
+	require.Equal(t, EndpointState{}, state.Get(e1))
+
+	require.NoError(t, state.Touch(e1))
+	require.NoError(t, state.Touch(e1))
+	require.NoError(t, state.TogglePin(e2))
+
+	require.Equal(t, 2, state.Get(e1).Count)
+	require.False(t, state.Get(e1).LastUsed.IsZero())
+	require.False(t, state.Get(e1).Pinned)
+	require.True(t, state.Get(e2).Pinned)
+	require.Zero(t, state.Get(e2).Count)
+
+	t.Run("reopen", func(t *testing.T) {
+		state, err := OpenState(path)
+		require.NoError(t, err)
+		require.Equal(t, 2, state.Get(e1).Count)
+		require.True(t, state.Get(e2).Pinned)
+
+		require.NoError(t, state.TogglePin(e2))
+		require.False(t, state.Get(e2).Pinned)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "state.json")
+		require.NoError(t, os.WriteFile(path, []byte("nope"), 0o600))
+		_, err := OpenState(path)
+		require.Error(t, err)
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		var state *State
+		require.NoError(t, state.Touch(e1))
+		require.NoError(t, state.TogglePin(e1))
+		require.Equal(t, EndpointState{}, state.Get(e1))
+	})
+}
+
+func TestStateStore(t *testing.T) {
+	store := newStateStore(t.TempDir())
+	s1, err := store.get("carlos")
+	require.NoError(t, err)
+	s2, err := store.get("carlos")
+	require.NoError(t, err)
+	s3, err := store.get("other")
+	require.NoError(t, err)
+	require.Same(t, s1, s2)
+	require.NotSame(t, s1, s3)
+}
+
+func TestStateFileName(t *testing.T) {
+	require.Equal(t, "carlos.json", stateFileName("carlos"))
+	for _, user := range []string{"", ".", "..", "../foo", `a\b`} {
+		name := stateFileName(user)
+		require.Equal(t, name, filepath.Base(name))
+		require.Len(t, name, 64+len(".json"))
+	}
+}
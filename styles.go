@@ -1,29 +1,112 @@
 package wishlist
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/lipgloss"
+)
 
 //nolint:mnd
-func makeStyles(r *lipgloss.Renderer) styles {
+func makeStyles(r *lipgloss.Renderer, theme Theme) styles {
+	colors := theme.colors()
 	return styles{
 		Logo: r.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#FFFDF5", Dark: "#FFFDF5"}).
-			Background(lipgloss.Color("#5A56E0")).
+			Foreground(colors.Logo.adaptive()).
+			Background(colors.LogoBackground.adaptive()).
 			Padding(0, 1).
-			SetString("Wishlist"),
+			SetString(FirstNonEmpty(theme.Logo, defaultLogo)),
 		Err: r.NewStyle().
 			Italic(true).
-			Foreground(lipgloss.AdaptiveColor{Light: "#FF4672", Dark: "#ED567A"}),
+			Foreground(colors.Error.adaptive()),
 		Footer: r.NewStyle().
-			Foreground(lipgloss.AdaptiveColor{Light: "#9B9B9B", Dark: "#5C5C5C"}),
+			Foreground(colors.Muted.adaptive()),
 		NoContent: r.NewStyle().Faint(true).Italic(true),
-		Doc:       r.NewStyle().Margin(1, 2),
+		Doc:       r.NewStyle().Margin(theme.margin()...),
+		Badge: r.NewStyle().
+			Foreground(colors.Badge.adaptive()).
+			Background(colors.BadgeBackground.adaptive()).
+			Padding(0, 1).
+			MarginRight(1),
+		Text:   r.NewStyle(),
+		Match:  r.NewStyle().Underline(true),
+		Header: r.NewStyle().Bold(true),
+		Success: r.NewStyle().
+			Foreground(colors.Success.adaptive()),
+		Detail: r.NewStyle().
+			Border(lipgloss.NormalBorder(), false).
+			BorderForeground(colors.Muted.adaptive()),
+		Title: FirstNonEmpty(theme.Title, defaultTitle),
+		List:  makeListStyles(r, colors),
+		Item:  makeItemStyles(r, colors),
 	}
 }
 
+// makeListStyles returns the list styles, using the given renderer and
+// colors.
+func makeListStyles(r *lipgloss.Renderer, colors ThemeColors) list.Styles {
+	s := list.DefaultStyles()
+	s.TitleBar = s.TitleBar.Renderer(r)
+	s.Title = s.Title.Renderer(r).
+		Foreground(colors.Title.adaptive()).
+		Background(colors.TitleBackground.adaptive())
+	s.Spinner = s.Spinner.Renderer(r)
+	s.FilterPrompt = s.FilterPrompt.Renderer(r)
+	s.FilterCursor = s.FilterCursor.Renderer(r).
+		Foreground(colors.Selected.adaptive())
+	s.DefaultFilterCharacterMatch = s.DefaultFilterCharacterMatch.Renderer(r)
+	s.StatusBar = s.StatusBar.Renderer(r).
+		Foreground(colors.Description.adaptive())
+	s.StatusEmpty = s.StatusEmpty.Renderer(r).
+		Foreground(colors.Muted.adaptive())
+	s.StatusBarActiveFilter = s.StatusBarActiveFilter.Renderer(r)
+	s.StatusBarFilterCount = s.StatusBarFilterCount.Renderer(r)
+	s.NoItems = s.NoItems.Renderer(r).
+		Foreground(colors.Muted.adaptive())
+	s.PaginationStyle = s.PaginationStyle.Renderer(r)
+	s.HelpStyle = s.HelpStyle.Renderer(r)
+	s.ActivePaginationDot = s.ActivePaginationDot.Renderer(r)
+	s.InactivePaginationDot = s.InactivePaginationDot.Renderer(r).
+		Foreground(colors.Muted.adaptive())
+	s.ArabicPagination = s.ArabicPagination.Renderer(r)
+	s.DividerDot = s.DividerDot.Renderer(r)
+	return s
+}
+
+// makeItemStyles returns the list item styles, using the given renderer and
+// colors.
+func makeItemStyles(r *lipgloss.Renderer, colors ThemeColors) list.DefaultItemStyles {
+	s := list.NewDefaultItemStyles()
+	s.NormalTitle = s.NormalTitle.Renderer(r)
+	s.NormalDesc = s.NormalDesc.Renderer(r).
+		Foreground(colors.Description.adaptive())
+	s.SelectedTitle = s.SelectedTitle.Renderer(r).
+		Foreground(colors.Selected.adaptive()).
+		BorderForeground(colors.SelectedDescription.adaptive())
+	s.SelectedDesc = s.SelectedDesc.Renderer(r).
+		Foreground(colors.SelectedDescription.adaptive()).
+		BorderForeground(colors.SelectedDescription.adaptive())
+	s.DimmedTitle = s.DimmedTitle.Renderer(r).
+		Foreground(colors.Muted.adaptive())
+	s.DimmedDesc = s.DimmedDesc.Renderer(r).
+		Foreground(colors.Muted.adaptive())
+	s.FilterMatch = s.FilterMatch.Renderer(r)
+	return s
+}
+
 type styles struct {
 	Logo      lipgloss.Style
 	Err       lipgloss.Style
 	Footer    lipgloss.Style
 	NoContent lipgloss.Style
 	Doc       lipgloss.Style
+	Badge     lipgloss.Style
+	Text      lipgloss.Style
+	Match     lipgloss.Style
+	Header    lipgloss.Style
+	Success   lipgloss.Style
+	Detail    lipgloss.Style
+
+	// Title of the list.
+	Title string
+	List  list.Styles
+	Item  list.DefaultItemStyles
 }
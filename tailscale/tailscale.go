@@ -61,6 +61,7 @@ func Endpoints(ctx context.Context, tailnet, key, clientID, clientSecret string)
 		endpoints = append(endpoints, &wishlist.Endpoint{
 			Name:    strings.Split(device.DeviceName, ".")[0],
 			Address: net.JoinHostPort(device.Addresses[0], "22"),
+			Tags:    deviceTags(device.Tags),
 		})
 	}
 
@@ -112,4 +113,17 @@ type device struct {
 	Authorized bool     `json:"Authorized"`
 	Hostname   string   `json:"hostname"`
 	DeviceName string   `json:"name"`
+	Tags       []string `json:"tags"`
+}
+
+// deviceTags removes the `tag:` prefix from tailscale ACL tags.
+func deviceTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, strings.TrimPrefix(tag, "tag:"))
+	}
+	return result
 }
@@ -0,0 +1,215 @@
+package wishlist
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/log"
+	"gopkg.in/yaml.v3"
+)
+
+// Theme presets.
+const (
+	ThemeDefault = "default"
+	ThemeDracula = "dracula"
+	ThemeNord    = "nord"
+	ThemeGruvbox = "gruvbox"
+)
+
+const (
+	defaultLogo  = "Wishlist"
+	defaultTitle = "Directory Listing"
+)
+
+// Theme configuration.
+type Theme struct {
+	// Preset the theme is based on, defaults to ThemeDefault.
+	Preset string `yaml:"preset"`
+	// Logo text, defaults to "Wishlist".
+	Logo string `yaml:"logo"`
+	// Title of the list, defaults to "Directory Listing".
+	Title string `yaml:"title"`
+	// Margin around the UI, with 1, 2 or 4 values, as in CSS.
+	// Defaults to 1 line and 2 columns.
+	Margin []int `yaml:"margin"`
+	// Colors override the preset colors.
+	Colors ThemeColors `yaml:"colors"`
+}
+
+// ThemeColors are the colors used by the UI.
+// Colors left empty are taken from the preset.
+type ThemeColors struct {
+	Logo                Color `yaml:"logo"`
+	LogoBackground      Color `yaml:"logo_background"`
+	Title               Color `yaml:"title"`
+	TitleBackground     Color `yaml:"title_background"`
+	Selected            Color `yaml:"selected"`
+	SelectedDescription Color `yaml:"selected_description"`
+	Description         Color `yaml:"description"`
+	Badge               Color `yaml:"badge"`
+	BadgeBackground     Color `yaml:"badge_background"`
+	Error               Color `yaml:"error"`
+	Success             Color `yaml:"success"`
+	Muted               Color `yaml:"muted"`
+}
+
+// Color is a color that can differ between light and dark backgrounds.
+//
+// In YAML, it can be either a single color, e.g. `"#5A56E0"` or `"62"`, or a
+// mapping with `light` and `dark` keys.
+type Color struct {
+	Light string `yaml:"light"`
+	Dark  string `yaml:"dark"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (c *Color) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Light, c.Dark = node.Value, node.Value
+		return nil
+	}
+	type color Color // avoids recursion
+	if err := node.Decode((*color)(c)); err != nil {
+		return fmt.Errorf("invalid color: %w", err)
+	}
+	return nil
+}
+
+// IsZero returns true if no color is set.
+func (c Color) IsZero() bool {
+	return c.Light == "" && c.Dark == ""
+}
+
+// or returns c if set, otherwise the fallback.
+func (c Color) or(fallback Color) Color {
+	if c.IsZero() {
+		return fallback
+	}
+	return c
+}
+
+// adaptive returns the color as a lipgloss.AdaptiveColor, using the same
+// color in both backgrounds if only one is set.
+func (c Color) adaptive() lipgloss.AdaptiveColor {
+	return lipgloss.AdaptiveColor{
+		Light: FirstNonEmpty(c.Light, c.Dark),
+		Dark:  FirstNonEmpty(c.Dark, c.Light),
+	}
+}
+
+// merge returns the colors, with the empty ones taken from the fallback.
+func (c ThemeColors) merge(fallback ThemeColors) ThemeColors {
+	return ThemeColors{
+		Logo:                c.Logo.or(fallback.Logo),
+		LogoBackground:      c.LogoBackground.or(fallback.LogoBackground),
+		Title:               c.Title.or(fallback.Title),
+		TitleBackground:     c.TitleBackground.or(fallback.TitleBackground),
+		Selected:            c.Selected.or(fallback.Selected),
+		SelectedDescription: c.SelectedDescription.or(fallback.SelectedDescription),
+		Description:         c.Description.or(fallback.Description),
+		Badge:               c.Badge.or(fallback.Badge),
+		BadgeBackground:     c.BadgeBackground.or(fallback.BadgeBackground),
+		Error:               c.Error.or(fallback.Error),
+		Success:             c.Success.or(fallback.Success),
+		Muted:               c.Muted.or(fallback.Muted),
+	}
+}
+
+// ThemePresets are the available theme presets.
+var ThemePresets = map[string]ThemeColors{
+	ThemeDefault: {
+		Logo:                Color{Light: "#FFFDF5", Dark: "#FFFDF5"},
+		LogoBackground:      Color{Light: "#5A56E0", Dark: "#5A56E0"},
+		Title:               Color{Light: "230", Dark: "230"},
+		TitleBackground:     Color{Light: "62", Dark: "62"},
+		Selected:            Color{Light: "#EE6FF8", Dark: "#EE6FF8"},
+		SelectedDescription: Color{Light: "#F793FF", Dark: "#AD58B4"},
+		Description:         Color{Light: "#A49FA5", Dark: "#777777"},
+		Badge:               Color{Light: "#FFFDF5", Dark: "#FFFDF5"},
+		BadgeBackground:     Color{Light: "#A49FA5", Dark: "#4D4A4E"},
+		Error:               Color{Light: "#FF4672", Dark: "#ED567A"},
+		Success:             Color{Light: "#02BA84", Dark: "#02BF87"},
+		Muted:               Color{Light: "#9B9B9B", Dark: "#5C5C5C"},
+	},
+	ThemeDracula: {
+		Logo:                Color{Light: "#282A36", Dark: "#282A36"},
+		LogoBackground:      Color{Light: "#BD93F9", Dark: "#BD93F9"},
+		Title:               Color{Light: "#282A36", Dark: "#282A36"},
+		TitleBackground:     Color{Light: "#FF79C6", Dark: "#FF79C6"},
+		Selected:            Color{Light: "#FF79C6", Dark: "#FF79C6"},
+		SelectedDescription: Color{Light: "#BD93F9", Dark: "#BD93F9"},
+		Description:         Color{Light: "#6272A4", Dark: "#6272A4"},
+		Badge:               Color{Light: "#282A36", Dark: "#282A36"},
+		BadgeBackground:     Color{Light: "#8BE9FD", Dark: "#8BE9FD"},
+		Error:               Color{Light: "#FF5555", Dark: "#FF5555"},
+		Success:             Color{Light: "#50FA7B", Dark: "#50FA7B"},
+		Muted:               Color{Light: "#6272A4", Dark: "#6272A4"},
+	},
+	ThemeNord: {
+		Logo:                Color{Light: "#2E3440", Dark: "#2E3440"},
+		LogoBackground:      Color{Light: "#88C0D0", Dark: "#88C0D0"},
+		Title:               Color{Light: "#ECEFF4", Dark: "#ECEFF4"},
+		TitleBackground:     Color{Light: "#5E81AC", Dark: "#5E81AC"},
+		Selected:            Color{Light: "#5E81AC", Dark: "#88C0D0"},
+		SelectedDescription: Color{Light: "#81A1C1", Dark: "#81A1C1"},
+		Description:         Color{Light: "#4C566A", Dark: "#D8DEE9"},
+		Badge:               Color{Light: "#2E3440", Dark: "#2E3440"},
+		BadgeBackground:     Color{Light: "#81A1C1", Dark: "#81A1C1"},
+		Error:               Color{Light: "#BF616A", Dark: "#BF616A"},
+		Success:             Color{Light: "#A3BE8C", Dark: "#A3BE8C"},
+		Muted:               Color{Light: "#D8DEE9", Dark: "#4C566A"},
+	},
+	ThemeGruvbox: {
+		Logo:                Color{Light: "#FBF1C7", Dark: "#282828"},
+		LogoBackground:      Color{Light: "#D79921", Dark: "#FABD2F"},
+		Title:               Color{Light: "#FBF1C7", Dark: "#282828"},
+		TitleBackground:     Color{Light: "#D65D0E", Dark: "#FE8019"},
+		Selected:            Color{Light: "#AF3A03", Dark: "#FE8019"},
+		SelectedDescription: Color{Light: "#B57614", Dark: "#FABD2F"},
+		Description:         Color{Light: "#928374", Dark: "#928374"},
+		Badge:               Color{Light: "#FBF1C7", Dark: "#282828"},
+		BadgeBackground:     Color{Light: "#427B58", Dark: "#8EC07C"},
+		Error:               Color{Light: "#9D0006", Dark: "#FB4934"},
+		Success:             Color{Light: "#79740E", Dark: "#B8BB26"},
+		Muted:               Color{Light: "#A89984", Dark: "#665C54"},
+	},
+}
+
+// Validate returns an error if the theme preset or margin are invalid.
+func (t Theme) Validate() error {
+	if _, ok := ThemePresets[FirstNonEmpty(t.Preset, ThemeDefault)]; !ok {
+		presets := make([]string, 0, len(ThemePresets))
+		for name := range ThemePresets {
+			presets = append(presets, name)
+		}
+		slices.Sort(presets)
+		return fmt.Errorf("invalid theme preset %q, valid presets are %v", t.Preset, presets)
+	}
+	if n := len(t.Margin); n != 0 && n != 1 && n != 2 && n != 4 {
+		return fmt.Errorf("invalid theme margin %v, it must have 1, 2 or 4 values", t.Margin)
+	}
+	return nil
+}
+
+// colors returns the theme colors, taking the missing ones from its preset.
+func (t Theme) colors() ThemeColors {
+	preset, ok := ThemePresets[FirstNonEmpty(t.Preset, ThemeDefault)]
+	if !ok {
+		log.Warn("invalid theme preset, using the default", "preset", t.Preset)
+		preset = ThemePresets[ThemeDefault]
+	}
+	return t.Colors.merge(preset)
+}
+
+// margin returns the theme margin.
+func (t Theme) margin() []int {
+	switch len(t.Margin) {
+	case 1, 2, 4: //nolint:mnd
+		return t.Margin
+	case 0:
+	default:
+		log.Warn("invalid theme margin, using the default", "margin", t.Margin)
+	}
+	return []int{1, 2} //nolint:mnd
+}
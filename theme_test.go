@@ -0,0 +1,80 @@
+package wishlist
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+func TestThemeYAML(t *testing.T) {
+	var theme Theme
+	require.NoError(t, yaml.Unmarshal([]byte(`
+preset: nord
+logo: My Servers
+title: Servers
+margin: [0, 1]
+colors:
+  selected: "#FF0000"
+  error:
+    light: "1"
+    dark: "9"
+  success:
+    dark: "10"
+`), &theme))
+	require.NoError(t, theme.Validate())
+	require.Equal(t, Theme{
+		Preset: ThemeNord,
+		Logo:   "My Servers",
+		Title:  "Servers",
+		Margin: []int{0, 1},
+		Colors: ThemeColors{
+			Selected: Color{Light: "#FF0000", Dark: "#FF0000"},
+			Error:    Color{Light: "1", Dark: "9"},
+			Success:  Color{Dark: "10"},
+		},
+	}, theme)
+
+	colors := theme.colors()
+	require.Equal(t, Color{Light: "#FF0000", Dark: "#FF0000"}, colors.Selected)
+	require.Equal(t, ThemePresets[ThemeNord].Badge, colors.Badge)
+	require.Equal(t, lipgloss.AdaptiveColor{Light: "10", Dark: "10"}, colors.Success.adaptive())
+
+	t.Run("invalid color", func(t *testing.T) {
+		require.Error(t, yaml.Unmarshal([]byte(`colors: {logo: [1, 2]}`), &theme))
+	})
+}
+
+func TestThemeValidate(t *testing.T) {
+	require.NoError(t, Theme{}.Validate())
+	for name := range ThemePresets {
+		require.NoError(t, Theme{Preset: name}.Validate())
+	}
+	require.EqualError(t, Theme{Preset: "nope"}.Validate(), `invalid theme preset "nope", valid presets are [default dracula gruvbox nord]`)
+	require.Error(t, Theme{Margin: []int{1, 2, 3}}.Validate())
+}
+
+func TestThemeMargin(t *testing.T) {
+	require.Equal(t, []int{1, 2}, Theme{}.margin())
+	require.Equal(t, []int{1, 2}, Theme{Margin: []int{1, 2, 3}}.margin())
+	require.Equal(t, []int{0}, Theme{Margin: []int{0}}.margin())
+}
+
+func TestWithTheme(t *testing.T) {
+	m := NewListing([]*Endpoint{
+		{Name: "foo", Address: "foo:22", Group: "prod"},
+	}, fakeClient{}, testRenderer, WithTheme(Theme{
+		Logo:   "Servers",
+		Title:  "All",
+		Margin: []int{0},
+	}))
+	require.Equal(t, "All", m.list.Title)
+	require.Equal(t, "Servers", m.styles.Logo.Value())
+	top, right, bottom, left := m.styles.Doc.GetMargin()
+	require.Equal(t, []int{0, 0, 0, 0}, []int{top, right, bottom, left})
+
+	_, _ = m.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	require.Equal(t, "All / prod", m.list.Title)
+}
@@ -0,0 +1,142 @@
+package wishlist
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/pkg/sftp"
+)
+
+// Copier is implemented by clients that can copy files from and to
+// endpoints.
+type Copier interface {
+	Copy(src, dst CopyPath) error
+}
+
+// CopyPath is a path to copy from or to, either on an endpoint or local.
+type CopyPath struct {
+	Endpoint *Endpoint // nil for local paths
+	Path     string
+}
+
+func (p CopyPath) String() string {
+	if p.Endpoint == nil {
+		return p.Path
+	}
+	return p.Endpoint.FullName() + ":" + p.Path
+}
+
+// ParseCopyPath parses a path in the `name:path` form, as scp does, where
+// name is the name of one of the endpoints.
+// Paths are local if they have no colon, or the part before it isn't the
+// name of any of the endpoints.
+// Remote paths are relative to the home directory, unless absolute.
+func ParseCopyPath(endpoints []*Endpoint, s string) CopyPath {
+	name, p, ok := strings.Cut(s, ":")
+	if !ok || name == "" {
+		return CopyPath{Path: s}
+	}
+	e := FindEndpoint(endpoints, name)
+	if e == nil {
+		return CopyPath{Path: s}
+	}
+	return CopyPath{Endpoint: e, Path: FirstNonEmpty(p, ".")}
+}
+
+// copyEndpoint returns the endpoint to copy from or to, as exactly one of the
+// paths should be on an endpoint.
+func copyEndpoint(src, dst CopyPath) (*Endpoint, error) {
+	switch {
+	case src.Endpoint != nil && dst.Endpoint != nil:
+		return nil, fmt.Errorf("copying between endpoints is not supported")
+	case src.Endpoint != nil:
+		return src.Endpoint, nil
+	case dst.Endpoint != nil:
+		return dst.Endpoint, nil
+	default:
+		return nil, fmt.Errorf("either %q or %q should be on an endpoint, e.g. name:path", src, dst)
+	}
+}
+
+// transfer copies the file from src to dst through the SFTP client, one of
+// them being on its endpoint.
+// If dst is a directory, the file is copied into it.
+func transfer(sc *sftp.Client, src, dst CopyPath) error {
+	if src.Endpoint != nil {
+		return download(sc, src.Path, dst.Path)
+	}
+	return upload(sc, src.Path, dst.Path)
+}
+
+// download copies the remote file src into the local file dst.
+func download(sc *sftp.Client, src, dst string) error {
+	in, err := sc.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", src, err)
+	}
+	defer in.Close() //nolint:errcheck
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %w", src, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%q is a directory", src)
+	}
+
+	if fi, err := os.Stat(dst); err == nil && fi.IsDir() {
+		dst = filepath.Join(dst, path.Base(src))
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", dst, err)
+	}
+	n, err := io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy %q to %q: %w", src, dst, err)
+	}
+	log.Info("downloaded", "src", src, "dst", dst, "bytes", n)
+	return out.Close() //nolint:wrapcheck
+}
+
+// upload copies the local file src into the remote file dst.
+func upload(sc *sftp.Client, src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", src, err)
+	}
+	defer in.Close() //nolint:errcheck
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %q: %w", src, err)
+	}
+	if info.IsDir() {
+		return fmt.Errorf("%q is a directory", src)
+	}
+
+	if fi, err := sc.Stat(dst); err == nil && fi.IsDir() {
+		dst = path.Join(dst, filepath.Base(src))
+	} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat %q: %w", dst, err)
+	}
+	out, err := sc.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return fmt.Errorf("failed to create %q: %w", dst, err)
+	}
+	n, err := io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy %q to %q: %w", src, dst, err)
+	}
+	if err := out.Chmod(info.Mode().Perm()); err != nil {
+		log.Warn("could not set file mode", "path", dst, "err", err)
+	}
+	log.Info("uploaded", "src", src, "dst", dst, "bytes", n)
+	return out.Close() //nolint:wrapcheck
+}
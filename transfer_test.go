@@ -0,0 +1,151 @@
+package wishlist
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestParseCopyPath(t *testing.T) {
+	db1 := &Endpoint{Name: "db1", Group: "prod", Address: "db1.local:22"}
+	app1 := &Endpoint{Name: "app1", Address: "app1.local:22"}
+	endpoints := []*Endpoint{db1, app1}
+
+	for s, expected := range map[string]CopyPath{
+		"db1:/tmp/dump.sql":      {Endpoint: db1, Path: "/tmp/dump.sql"},
+		"prod/db1:dump.sql":      {Endpoint: db1, Path: "dump.sql"},
+		"app1:":                  {Endpoint: app1, Path: "."},
+		"app1:a:b":               {Endpoint: app1, Path: "a:b"},
+		"./dump.sql":             {Path: "./dump.sql"},
+		"db2:dump.sql":           {Path: "db2:dump.sql"},
+		":dump.sql":              {Path: ":dump.sql"},
+		"/var/backups/dump.sql":  {Path: "/var/backups/dump.sql"},
+		"backups/2024:01:01.sql": {Path: "backups/2024:01:01.sql"},
+	} {
+		t.Run(s, func(t *testing.T) {
+			require.Equal(t, expected, ParseCopyPath(endpoints, s))
+		})
+	}
+}
+
+func TestCopyEndpoint(t *testing.T) {
+	e := &Endpoint{Name: "db1"}
+
+	got, err := copyEndpoint(CopyPath{Endpoint: e, Path: "a"}, CopyPath{Path: "b"})
+	require.NoError(t, err)
+	require.Equal(t, e, got)
+
+	got, err = copyEndpoint(CopyPath{Path: "a"}, CopyPath{Endpoint: e, Path: "b"})
+	require.NoError(t, err)
+	require.Equal(t, e, got)
+
+	_, err = copyEndpoint(CopyPath{Endpoint: e, Path: "a"}, CopyPath{Endpoint: e, Path: "b"})
+	require.EqualError(t, err, "copying between endpoints is not supported")
+
+	_, err = copyEndpoint(CopyPath{Path: "a"}, CopyPath{Path: "b"})
+	require.EqualError(t, err, `either "a" or "b" should be on an endpoint, e.g. name:path`)
+}
+
+func TestTransfer(t *testing.T) {
+	remote := t.TempDir()
+	local := t.TempDir()
+	sc := sftpClient(t, sftpServer(t, remote))
+	e := &Endpoint{Name: "db1"}
+
+	t.Run("upload", func(t *testing.T) {
+		src := filepath.Join(local, "up.txt")
+		require.NoError(t, os.WriteFile(src, []byte("up"), 0o640))
+		require.NoError(t, transfer(sc, CopyPath{Path: src}, CopyPath{Endpoint: e, Path: "up.txt"}))
+		requireFile(t, filepath.Join(remote, "up.txt"), "up", 0o640)
+	})
+
+	t.Run("upload into directory", func(t *testing.T) {
+		src := filepath.Join(local, "into.txt")
+		require.NoError(t, os.WriteFile(src, []byte("into"), 0o600))
+		require.NoError(t, os.Mkdir(filepath.Join(remote, "sub"), 0o755))
+		require.NoError(t, transfer(sc, CopyPath{Path: src}, CopyPath{Endpoint: e, Path: "sub"}))
+		requireFile(t, filepath.Join(remote, "sub", "into.txt"), "into", 0o600)
+	})
+
+	t.Run("download", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(remote, "down.txt"), []byte("down"), 0o644))
+		dst := filepath.Join(local, "downloaded.txt")
+		require.NoError(t, transfer(sc, CopyPath{Endpoint: e, Path: "down.txt"}, CopyPath{Path: dst}))
+		requireFile(t, dst, "down", 0o644)
+	})
+
+	t.Run("download into directory", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(remote, "log.txt"), []byte("log"), 0o600))
+		require.NoError(t, transfer(sc, CopyPath{Endpoint: e, Path: "log.txt"}, CopyPath{Path: local}))
+		requireFile(t, filepath.Join(local, "log.txt"), "log", 0o600)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		err := transfer(sc, CopyPath{Path: local}, CopyPath{Endpoint: e, Path: "sub"})
+		require.ErrorContains(t, err, "is a directory")
+		err = transfer(sc, CopyPath{Endpoint: e, Path: "sub"}, CopyPath{Path: local})
+		require.ErrorContains(t, err, "is a directory")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := transfer(sc, CopyPath{Endpoint: e, Path: "nope.txt"}, CopyPath{Path: local})
+		require.ErrorIs(t, err, os.ErrNotExist)
+		err = transfer(sc, CopyPath{Path: filepath.Join(local, "nope.txt")}, CopyPath{Endpoint: e, Path: "nope.txt"})
+		require.ErrorIs(t, err, os.ErrNotExist)
+	})
+}
+
+// requireFile expects the file to have the given content and permissions.
+func requireFile(tb testing.TB, path, content string, perm os.FileMode) {
+	tb.Helper()
+	bts, err := os.ReadFile(path)
+	require.NoError(tb, err)
+	require.Equal(tb, content, string(bts))
+	info, err := os.Stat(path)
+	require.NoError(tb, err)
+	require.Equal(tb, perm, info.Mode().Perm())
+}
+
+// sftpClient returns an SFTP client connected to the given server.
+func sftpClient(tb testing.TB, addr string) *sftp.Client {
+	tb.Helper()
+	client, err := gossh.Dial("tcp", addr, &gossh.ClientConfig{
+		User:            "carlos",
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+	})
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = client.Close() })
+	sc, err := sftp.NewClient(client)
+	require.NoError(tb, err)
+	tb.Cleanup(func() { _ = sc.Close() })
+	return sc
+}
+
+// sftpServer starts an SSH server with the sftp subsystem, whose working
+// directory is the given one, and returns its address.
+func sftpServer(tb testing.TB, dir string) string {
+	tb.Helper()
+	srv := &ssh.Server{
+		Handler: func(ssh.Session) {},
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{
+			"sftp": func(s ssh.Session) {
+				server, err := sftp.NewServer(s, sftp.WithServerWorkingDirectory(dir))
+				if err != nil {
+					return
+				}
+				_ = server.Serve()
+			},
+		},
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(tb, err)
+	go func() { _ = srv.Serve(l) }()
+	tb.Cleanup(func() { _ = srv.Close() })
+	return l.Addr().String()
+}
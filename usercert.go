@@ -0,0 +1,106 @@
+package wishlist
+
+import (
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/log"
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// source-address critical option of certificates, which x/crypto leaves to
+// the server to enforce.
+const certSourceAddress = "source-address"
+
+// parseUserCAKeys parses the trusted user CA keys, in the authorized_keys
+// format.
+func parseUserCAKeys(keys []string) ([]gossh.PublicKey, error) {
+	result := make([]gossh.PublicKey, 0, len(keys))
+	for _, key := range keys {
+		ca, _, _, _, err := gossh.ParseAuthorizedKey([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted user CA key %q: %w", key, err)
+		}
+		result = append(result, ca)
+	}
+	return result, nil
+}
+
+// userCertChecker returns a checker of certificates signed by any of the
+// given CAs, or nil if there are none.
+func userCertChecker(cas []gossh.PublicKey) *gossh.CertChecker {
+	if len(cas) == 0 {
+		return nil
+	}
+	return &gossh.CertChecker{
+		IsUserAuthority: func(auth gossh.PublicKey) bool {
+			for _, ca := range cas {
+				if ssh.KeysEqual(ca, auth) {
+					return true
+				}
+			}
+			return false
+		},
+	}
+}
+
+// checkUserCert returns an error if the certificate can't be used to log in
+// as the context user.
+//
+// As in OpenSSH, the user must be one of the certificate principals, and it
+// must be valid now and from the remote address.
+// If there are users in the config, the user must also be one of them, so
+// their permissions apply.
+func checkUserCert(checker *gossh.CertChecker, ctx ssh.Context, cert *gossh.Certificate, users []User) error {
+	if cert.CertType != gossh.UserCert {
+		return fmt.Errorf("not a user certificate")
+	}
+	if !checker.IsUserAuthority(cert.SignatureKey) {
+		return fmt.Errorf("certificate signed by an untrusted CA")
+	}
+	if len(cert.ValidPrincipals) == 0 {
+		return fmt.Errorf("certificate has no principals")
+	}
+	if err := checker.CheckCert(ctx.User(), cert); err != nil {
+		return fmt.Errorf("invalid certificate: %w", err)
+	}
+	if opt, ok := cert.CriticalOptions[certSourceAddress]; ok {
+		if err := checkSourceAddress(ctx.RemoteAddr(), opt); err != nil {
+			return err
+		}
+	}
+	if len(users) > 0 && !slices.ContainsFunc(users, func(u User) bool { return u.Name == ctx.User() }) {
+		return fmt.Errorf("user %q not found", ctx.User())
+	}
+	return nil
+}
+
+// checkSourceAddress returns an error if the address is not in the
+// comma-separated list of addresses or CIDRs.
+func checkSourceAddress(addr net.Addr, sourceAddrs string) error {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return fmt.Errorf("certificate source-address: unknown remote address %q", addr)
+	}
+	for _, source := range strings.Split(sourceAddrs, ",") {
+		source = strings.TrimSpace(source)
+		if ip := net.ParseIP(source); ip != nil {
+			if ip.Equal(tcp.IP) {
+				return nil
+			}
+			continue
+		}
+		_, ipnet, err := net.ParseCIDR(source)
+		if err != nil {
+			log.Warn("invalid certificate source-address", "source", source, "err", err)
+			continue
+		}
+		if ipnet.Contains(tcp.IP) {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate source-address: %q not in %q", tcp.IP, sourceAddrs)
+}
@@ -0,0 +1,108 @@
+package wishlist
+
+import (
+	"crypto/rand"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/keygen"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func TestPublicKeyHandlerCertificates(t *testing.T) {
+	ca := newTestSigner(t)
+	otherCA := newTestSigner(t)
+	user := newTestSigner(t)
+	caKey := string(gossh.MarshalAuthorizedKey(ca.PublicKey()))
+
+	sign := func(t *testing.T, signer gossh.Signer, fn func(cert *gossh.Certificate)) *gossh.Certificate {
+		t.Helper()
+		cert := &gossh.Certificate{
+			Key:             user.PublicKey(),
+			CertType:        gossh.UserCert,
+			KeyId:           "carlos@example.com",
+			ValidPrincipals: []string{"test"},
+			ValidAfter:      uint64(time.Now().Add(-time.Hour).Unix()),
+			ValidBefore:     uint64(time.Now().Add(time.Hour).Unix()),
+		}
+		if fn != nil {
+			fn(cert)
+		}
+		require.NoError(t, cert.SignCert(rand.Reader, signer))
+		return cert
+	}
+	ctx := addrCtx{addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.5"), Port: 51234}}
+
+	t.Run("no users", func(t *testing.T) {
+		handler := publicKeyAccessOption(nil, []string{caKey}, nil, nil)
+		require.NotNil(t, handler)
+		require.True(t, handler(ctx, sign(t, ca, nil)))
+		require.False(t, handler(ctx, user.PublicKey()))
+	})
+
+	for name, tc := range map[string]struct {
+		users   []User
+		signer  gossh.Signer
+		edit    func(cert *gossh.Certificate)
+		allowed bool
+	}{
+		"valid":            {users: []User{{Name: "test"}}, signer: ca, allowed: true},
+		"untrusted ca":     {users: []User{{Name: "test"}}, signer: otherCA},
+		"unknown user":     {users: []User{{Name: "carlos"}}, signer: ca},
+		"wrong principal":  {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.ValidPrincipals = []string{"root"} }},
+		"no principals":    {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.ValidPrincipals = nil }},
+		"expired":          {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.ValidBefore = uint64(time.Now().Add(-time.Minute).Unix()) }},
+		"not yet valid":    {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.ValidAfter = uint64(time.Now().Add(time.Minute).Unix()) }},
+		"host certificate": {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.CertType = gossh.HostCert }},
+		"force command":    {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.CriticalOptions = map[string]string{"force-command": "uptime"} }},
+		"source address": {users: []User{{Name: "test"}}, signer: ca, allowed: true, edit: func(c *gossh.Certificate) {
+			c.CriticalOptions = map[string]string{"source-address": "192.168.0.1,10.0.0.0/8"}
+		}},
+		"invalid source address": {users: []User{{Name: "test"}}, signer: ca, edit: func(c *gossh.Certificate) { c.CriticalOptions = map[string]string{"source-address": "192.168.0.0/16"} }},
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := publicKeyAccessOption(tc.users, []string{caKey}, nil, nil)
+			require.Equal(t, tc.allowed, handler(ctx, sign(t, tc.signer, tc.edit)))
+		})
+	}
+
+	t.Run("keys still work", func(t *testing.T) {
+		handler := publicKeyAccessOption([]User{{
+			Name:       "test",
+			PublicKeys: []string{string(gossh.MarshalAuthorizedKey(user.PublicKey()))},
+		}}, []string{caKey}, nil, nil)
+		require.True(t, handler(ctx, user.PublicKey()))
+	})
+}
+
+func TestParseUserCAKeys(t *testing.T) {
+	ca := newTestSigner(t)
+	keys, err := parseUserCAKeys([]string{string(gossh.MarshalAuthorizedKey(ca.PublicKey()))})
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	_, err = parseUserCAKeys([]string{"nope"})
+	require.ErrorContains(t, err, `invalid trusted user CA key "nope"`)
+}
+
+// newTestSigner returns a new ed25519 signer.
+func newTestSigner(tb testing.TB) gossh.Signer {
+	tb.Helper()
+	kp, err := keygen.New("", keygen.WithKeyType(keygen.Ed25519))
+	require.NoError(tb, err)
+	signer, err := gossh.NewSignerFromKey(kp.PrivateKey())
+	require.NoError(tb, err)
+	return signer
+}
+
+// addrCtx is a fakeCtx with the given remote address.
+type addrCtx struct {
+	fakeCtx
+	addr net.Addr
+}
+
+func (ctx addrCtx) RemoteAddr() net.Addr {
+	return ctx.addr
+}
@@ -3,45 +3,128 @@ package wishlist
 import (
 	"errors"
 	"fmt"
-	"net"
 
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/log"
-	"github.com/muesli/termenv"
 )
 
-var (
-	copyIPAddr = key.NewBinding(
-		key.WithKeys("y"),
-		key.WithHelp("y", "copy address"),
-	)
-	enter = key.NewBinding(
-		key.WithKeys("enter", "o"),
-		key.WithHelp("enter/o", "connect"),
-	)
+// view is the view currently being shown by the ListModel.
+type view int
+
+const (
+	viewList view = iota
+	viewPrompt
+	viewResults
+	viewHostKey
+	viewForm
+	viewCommands
+	viewCopy
 )
 
+// ListingOption can be used to customize a ListModel.
+type ListingOption func(*ListModel)
+
+// WithFilterFields sets the fields free text filter terms are matched against,
+// in order of preference.
+// Invalid fields are ignored, and if none are valid, DefaultFilterFields
+// are used.
+func WithFilterFields(fields ...string) ListingOption {
+	return func(m *ListModel) {
+		m.filterFields = validFilterFields(fields)
+	}
+}
+
+// WithState sets the state used to pin and sort endpoints.
+func WithState(state *State) ListingOption {
+	return func(m *ListModel) {
+		m.state = state
+	}
+}
+
+// WithTheme sets the theme of the listing.
+func WithTheme(theme Theme) ListingOption {
+	return func(m *ListModel) {
+		m.theme = theme
+	}
+}
+
+// WithKeys remaps the key bindings of the listing.
+// The keys should be validated beforehand with Keys.Validate.
+func WithKeys(keys Keys) ListingOption {
+	return func(m *ListModel) {
+		m.keys = newKeyMap(keys)
+	}
+}
+
+// WithHostKeyReplace sets whether users can replace changed host keys in the
+// known hosts file. It's enabled by default.
+func WithHostKeyReplace(enabled bool) ListingOption {
+	return func(m *ListModel) {
+		m.hostKeyReplace = enabled
+	}
+}
+
+// WithEndpointWriter allows to add and edit endpoints from the listing,
+// persisting them with the given writer.
+func WithEndpointWriter(w EndpointWriter) ListingOption {
+	return func(m *ListModel) {
+		m.writer = w
+	}
+}
+
 // NewListing creates a new listing model for the given endpoints and SSH session.
 // If session is nil, it is assume to be a local listing.
-func NewListing(endpoints []*Endpoint, client SSHClient, r *lipgloss.Renderer) *ListModel {
+func NewListing(endpoints []*Endpoint, client SSHClient, r *lipgloss.Renderer, opts ...ListingOption) *ListModel {
 	l := list.New(nil, list.NewDefaultDelegate(), 0, 0)
-	l.Title = "Directory Listing"
-	l.AdditionalFullHelpKeys = func() []key.Binding {
-		return []key.Binding{copyIPAddr}
-	}
-	l.AdditionalShortHelpKeys = func() []key.Binding {
-		return []key.Binding{enter}
-	}
+
+	prompt := textinput.New()
+	prompt.Placeholder = "uptime"
+	prompt.Prompt = "$ "
 
 	m := &ListModel{
-		list:      l,
-		endpoints: endpoints,
-		client:    client,
-		styles:    makeStyles(r),
+		list:         l,
+		endpoints:    endpoints,
+		client:       client,
+		filterFields: DefaultFilterFields,
+		marked:       map[string]bool{},
+		prompt:       prompt,
+		detail:       &detailPane{renderer: r},
+		keys:         defaultKeyMap(),
+		clipboard:    r.Output().Copy,
+
+		hostKeyReplace: true,
+	}
+	m.list.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{m.keys.Connect}
 	}
+	m.list.AdditionalFullHelpKeys = func() []key.Binding {
+		sortHelp := m.keys.Sort
+		sortHelp.SetHelp(sortHelp.Help().Key, "sort: "+m.sort.String())
+		keys := []key.Binding{
+			m.keys.Copy,
+			m.keys.Back,
+			m.keys.Pin,
+			sortHelp,
+			m.keys.Mark,
+			m.keys.Run,
+			m.keys.Details,
+		}
+		if m.writer != nil {
+			keys = append(keys, m.keys.Add, m.keys.Edit)
+		}
+		return keys
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	m.list.KeyMap = m.keys.List
+	m.styles = makeStyles(r, m.theme)
+	m.list.Styles = m.styles.List
+	m.list.Title = m.styles.Title
 	m.SetItems(endpoints)
 	return m
 }
@@ -53,24 +136,120 @@ type ListModel struct {
 	client    SSHClient
 	quitting  bool
 	width     int
+	height    int
 	err       error
+	theme     Theme
 	styles    styles
+	keys      keyMap
+
+	// group currently being listed, empty being the root.
+	group string
+
+	// fields free text filter terms are matched against.
+	filterFields []string
+
+	// state used to pin and sort endpoints, might be nil.
+	state *State
+	sort  sortMode
+
+	// endpoints marked to run a command on, by full name.
+	marked map[string]bool
+
+	view      view
+	prompt    textinput.Model
+	broadcast *broadcastRun
+
+	// latest probe results, nil if endpoints are not being probed.
+	probes ProbeResults
+
+	// whether the detail pane of the selected endpoint is shown.
+	showDetail bool
+	detail     *detailPane
+
+	// host key change being resolved, and whether it can be replaced.
+	hostKey        *hostKeyPrompt
+	hostKeyReplace bool
+
+	// writer of added and edited endpoints, nil if editing is not allowed.
+	writer EndpointWriter
+	form   *endpointForm
+
+	// commands menu of the endpoint being connected to.
+	commands *commandMenu
+
+	// copy menu of the selected endpoint, and how to copy into the
+	// clipboard of the user's terminal.
+	copying   *copyMenu
+	clipboard func(string)
 }
 
 // SetItems allows to update the listing items.
 func (m *ListModel) SetItems(endpoints []*Endpoint) tea.Cmd {
-	descriptors := features(endpoints)
+	m.endpoints = endpoints
+	if m.group != "" && !hasGroup(endpoints, m.group) {
+		m.group = ""
+	}
+	m.unmarkMissing()
+	return m.refresh()
+}
+
+// refresh sets the list items to the ones in the current group.
+func (m *ListModel) refresh() tea.Cmd {
+	descriptors := features(m.endpoints)
 	h := len(descriptors) + 1 // desc lines + title
-	d := list.NewDefaultDelegate()
+	d := itemDelegate{
+		DefaultDelegate: list.NewDefaultDelegate(),
+		fields:          m.filterFields,
+	}
+	d.Styles = m.styles.Item
 	d.SetHeight(h)
 	m.list.SetDelegate(d)
 	log.Debug("setting delegate height", "height", h)
-	return m.list.SetItems(endpointsToListItems(endpoints, descriptors, m.styles))
+	m.list.Title = breadcrumb(m.styles.Title, m.group)
+	if n := len(m.markedNames()); n > 0 {
+		m.list.Title += fmt.Sprintf(" (%d marked)", n)
+	}
+	endpoints := sortEndpoints(inGroup(m.endpoints, m.group), m.state, m.probes, m.sort)
+	items := append(
+		groupsToListItems(m.endpoints, m.group, len(descriptors)),
+		endpointsToListItems(endpoints, descriptors, m.styles)...,
+	)
+	for i, item := range items {
+		if w, ok := item.(ItemWrapper); ok {
+			w.pinned = m.state.Get(w.endpoint).Pinned
+			w.marked = m.marked[w.endpoint.FullName()]
+			if r, ok := m.probes[w.endpoint.FullName()]; ok {
+				w.probe = &r
+			}
+			items[i] = w
+		}
+	}
+	m.list.Filter = queryFilter(items, m.filterFields)
+	return m.list.SetItems(items)
+}
+
+// selectEndpoint moves the cursor to the given endpoint, if it is listed.
+func (m *ListModel) selectEndpoint(e *Endpoint) {
+	for i, item := range m.list.VisibleItems() {
+		if w, ok := item.(ItemWrapper); ok && w.endpoint == e {
+			m.list.Select(i)
+			return
+		}
+	}
+}
+
+// enterGroup changes the current group and resets the list state.
+func (m *ListModel) enterGroup(group string) tea.Cmd {
+	m.group = group
+	m.list.ResetFilter()
+	m.list.ResetSelected()
+	return m.refresh()
 }
 
 func features(endpoints []*Endpoint) []descriptor {
 	var hasDesc bool
 	var hasLink bool
+	var hasTags bool
 	for _, endpoint := range endpoints {
 		if !endpoint.Valid() {
 			continue
@@ -81,19 +260,25 @@ func features(endpoints []*Endpoint) []descriptor {
 		if endpoint.Link.URL != "" {
 			hasLink = true
 		}
-		if hasDesc && hasLink {
+		if len(endpoint.Tags) > 0 {
+			hasTags = true
+		}
+		if hasDesc && hasLink && hasTags {
 			break
 		}
 	}
 
 	var descriptors []descriptor
 	if hasDesc {
-		descriptors = append(descriptors, withDescription)
+		descriptors = append(descriptors, descDescription)
 	}
 	if hasLink {
-		descriptors = append(descriptors, withLink)
+		descriptors = append(descriptors, descLink)
+	}
+	if hasTags {
+		descriptors = append(descriptors, descTags)
 	}
-	return append(descriptors, withSSHURL)
+	return append(descriptors, descSSHURL)
 }
 
 func endpointsToListItems(endpoints []*Endpoint, descriptors []descriptor, styles styles) []list.Item {
@@ -133,48 +318,125 @@ func (m *ListModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.err = nil
 			return m, nil
 		}
-		if key.Matches(msg, list.DefaultKeyMap().Quit) && !m.list.SettingFilter() && m.list.FilterState() != list.FilterApplied {
+		if m.view != viewList && key.Matches(msg, m.keys.List.ForceQuit) {
+			if m.broadcast != nil {
+				m.broadcast.cancel()
+			}
 			m.quitting = true
+			return m, tea.Quit
 		}
-		if key.Matches(msg, copyIPAddr) && !m.list.SettingFilter() {
-			if w := m.selected(); w != nil {
-				host, _, _ := net.SplitHostPort(w.endpoint.Address)
-				termenv.Copy(host)
-				return m, m.list.NewStatusMessage(fmt.Sprintf("copied %q to the clipboard", host))
+		switch m.view {
+		case viewPrompt:
+			return m, m.updatePrompt(msg)
+		case viewResults:
+			return m, m.updateResults(msg)
+		case viewHostKey:
+			return m, m.updateHostKey(msg)
+		case viewForm:
+			return m, m.updateForm(msg)
+		case viewCommands:
+			return m, m.updateCommands(msg)
+		case viewCopy:
+			return m, m.updateCopy(msg)
+		case viewList:
+		}
+		if key.Matches(msg, m.list.KeyMap.Quit) && !m.list.SettingFilter() && m.list.FilterState() != list.FilterApplied {
+			m.quitting = true
+		}
+		if key.Matches(msg, m.keys.Copy) && !m.list.SettingFilter() {
+			return m, m.startCopy()
+		}
+		if key.Matches(msg, m.keys.Pin) && !m.list.SettingFilter() {
+			w := m.selected()
+			if w == nil {
+				return m, nil
 			}
-
+			if err := m.state.TogglePin(w.endpoint); err != nil {
+				m.err = err
+				return m, nil
+			}
+			cmd := m.refresh()
+			m.selectEndpoint(w.endpoint)
+			return m, cmd
+		}
+		if key.Matches(msg, m.keys.Sort) && !m.list.SettingFilter() {
+			m.sort = m.sort.next(m.probes != nil)
+			return m, tea.Batch(
+				m.refresh(),
+				m.list.NewStatusMessage("sorting by "+m.sort.String()),
+			)
+		}
+		if key.Matches(msg, m.keys.Mark) && !m.list.SettingFilter() {
+			return m, m.toggleMark()
+		}
+		if key.Matches(msg, m.keys.Run) && !m.list.SettingFilter() {
+			return m, m.startPrompt()
+		}
+		if key.Matches(msg, m.keys.Add) && !m.list.SettingFilter() {
+			return m, m.startForm(false)
+		}
+		if key.Matches(msg, m.keys.Edit) && !m.list.SettingFilter() {
+			return m, m.startForm(true)
+		}
+		if key.Matches(msg, m.keys.Details) && !m.list.SettingFilter() {
+			m.showDetail = !m.showDetail
+			m.resize()
 			return m, nil
 		}
-		if key.Matches(msg, enter) {
+		if key.Matches(msg, m.keys.Back) && !m.list.SettingFilter() && m.list.FilterState() != list.FilterApplied && m.group != "" {
+			return m, m.enterGroup(parentGroup(m.group))
+		}
+		if key.Matches(msg, m.keys.Connect) {
 			if m.list.SettingFilter() {
 				break
 			}
+			if g, ok := m.list.SelectedItem().(groupItem); ok {
+				return m, m.enterGroup(g.path)
+			}
 			w := m.selected()
 			if w == nil {
 				return m, nil
 			}
-			cmd := m.client.For(w.endpoint)
-			return m, tea.Exec(cmd, func(err error) tea.Msg {
-				return errMsg{err}
-			})
+			return m, m.connect(w.endpoint)
 		}
 
 	case tea.WindowSizeMsg:
-		top, right, bottom, left := m.styles.Doc.GetMargin()
+		_, right, _, left := m.styles.Doc.GetMargin()
 		m.width = msg.Width
-		m.list.SetSize(msg.Width-left-right, msg.Height-top-bottom)
+		m.height = msg.Height
+		m.resize()
+		m.prompt.Width = msg.Width - left - right
+		m.resizeResults()
+		m.resizeForm()
 
 	case SetEndpointsMsg:
 		if cmd := m.SetItems(msg.Endpoints); cmd != nil {
 			return m, cmd
 		}
 
+	case ProbeResultsMsg:
+		w := m.selected()
+		m.probes = msg.Results
+		cmd := m.refresh()
+		if w != nil {
+			m.selectEndpoint(w.endpoint)
+		}
+		return m, cmd
+
+	case commandResultMsg:
+		return m, m.handleCommandResult(msg)
+
 	case errMsg:
+		// usage might have changed, which might change the order.
+		cmd := m.refresh()
+		if m.handleHostKeyError(msg.err) {
+			return m, cmd
+		}
 		if msg.err != nil {
 			log.Warn("got an error", "err", msg.err)
 			m.err = msg.err
-			return m, nil
 		}
+		return m, cmd
 	}
 
 	var cmd tea.Cmd
@@ -202,7 +464,7 @@ func (m *ListModel) View() string {
 	}
 
 	if m.err != nil {
-		header := lipgloss.NewStyle().
+		header := m.styles.Text.
 			Width(m.width).
 			Render("Something went wrong:")
 		errstr := m.styles.Err.
@@ -216,7 +478,22 @@ func (m *ListModel) View() string {
 			errstr + "\n\n" +
 			footer + "\n"
 	}
-	return m.styles.Doc.Render(m.list.View())
+	switch m.view {
+	case viewPrompt:
+		return m.promptView()
+	case viewResults:
+		return m.resultsView()
+	case viewHostKey:
+		return m.hostKeyView()
+	case viewForm:
+		return m.formView()
+	case viewCommands:
+		return m.commandsView()
+	case viewCopy:
+		return m.copyView()
+	case viewList:
+	}
+	return m.styles.Doc.Render(m.listView())
 }
 
 func rootCause(err error) error {
@@ -2,8 +2,10 @@ package wishlist
 
 import (
 	"fmt"
+	"path/filepath"
 	"testing"
 
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/require"
 )
 
@@ -16,7 +18,7 @@ func TestEndointToListItems(t *testing.T) {
 		{
 			// invalid
 		},
-	}, nil, makeStyles(testRenderer))
+	}, nil, makeStyles(testRenderer, Theme{}))
 
 	require.Len(t, result, 1)
 	item := result[0]
@@ -60,9 +62,14 @@ func TestFeatures(t *testing.T) {
 					URL: "link",
 				},
 			},
+			{
+				Name:    "foo",
+				Address: "foo:22",
+				Tags:    []string{"prod"},
+			},
 		})
 
-		require.Len(t, descriptors, 3)
+		require.Len(t, descriptors, 4)
 	})
 
 	t.Run("simple", func(t *testing.T) {
@@ -90,6 +97,17 @@ func TestFeatures(t *testing.T) {
 		require.Len(t, descriptors, 2)
 	})
 
+	t.Run("with tags", func(t *testing.T) {
+		descriptors := features([]*Endpoint{
+			{
+				Name:    "foo",
+				Address: "foo:22",
+				Tags:    []string{"prod"},
+			},
+		})
+		require.Len(t, descriptors, 2)
+	})
+
 	t.Run("with link", func(t *testing.T) {
 		descriptors := features([]*Endpoint{
 			{
@@ -127,3 +145,20 @@ func TestFirstNonEmpty(t *testing.T) {
 	require.Equal(t, "a", FirstNonEmpty("", "a"))
 	require.Equal(t, "", FirstNonEmpty("", ""))
 }
+
+func TestPin(t *testing.T) {
+	state, err := OpenState(filepath.Join(t.TempDir(), "state.json"))
+	require.NoError(t, err)
+
+	endpoints := []*Endpoint{
+		{Name: "a", Address: "a:22"},
+		{Name: "b", Address: "b:22"},
+	}
+	m := NewListing(endpoints, NewLocalSSHClient(), testRenderer, WithState(state))
+	m.list.Select(1)
+	m.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("p")})
+
+	require.True(t, state.Get(endpoints[1]).Pinned)
+	require.Equal(t, "b "+pinMark, m.list.Items()[0].(ItemWrapper).Title())
+	require.Equal(t, endpoints[1], m.selected().endpoint)
+}
@@ -13,7 +13,10 @@ import (
 	"github.com/grandcat/zeroconf"
 )
 
-const service = "_ssh._tcp"
+const (
+	service        = "_ssh._tcp"
+	txtGroupPrefix = "wishlist.group="
+)
 
 // Endpoints returns the found endpoints from zeroconf.
 func Endpoints(ctx context.Context, domain string, timeout time.Duration) ([]*wishlist.Endpoint, error) {
@@ -37,9 +40,20 @@ func Endpoints(ctx context.Context, domain string, timeout time.Duration) ([]*wi
 		port := strconv.Itoa(entry.Port)
 		endpoints = append(endpoints, &wishlist.Endpoint{
 			Name:    hostname,
+			Group:   groupFromTXT(entry.Text),
 			Address: net.JoinHostPort(hostname, port),
 		})
 	}
 	log.Info("discovered from zeroconf", "service", service, "domain", domain, "devices", len(endpoints))
 	return endpoints, nil
 }
+
+// groupFromTXT gets the endpoint group from the service TXT records, if any.
+func groupFromTXT(txts []string) string {
+	for _, txt := range txts {
+		if strings.HasPrefix(txt, txtGroupPrefix) {
+			return strings.TrimPrefix(txt, txtGroupPrefix)
+		}
+	}
+	return ""
+}